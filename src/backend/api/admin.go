@@ -0,0 +1,195 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/scheduler"
+	"Go_surf_redesign/src/backend/store"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type apiIngestionRun struct {
+	ID          int64             `json:"id"`
+	Source      string            `json:"source"`
+	Status      string            `json:"status"`
+	StartedAt   time.Time         `json:"startedAt"`
+	FinishedAt  *time.Time        `json:"finishedAt"`
+	RowsWritten int               `json:"rowsWritten"`
+	Failures    map[string]string `json:"failures"`
+	Error       *string           `json:"error"`
+}
+
+type apiIngestionSource struct {
+	Source        string           `json:"source"`
+	LastRun       *apiIngestionRun `json:"lastRun"`
+	LastSuccessAt *time.Time       `json:"lastSuccessAt"`
+	Runs24h       int              `json:"runs24h"`
+	Failures24h   int              `json:"failures24h"`
+	StaleAfter    string           `json:"staleAfter"`
+	Stale         bool             `json:"stale"`
+}
+
+// apiIngestionJob is the scheduler's view of one ingestion source.
+type apiIngestionJob struct {
+	Name         string     `json:"name"`
+	Running      bool       `json:"running"`
+	WaitingOn    []string   `json:"waitingOn"`
+	LastRun      *time.Time `json:"lastRun"`
+	LastSuccess  *time.Time `json:"lastSuccess"`
+	LastDuration string     `json:"lastDuration"`
+	NextRun      *time.Time `json:"nextRun"`
+	LastError    *string    `json:"lastError"`
+	Runs         int        `json:"runs"`
+	Failures     int        `json:"failures"`
+}
+
+// apiIngestionScheduler reports the ingestion scheduler of the serving
+// process. Jobs is empty while another instance leads.
+type apiIngestionScheduler struct {
+	Leading bool              `json:"leading"`
+	Jobs    []apiIngestionJob `json:"jobs"`
+}
+
+type apiIngestionSummary struct {
+	Sources    []apiIngestionSource `json:"sources"`
+	RecentRuns []apiIngestionRun    `json:"recentRuns"`
+	// Scheduler is nil when ingestion does not run in this process.
+	Scheduler *apiIngestionScheduler `json:"scheduler"`
+}
+
+const defaultRecentRuns = 20
+
+// requireAdmin ends requests that do not carry the configured admin token
+// as a bearer token. Without a configured token, every request is refused.
+func (h *Handler) requireAdmin(c *gin.Context) {
+	want := h.cfg.Server.AdminToken
+	got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if want == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+		abortWithError(c, http.StatusUnauthorized, codeUnauthenticated, "a valid admin token is required")
+		return
+	}
+	c.Next()
+}
+
+type ingestionParams struct {
+	Limit int `form:"limit" binding:"min=1,max=200"`
+}
+
+// getIngestionSummary - returns the latest run and last success of every
+// ingestion source, flags sources whose data has gone stale, and lists the
+// most recent runs (?limit=N, default 20).
+func (h *Handler) getIngestionSummary(c *gin.Context) {
+	params := ingestionParams{Limit: defaultRecentRuns}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	limit := params.Limit
+
+	ctx := c.Request.Context()
+	recentRuns, err := h.store.RecentRuns(ctx, limit)
+	if err != nil {
+		internalError(c, err, "failed to fetch ingestion runs")
+		return
+	}
+	recent := make([]apiIngestionRun, 0, len(recentRuns))
+	for _, run := range recentRuns {
+		recent = append(recent, toAPIIngestionRun(run))
+	}
+
+	latest, err := h.store.LatestRuns(ctx)
+	if err != nil {
+		internalError(c, err, "failed to fetch ingestion runs")
+		return
+	}
+	latestBySource := make(map[string]apiIngestionRun)
+	for _, run := range latest {
+		latestBySource[run.Source] = toAPIIngestionRun(run)
+	}
+
+	now := time.Now()
+	stats, err := h.store.RunStats(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		internalError(c, err, "failed to summarise ingestion runs")
+		return
+	}
+	summaries := make(map[string]apiIngestionSource)
+	for _, st := range stats {
+		summaries[st.Source] = apiIngestionSource{
+			Source:        st.Source,
+			LastSuccessAt: st.LastSuccessAt,
+			Runs24h:       st.Runs,
+			Failures24h:   st.Failures,
+		}
+	}
+
+	// Report every configured source, including ones that have never run.
+	summary := apiIngestionSummary{RecentRuns: recent}
+	for _, job := range h.cfg.Ingestion.Jobs() {
+		s := summaries[job.Name]
+		s.Source = job.Name
+		if run, ok := latestBySource[job.Name]; ok {
+			s.LastRun = &run
+		}
+		staleAfter := job.Config.StaleAfter()
+		s.StaleAfter = staleAfter.String()
+		s.Stale = s.LastSuccessAt == nil || now.Sub(*s.LastSuccessAt) > staleAfter
+		summary.Sources = append(summary.Sources, s)
+	}
+	if h.ingestion != nil {
+		summary.Scheduler = toAPIIngestionScheduler(h.ingestion.Jobs())
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func toAPIIngestionScheduler(leading bool, jobs []scheduler.Status) *apiIngestionScheduler {
+	s := &apiIngestionScheduler{Leading: leading, Jobs: make([]apiIngestionJob, 0, len(jobs))}
+	for _, job := range jobs {
+		j := apiIngestionJob{
+			Name:         job.Name,
+			Running:      job.Running,
+			WaitingOn:    job.WaitingOn,
+			LastRun:      timeOrNil(job.LastRun),
+			LastSuccess:  timeOrNil(job.LastSuccess),
+			LastDuration: job.LastDuration.String(),
+			NextRun:      timeOrNil(job.NextRun),
+			Runs:         job.Runs,
+			Failures:     job.Failures,
+		}
+		if j.WaitingOn == nil {
+			j.WaitingOn = []string{}
+		}
+		if job.LastError != "" {
+			j.LastError = &job.LastError
+		}
+		s.Jobs = append(s.Jobs, j)
+	}
+	return s
+}
+
+// timeOrNil returns nil for the zero time, which the scheduler uses for
+// events that have not happened.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func toAPIIngestionRun(run store.IngestionRun) apiIngestionRun {
+	return apiIngestionRun{
+		ID:          run.ID,
+		Source:      run.Source,
+		Status:      run.Status,
+		StartedAt:   run.StartedAt,
+		FinishedAt:  run.FinishedAt,
+		RowsWritten: run.RowsWritten,
+		Failures:    run.Failures,
+		Error:       run.Error,
+	}
+}
@@ -1,214 +1,554 @@
 package meteo
 
 import (
+	"Go_surf_redesign/src/backend/events"
+	"Go_surf_redesign/src/backend/metrics"
 	"Go_surf_redesign/src/backend/models"
-	"database/sql"
+	"Go_surf_redesign/src/backend/scheduler"
+	"Go_surf_redesign/src/backend/spacial"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/config"
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout bounds how long StartRouter waits for in-flight requests.
+const shutdownTimeout = 10 * time.Second
+
 // The handler sctruct is needed to provide the get functions with access
-// to the data base.
+// to the data store.
 type Handler struct {
-	DB *sql.DB
+	store  store.Store
+	cache  *responseCache
+	events *events.Bus
+	// shutdown is closed when the server stops, ending open streams.
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+
+	logger *slog.Logger
+	cfg    *config.Config
+	// ingestion reports the jobs of ingestion running in this process;
+	// nil when it runs elsewhere.
+	ingestion IngestionJobs
+}
+
+// IngestionJobs reports the ingestion scheduler of the serving process.
+type IngestionJobs interface {
+	// Jobs reports whether the process is running the ingestion jobs
+	// and, when it is, the state of each job.
+	Jobs() (leading bool, jobs []scheduler.Status)
+}
+
+// RouterOption customises the router.
+type RouterOption func(*Handler)
+
+// WithIngestion reports the jobs of ingestion running in the same process
+// in /admin/ingestion.
+func WithIngestion(jobs IngestionJobs) RouterOption {
+	return func(h *Handler) {
+		h.ingestion = jobs
+	}
 }
 
 type apiCity struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	State string `json:"state"`
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	State      string   `json:"state"`
+	DistanceKm *float64 `json:"distanceKm,omitempty"` // from ?near
+}
+
+// apiSpot is a surf spot in a list. Score is only reported when sorting
+// by it, since it changes with the conditions.
+type apiSpot struct {
+	models.StaticSurfSpot
+	BreakType  string   `json:"breakType"`
+	DistanceKm *float64 `json:"distanceKm,omitempty"` // from ?near
+	Score      *float64 `json:"score,omitempty"`
 }
 
 type apiSurfConditions struct {
 	ID int `json:"id"`
 }
 
-// getCities - returns a json list of all city names and their IDs.
+type cityParams struct {
+	CityID int `uri:"cityID" binding:"min=1"`
+}
+
+type spotParams struct {
+	SpotID int `uri:"spotID" binding:"min=1"`
+}
+
+// listFilters are the filters shared by the city and spot lists. State
+// and county match case-insensitively; HasTideStation keeps spots with a
+// tide region, or cities with such a spot.
+type listFilters struct {
+	State          string `form:"state"`
+	County         string `form:"county"`
+	HasTideStation *bool  `form:"has_tide_station"`
+	Near           string `form:"near"`
+}
+
+// filter returns the filters for the store.
+func (f listFilters) filter() store.ListFilter {
+	return store.ListFilter{
+		RegionFilter:   store.RegionFilter{State: f.State, County: f.County},
+		HasTideStation: f.HasTideStation,
+	}
+}
+
+// listQuery returns the parameters of a list request, for cache keys.
+func listQuery(f listFilters, p pageParams, sort string) url.Values {
+	v := url.Values{"state": {f.State}, "county": {f.County}, "near": {f.Near}}
+	if f.HasTideStation != nil {
+		v.Set("has_tide_station", strconv.FormatBool(*f.HasTideStation))
+	}
+	v.Set("sort", sort)
+	v.Set("limit", strconv.Itoa(p.Limit))
+	v.Set("cursor", p.Cursor)
+	return v
+}
+
+// listTTL is how long a list page is cached. Pages with distances are
+// not cached, since every position would cache its own copy of the list.
+func listTTL(ttl time.Duration, near *point) time.Duration {
+	if near != nil {
+		return 0
+	}
+	return ttl
+}
+
+type cityListParams struct {
+	pageParams
+	listFilters
+	Sort string `form:"sort" binding:"omitempty,oneof=name distance"`
+}
+
+type spotListParams struct {
+	pageParams
+	listFilters
+	BreakType string `form:"break_type"`
+	Sort      string `form:"sort" binding:"omitempty,oneof=name distance score"`
+}
+
+// getCities - returns a json list of cities and their IDs, filtered by
+// state, county and whether they have spots with a tide station, sorted
+// by name or by distance from ?near, one page at a time.
 func (h *Handler) getCities(c *gin.Context) {
-	cities := []apiCity{}
+	params := cityListParams{pageParams: pageParams{Limit: defaultPageSize}}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	params.Sort = cmp.Or(params.Sort, sortName)
+	near, ok := bindNear(c, params.Near, params.Sort)
+	if !ok {
+		return
+	}
+	after, ok := bindCursor(c, params.Cursor, params.Sort)
+	if !ok {
+		return
+	}
 
-	rows, err := h.DB.Query("SELECT id, name, state FROM cities ORDER BY name")
+	key := citiesKey + "?" + listQuery(params.listFilters, params.pageParams, params.Sort).Encode()
+	entry, err := h.cache.get(key, listTTL(h.cfg.Server.Cache.StaticTTL.Duration, near), func() (any, time.Time, error) {
+		cities, err := h.listCities(c.Request.Context(), params, near, after)
+		return cities, time.Time{}, err
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to fetch cities",
-		})
+		internalError(c, err, "failed to fetch cities")
+		return
+	}
+	if h.notModified(c, entry) {
 		return
 	}
-	defer rows.Close()
+	respondPage(c, entry.value.(listPage[apiCity]), params.Sort)
+}
 
-	for rows.Next() {
-		var city apiCity
-		if err := rows.Scan(&city.ID, &city.Name, &city.State); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "failed to parse city data",
-			})
-			return
+// listCities returns the page of the cities matching params that follows
+// after. The store pages through the name order; other orders are ranked
+// here.
+func (h *Handler) listCities(ctx context.Context, params cityListParams, near *point, after *listKey) (listPage[apiCity], error) {
+	var keyset store.Keyset
+	if params.Sort == sortName {
+		// One more than the page, to tell whether another follows.
+		keyset.Limit = params.Limit + 1
+		if after != nil {
+			keyset.AfterName, keyset.AfterID = after.Text, after.ID
 		}
-		cities = append(cities, city)
+	}
+	stored, total, err := h.store.ListCities(ctx, params.filter(), keyset)
+	if err != nil {
+		return listPage[apiCity]{}, err
 	}
 
-	c.JSON(http.StatusOK, cities)
+	cities := make([]listed[apiCity], 0, len(stored))
+	for _, city := range stored {
+		item := listed[apiCity]{
+			Item: apiCity{ID: city.ID, Name: city.Name, State: city.State},
+			key:  listKey{Text: city.Name, ID: city.ID},
+		}
+		if near != nil {
+			d := spacial.Haversine(near.Latitude, near.Longitude, city.Latitude, city.Longitude)
+			item.Item.DistanceKm = &d
+		}
+		if params.Sort == sortDistance {
+			item.key.Num = item.Item.DistanceKm
+		}
+		cities = append(cities, item)
+	}
+	if params.Sort == sortName {
+		return keysetPage(cities, params.Limit, total), nil
+	}
+	sortListed(cities)
+	return pageOf(cities, after, params.Limit), nil
 }
 
-// getSurfSpots - takes cityID, returns json of all surf spots and their
-// ids for that cityID.
+// getSurfSpots - takes cityID, returns json of the surf spots and their
+// ids for that cityID. It takes the same parameters as getSpots.
 func (h *Handler) getSurfSpots(c *gin.Context) {
-	cityIDParam := c.Param("cityID")
+	var params cityParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	h.respondSpots(c, params.CityID)
+}
 
-	cityID, err := strconv.Atoi(cityIDParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid cityID",
-		})
+// getSpots - returns json of surf spots in every city, filtered by state,
+// county, break type and tide station, sorted by name, distance from
+// ?near or current score, one page at a time.
+func (h *Handler) getSpots(c *gin.Context) {
+	h.respondSpots(c, 0)
+}
+
+// respondSpots responds with a page of the spots in cityID, or in every
+// city when cityID is 0.
+func (h *Handler) respondSpots(c *gin.Context, cityID int) {
+	params := spotListParams{pageParams: pageParams{Limit: defaultPageSize}}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	params.Sort = cmp.Or(params.Sort, sortName)
+	near, ok := bindNear(c, params.Near, params.Sort)
+	if !ok {
+		return
+	}
+	after, ok := bindCursor(c, params.Cursor, params.Sort)
+	if !ok {
 		return
 	}
 
-	surfSpots := []models.StaticSurfSpot{}
+	query := listQuery(params.listFilters, params.pageParams, params.Sort)
+	query.Set("break_type", params.BreakType)
+	key := spotsKey + strconv.Itoa(cityID) + "?" + query.Encode()
+	ttl := h.cfg.Server.Cache.StaticTTL.Duration
+	if params.Sort == sortScore {
+		// Scores follow the conditions.
+		key = rankedKey + key
+		ttl = h.cfg.Server.Cache.ConditionsTTL.Duration
+	}
+	entry, err := h.cache.get(key, listTTL(ttl, near), func() (any, time.Time, error) {
+		spots, err := h.listSpots(c.Request.Context(), cityID, params, near, after)
+		return spots, time.Time{}, err
+	})
+	if err != nil {
+		internalError(c, err, "failed to fetch static surf spots")
+		return
+	}
+	if h.notModified(c, entry) {
+		return
+	}
+	respondPage(c, entry.value.(listPage[apiSpot]), params.Sort)
+}
 
-	rows, err := h.DB.Query(`
-		SELECT id, name, latitude, longitude, city_id, nearest_buoy
-		FROM surfspot
-		WHERE city_id = $1
-		`, cityID)
+// listSpots returns the page of the spots matching params that follows
+// after. The store pages through the name order; other orders are ranked
+// here.
+func (h *Handler) listSpots(ctx context.Context, cityID int, params spotListParams, near *point, after *listKey) (listPage[apiSpot], error) {
+	filter := params.filter()
+	filter.CityID = cityID
+	filter.BreakType = params.BreakType
+	var keyset store.Keyset
+	if params.Sort == sortName {
+		// One more than the page, to tell whether another follows.
+		keyset.Limit = params.Limit + 1
+		if after != nil {
+			keyset.AfterName, keyset.AfterID = after.Text, after.ID
+		}
+	}
+	stored, total, err := h.store.ListSpots(ctx, filter, keyset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to fetch static surf spots",
-		})
-		return
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var spot models.StaticSurfSpot
-		if err := rows.Scan(
-			&spot.ID,
-			&spot.Name,
-			&spot.Latitude,
-			&spot.Longitude,
-			&spot.CityID,
-			&spot.NearestBuoy,
-		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "failed to parse surfspot data",
-			})
-			return
+		return listPage[apiSpot]{}, err
+	}
+
+	var conditions map[int]models.CurrentSurfSpotConditions
+	if params.Sort == sortScore {
+		all, err := h.store.AllConditions(ctx)
+		if err != nil {
+			return listPage[apiSpot]{}, err
+		}
+		conditions = make(map[int]models.CurrentSurfSpotConditions, len(all))
+		for _, cond := range all {
+			conditions[cond.SpotId] = cond
 		}
-		surfSpots = append(surfSpots, spot)
 	}
 
-	c.JSON(http.StatusOK, surfSpots)
+	spots := make([]listed[apiSpot], 0, len(stored))
+	for _, spot := range stored {
+		item := listed[apiSpot]{
+			Item: apiSpot{
+				StaticSurfSpot: models.StaticSurfSpot{
+					ID:          spot.ID,
+					Name:        spot.Name,
+					Latitude:    spot.Latitude,
+					Longitude:   spot.Longitude,
+					CityID:      spot.CityID,
+					NearestBuoy: spot.NearestBuoy,
+				},
+				BreakType: spot.BreakType,
+			},
+			key: listKey{Text: spot.Name, ID: spot.ID},
+		}
+		if near != nil {
+			d := spacial.Haversine(near.Latitude, near.Longitude, spot.Latitude, spot.Longitude)
+			item.Item.DistanceKm = &d
+		}
+		switch params.Sort {
+		case sortDistance:
+			item.key.Num = item.Item.DistanceKm
+		case sortScore:
+			if cond, ok := conditions[spot.ID]; ok {
+				item.Item.Score = cond.Score(spot.Orientation)
+			}
+			item.key.Num = negate(item.Item.Score) // best first
+		}
+		spots = append(spots, item)
+	}
+	if params.Sort == sortName {
+		return keysetPage(spots, params.Limit, total), nil
+	}
+	sortListed(spots)
+	return pageOf(spots, after, params.Limit), nil
 }
 
 // getSpotConditionsCurrent recieves a surfSpotID and retuns a json response
 // of current conditions for that surfSpotID
 func (h *Handler) getSpotConditionsCurrent(c *gin.Context) {
-	spotIDParam := c.Param("spotID")
+	var params spotParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	surfSpotID := params.SpotID
 
-	surfSpotID, err := strconv.Atoi(spotIDParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid spotID",
-		})
-		return
-	}
-
-	var conditions models.CurrentSurfSpotConditions
-	err = h.DB.QueryRow(`
-		SELECT
-			id,
-			spot_id,
-			recorded_at,
-			dom_swell_height_m,
-			dom_swell_dir,
-			wind_speed_mph,
-			wind_direction,
-			air_temp_deg_c,
-			water_temp_deg_c,
-			precipitation,
-			cloud_coverage,
-			domwp_sec,
-			nearest_buoy
-		FROM current_surf_spot_conditions
-		WHERE spot_id = $1
-	`, surfSpotID).Scan(
-		&conditions.ID,
-		&conditions.SpotId,
-		&conditions.RecordedAt,
-		&conditions.DomSwellHeightM,
-		&conditions.DomSwellDir,
-		&conditions.WindSpeedMph,
-		&conditions.WindDirection,
-		&conditions.AirTempDegC,
-		&conditions.WaterTempDegC,
-		&conditions.Precipitation,
-		&conditions.CloudCoverage,
-		&conditions.DominantWavePeriodSec,
-		&conditions.NearestBuoy,
-	)
+	key := conditionsKey + strconv.Itoa(surfSpotID)
+	entry, err := h.cache.get(key, h.cfg.Server.Cache.ConditionsTTL.Duration, func() (any, time.Time, error) {
+		conditions, err := h.store.SpotConditions(c.Request.Context(), surfSpotID)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return conditions, conditions.RecordedAt, nil
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "no surf conditions found for spot",
-			})
+		if errors.Is(err, store.ErrNotFound) {
+			notFound(c, "no surf conditions found for spot")
 			return
 		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		internalError(c, err, "failed to fetch surf conditions")
 		return
 	}
+	if h.notModified(c, entry) {
+		return
+	}
+
+	// Ages are reported as of the request, not the last rebuild. The
+	// cached value is a copy, so this does not change the cache.
+	conditions := entry.value.(models.CurrentSurfSpotConditions)
+	conditions.Provenance.UpdateFreshness(time.Now(), h.cfg.QC.MaxAge.Duration)
 	c.JSON(http.StatusOK, conditions)
 }
 
-// getTideDatacurrent fetches and returns tidal information for all counties
-// given todays date.
-/* func (h *Handler) getTideDataCurrent(c *gin.Context) {
-	// get all of the tide data for today.
-	now := time.Now()
-	date := now.Format("2006-01-02")
-
-	var tidalChart models.TideData
-	var charts []models.TideData
-	err := h.DB.QueryRow(`
-		SELECT
-			county_name,
-			measurement_date,
-			measurement_time,
-			water_level,
-			tidal_state
-		FROM tide_data
-		WHERE measurement_date = %1
-		`, date).Scan(
-			&tidalChart.County,
-			&tidalChart.Date,
-			&tidalChart.Time,
-			&tidalChart.HeightFt,
-			&tidalChart.HighLow,
-		)
+// apiTide is one predicted high or low tide. HighLow is "H" or "L".
+type apiTide struct {
+	Station  string  `json:"station"`
+	County   string  `json:"county"`
+	State    string  `json:"state"`
+	Region   string  `json:"region"`
+	Date     string  `json:"date"`
+	Time     string  `json:"time"`
+	HeightFt float64 `json:"heightFt"`
+	HighLow  string  `json:"highLow"`
+}
+
+type tideParams struct {
+	Date string `form:"date" binding:"omitempty,datetime=2006-01-02"`
+}
+
+// getTides - returns the predicted high and low tides of every tide
+// station on ?date=YYYY-MM-DD, today by default, ordered by tide region,
+// station and time.
+func (h *Handler) getTides(c *gin.Context) {
+	var params tideParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	date := cmp.Or(params.Date, time.Now().Format(time.DateOnly))
+
+	entry, err := h.cache.get(tidesKey+date, h.cfg.Server.Cache.StaticTTL.Duration, func() (any, time.Time, error) {
+		stored, err := h.store.TidesOn(c.Request.Context(), date)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		tides := make([]apiTide, 0, len(stored))
+		for _, t := range stored {
+			tides = append(tides, apiTide{
+				Station:  t.StationName,
+				County:   t.CountyName,
+				State:    t.StateCode,
+				Region:   t.TideRegion,
+				Date:     t.Date,
+				Time:     t.Time,
+				HeightFt: t.WaterLevel,
+				HighLow:  t.TidalState,
+			})
+		}
+		return tides, time.Time{}, nil
+	})
 	if err != nil {
+		internalError(c, err, "failed to fetch tides")
+		return
+	}
+	if h.notModified(c, entry) {
+		return
+	}
+	c.JSON(http.StatusOK, entry.value)
+}
+
+// NewRouter - returns the gin router serving the API and frontend from st.
+// Cached responses are dropped when bus reports that ingestion rewrote
+// their data; with a nil bus they only expire.
+func NewRouter(st store.Store, cfg *config.Config, bus *events.Bus, opts ...RouterOption) *gin.Engine {
+	router, _ := newRouter(st, cfg, bus, opts...)
+	return router
+}
 
+func newRouter(st store.Store, cfg *config.Config, bus *events.Bus, opts ...RouterOption) (*gin.Engine, *Handler) {
+	h := &Handler{
+		store:    st,
+		cache:    newResponseCache(),
+		events:   bus,
+		shutdown: make(chan struct{}),
+		logger:   slog.Default().With("component", "api"),
+		cfg:      cfg,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if bus != nil {
+		bus.Subscribe(h.cache.invalidate)
+	}
+
+	router := gin.New()
+	router.Use(h.logRequests)
+	router.Use(gin.CustomRecoveryWithWriter(nil, h.recoverPanics))
+	router.Use(cors.New(corsConfig()))
+	router.Use(recordMetrics)
 
+	for _, v := range apiVersions {
+		group := router.Group(v.prefix)
+		if v.deprecated() {
+			group.Use(deprecation(v))
+		}
+		h.routes(group)
+	}
+
+	// Probes, scrapers and the frontend are not part of the versioned API.
+	router.GET("/healthz", h.getHealthz)
+	router.GET("/readyz", h.getReadyz)
+	router.GET("/metrics", gin.WrapH(metrics.Handler(metrics.BuoyObservationAge(h.buoyObservationTimes))))
+	router.GET("/openapi.json", h.getOpenAPI)
+	router.Static("/gosurf", cfg.Server.StaticDir)
+
+	router.NoRoute(func(c *gin.Context) {
+		notFound(c, "no route for "+c.Request.URL.Path)
+	})
+	return router, h
+}
+
+// corsConfig allows any origin, like cors.Default, and lets browsers
+// read the paging, caching and versioning headers.
+func corsConfig() cors.Config {
+	cfg := cors.DefaultConfig()
+	cfg.AllowAllOrigins = true
+	cfg.ExposeHeaders = []string{"Link", "X-Total-Count", "X-Request-ID", "ETag", "Deprecation", "Sunset"}
+	return cfg
+}
+
+// routes registers the API routes on group.
+func (h *Handler) routes(group *gin.RouterGroup) {
+	group.GET("/cities", h.getCities)
+	group.GET("/surfspots/:cityID", h.getSurfSpots)
+	group.GET("/spots", h.getSpots)
+	group.GET("/cities/:cityID/conditions", h.getCityConditions)
+	group.GET("/conditions", h.getRegionConditions)
+	group.GET("/surfforecast/current/:spotID", h.getSpotConditionsCurrent)
+	group.GET("/tides", h.getTides)
+	group.GET("/stream/spots", h.streamSpots)
+
+	group.GET("/admin/ingestion", h.requireAdmin, h.getIngestionSummary)
+	group.GET("/status/data", h.getDataStatus)
 }
-*/
 
-// StartRouter - creates gin router with default middleware.
-// By default it serves on :8080 unless PORT variable is defined.
-func StartRouter(db *sql.DB) {
-	h := &Handler{DB: db}
+// closeStreams ends every open event stream.
+func (h *Handler) closeStreams() {
+	h.shutdownOnce.Do(func() { close(h.shutdown) })
+}
 
-	router := gin.Default()
-	router.Use(cors.Default())
+// StartRouter - creates the gin router with request logging and recovery.
+// It serves on cfg.Server.Addr (":8080" unless PORT is defined) and blocks until
+// the server fails or ctx is cancelled, in which case in-flight requests
+// are given time to finish.
+func StartRouter(ctx context.Context, st store.Store, cfg *config.Config, bus *events.Bus, opts ...RouterOption) error {
+	// Gin's debug output is plain text, which would break JSON logs.
+	if cfg.Log.Format == config.LogJSON {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router, h := newRouter(st, cfg, bus, opts...)
 
-	router.GET("/cities", h.getCities)
-	router.GET("/surfspots/:cityID", h.getSurfSpots)
-	router.GET("/surfforecast/current/:spotID", h.getSpotConditionsCurrent)
+	srv := &http.Server{
+		Addr:    cfg.Server.Addr,
+		Handler: router,
+	}
+	// Streams never finish on their own, so Shutdown would otherwise wait
+	// out its whole timeout.
+	srv.RegisterOnShutdown(h.closeStreams)
 
-	router.Static("/gosurf", "./src/frontend")
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
 
-	router.Run(":8080")
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
 }
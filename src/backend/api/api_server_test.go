@@ -0,0 +1,176 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/scheduler"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// get serves a GET for path from a router on the demo store and decodes
+// the JSON response into v.
+func get(t *testing.T, router http.Handler, path string, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	return rec.Code
+}
+
+// The API serves cities, spots and conditions from the demo store without
+// a database.
+func TestRouterWithDemoStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(memory.Demo(), config.Default(), nil)
+
+	var cities []apiCity
+	if code := get(t, router, "/v1/cities", &cities); code != http.StatusOK {
+		t.Fatalf("/v1/cities: status %d", code)
+	}
+	if len(cities) != 2 || cities[0].Name != "Huntington Beach" {
+		t.Errorf("/v1/cities: %+v", cities)
+	}
+
+	var spots []struct {
+		ID          int `json:"id"`
+		NearestBuoy int `json:"nearestBuoy"`
+	}
+	if code := get(t, router, "/v1/surfspots/4", &spots); code != http.StatusOK {
+		t.Fatalf("/v1/surfspots/4: status %d", code)
+	}
+	if len(spots) != 2 || spots[0].NearestBuoy == 0 {
+		t.Errorf("/v1/surfspots/4: %+v", spots)
+	}
+
+	var conditions struct {
+		SpotId          int
+		DomSwellHeightM *float64
+		Provenance      models.Provenance
+	}
+	if code := get(t, router, "/v1/surfforecast/current/10", &conditions); code != http.StatusOK {
+		t.Fatalf("/v1/surfforecast/current/10: status %d", code)
+	}
+	if conditions.SpotId != 10 || conditions.DomSwellHeightM == nil {
+		t.Errorf("/v1/surfforecast/current/10: %+v", conditions)
+	}
+	p := conditions.Provenance
+	if p.Status != models.Fresh || p.Buoy.ID == "" || p.Weather.ID != "KSNA" {
+		t.Errorf("provenance: %+v", p)
+	}
+	for _, src := range []models.Source{p.Buoy, p.Weather} {
+		if src.DistanceKm == nil || src.AgeMinutes == nil || *src.AgeMinutes < 10 {
+			t.Errorf("source %s: distance %v, age %v", src.ID, src.DistanceKm, src.AgeMinutes)
+		}
+	}
+
+	if code := get(t, router, "/v1/surfforecast/current/999", nil); code != http.StatusNotFound {
+		t.Errorf("unknown spot: status %d, want 404", code)
+	}
+}
+
+const testAdminToken = "test-admin-token"
+
+// adminConfig returns the default configuration with testAdminToken set.
+func adminConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Server.AdminToken = testAdminToken
+	return cfg
+}
+
+// adminRequest returns a GET for path that carries testAdminToken.
+func adminRequest(path string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+// The admin endpoints need the configured token, on every API version,
+// and are closed while none is configured.
+func TestAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		name   string
+		cfg    *config.Config
+		header string
+		status int
+	}{
+		{"no token configured", config.Default(), "Bearer ", http.StatusUnauthorized},
+		{"no header", adminConfig(), "", http.StatusUnauthorized},
+		{"wrong token", adminConfig(), "Bearer nope", http.StatusUnauthorized},
+		{"basic auth", adminConfig(), "Basic " + testAdminToken, http.StatusUnauthorized},
+		{"token", adminConfig(), "Bearer " + testAdminToken, http.StatusOK},
+	} {
+		router := NewRouter(memory.Demo(), tc.cfg, nil)
+		for _, path := range []string{"/v1/admin/ingestion", "/admin/ingestion"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("%s %s: status %d, want %d", tc.name, path, rec.Code, tc.status)
+			}
+		}
+	}
+}
+
+// fakeIngestion leads with one job that last failed.
+type fakeIngestion struct{}
+
+func (fakeIngestion) Jobs() (bool, []scheduler.Status) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return true, []scheduler.Status{{
+		Name:         "buoys",
+		LastRun:      at,
+		LastDuration: 1500 * time.Millisecond,
+		NextRun:      at.Add(10 * time.Minute),
+		LastError:    "upstream timeout",
+		Runs:         3,
+		Failures:     1,
+	}}
+}
+
+// The ingestion summary includes the scheduler's jobs only when ingestion
+// runs in the serving process.
+func TestIngestionScheduler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	summary := func(router http.Handler) apiIngestionSummary {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, adminRequest("/v1/admin/ingestion"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d", rec.Code)
+		}
+		var summary apiIngestionSummary
+		if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+			t.Fatal(err)
+		}
+		return summary
+	}
+
+	if s := summary(NewRouter(memory.Demo(), adminConfig(), nil)).Scheduler; s != nil {
+		t.Errorf("scheduler without ingestion: %+v", s)
+	}
+	s := summary(NewRouter(memory.Demo(), adminConfig(), nil, WithIngestion(fakeIngestion{}))).Scheduler
+	if s == nil || !s.Leading || len(s.Jobs) != 1 {
+		t.Fatalf("scheduler: %+v", s)
+	}
+	job := s.Jobs[0]
+	if job.Name != "buoys" || job.LastError == nil || *job.LastError != "upstream timeout" ||
+		job.LastSuccess != nil || job.NextRun == nil || job.LastDuration != "1.5s" {
+		t.Errorf("job: %+v", job)
+	}
+}
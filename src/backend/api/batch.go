@@ -0,0 +1,60 @@
+package meteo
+
+import (
+	"context"
+	"sync"
+)
+
+// fetchAll calls fetch for every id using at most workers goroutines. It
+// returns the successful results and the errors, both keyed by id.
+func fetchAll[T any](ctx context.Context, workers int, ids []string, fetch func(context.Context, string) (T, error)) (map[string]T, map[string]error) {
+	results := make(map[string]T, len(ids))
+	errs := make(map[string]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+
+	for range max(1, min(workers, len(ids))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range queue {
+				v, err := fetch(ctx, id)
+				mu.Lock()
+				if err != nil {
+					errs[id] = err
+				} else {
+					results[id] = v
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, id := range ids {
+		queue <- id
+	}
+	close(queue)
+	wg.Wait()
+
+	return results, errs
+}
+
+// GetObservations fetches the newest observation of each buoy concurrently.
+// Buoys whose file has no data rows are reported as errors.
+func (s *RTBouyService) GetObservations(ctx context.Context, bouyIds []string) (map[string]*BouyObservation, map[string]error) {
+	return fetchAll(ctx, s.client.workers, bouyIds, func(ctx context.Context, id string) (*BouyObservation, error) {
+		obs, err := s.GetObservation(ctx, id)
+		if err == nil && obs == nil {
+			err = ErrNoObservations
+		}
+		return obs, err
+	})
+}
+
+// GetObservations fetches the latest observation of each station
+// concurrently.
+func (s *RTWeatherService) GetObservations(ctx context.Context, stationIds []string) (map[string]*WeatherObservation, map[string]error) {
+	return fetchAll(ctx, s.client.workers, stationIds, s.GetObservation)
+}
@@ -0,0 +1,170 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/events"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cache keys start with the kind of response they hold.
+const (
+	citiesKey     = "cities"
+	spotsKey      = "spots/"
+	conditionsKey = "conditions/"
+	rankedKey     = "ranked/" // lists ordered by the current conditions
+	tidesKey      = "tides/"
+)
+
+// invalidatedBy lists the cache keys, by prefix, holding data each event
+// source rewrites.
+var invalidatedBy = map[string][]string{
+	events.SourceStatic:     {citiesKey, spotsKey, conditionsKey, rankedKey},
+	events.SourceConditions: {conditionsKey, rankedKey},
+	events.SourceTides:      {tidesKey},
+}
+
+// maxCacheEntries bounds the cache, since spot and city IDs come from the
+// request path.
+const maxCacheEntries = 10000
+
+// cacheEntry is a response value with the validators sent along with it.
+type cacheEntry struct {
+	value        any
+	etag         string
+	lastModified time.Time // zero when the data has no timestamp
+	expires      time.Time
+}
+
+// responseCache keeps response values in memory until their TTL runs out
+// or ingestion rewrites the data behind them.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// generation counts invalidations, so a value loaded while one ran is
+	// not stored over the invalidation.
+	generation uint64
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]cacheEntry)}
+}
+
+// get returns the entry for key, calling load to fill it when it is
+// missing or expired. Errors are not cached. With a zero ttl nothing is
+// cached, but the entry still carries validators.
+func (rc *responseCache) get(key string, ttl time.Duration, load func() (any, time.Time, error)) (cacheEntry, error) {
+	now := time.Now()
+	rc.mu.Lock()
+	e, ok := rc.entries[key]
+	generation := rc.generation
+	rc.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e, nil
+	}
+
+	value, lastModified, err := load()
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return cacheEntry{}, fmt.Errorf("could not encode response: %w", err)
+	}
+	h := fnv.New64a()
+	h.Write(body)
+	e = cacheEntry{
+		value: value,
+		// Weak, because conditions report ages as of each request.
+		etag:         fmt.Sprintf(`W/"%016x"`, h.Sum64()),
+		lastModified: lastModified,
+		expires:      now.Add(ttl),
+	}
+	if ttl <= 0 {
+		return e, nil
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.generation != generation {
+		// The data may have been rewritten after load read it.
+		return e, nil
+	}
+	if len(rc.entries) >= maxCacheEntries {
+		for k, old := range rc.entries {
+			if !now.Before(old.expires) {
+				delete(rc.entries, k)
+			}
+		}
+		if len(rc.entries) >= maxCacheEntries {
+			clear(rc.entries)
+		}
+	}
+	rc.entries[key] = e
+	return e, nil
+}
+
+// invalidate drops the entries holding data rewritten by e's source.
+func (rc *responseCache) invalidate(e events.Event) {
+	prefixes := invalidatedBy[e.Source]
+	if len(prefixes) == 0 {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.generation++
+	for key := range rc.entries {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				delete(rc.entries, key)
+				break
+			}
+		}
+	}
+}
+
+// notModified sets the caching headers for e and, when the request's
+// validators still match it, responds 304 and returns true.
+func (h *Handler) notModified(c *gin.Context, e cacheEntry) bool {
+	c.Header("ETag", e.etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.cfg.Server.Cache.MaxAge.Seconds())))
+	if !e.lastModified.IsZero() {
+		c.Header("Last-Modified", e.lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since (RFC 9110).
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if etagMatches(match, e.etag) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+	if since := c.GetHeader("If-Modified-Since"); since != "" && !e.lastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		if err == nil && !e.lastModified.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches compares an If-None-Match header with etag using the weak
+// comparison.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
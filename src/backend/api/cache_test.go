@@ -0,0 +1,93 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/events"
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Conditions are served from the cache until ingestion reports a rebuild,
+// and clients holding the current ETag or Last-Modified get a 304.
+func TestConditionsCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	st := memory.Demo()
+	bus := events.NewBus()
+	router := NewRouter(st, config.Default(), bus)
+	const path = "/v1/surfforecast/current/10"
+
+	request := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := request("", "")
+	etag := first.Header().Get("ETag")
+	lastModified := first.Header().Get("Last-Modified")
+	if first.Code != http.StatusOK || etag == "" || lastModified == "" || first.Header().Get("Cache-Control") == "" {
+		t.Fatalf("first response: %d %v", first.Code, first.Header())
+	}
+	if rec := request("If-None-Match", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("If-None-Match: status %d", rec.Code)
+	}
+	if rec := request("If-Modified-Since", lastModified); rec.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: status %d", rec.Code)
+	}
+
+	conditions, err := st.SpotConditions(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	height := 3.5
+	conditions.DomSwellHeightM = &height
+	conditions.RecordedAt = conditions.RecordedAt.Add(15 * time.Minute)
+	if err := st.ReplaceConditions(ctx, []models.CurrentSurfSpotConditions{conditions}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := request("If-None-Match", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("before the event: status %d, want the cached 304", rec.Code)
+	}
+	bus.Publish(events.Event{Source: events.SourceConditions, At: time.Now()})
+	if rec := request("If-None-Match", etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("after the event: status %d, ETag %s", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+// A value loaded while an invalidation runs is returned but not cached.
+func TestCacheInvalidatedDuringLoad(t *testing.T) {
+	rc := newResponseCache()
+	loads := 0
+	load := func(invalidate bool) func() (any, time.Time, error) {
+		return func() (any, time.Time, error) {
+			loads++
+			if invalidate {
+				rc.invalidate(events.Event{Source: events.SourceConditions})
+			}
+			return loads, time.Time{}, nil
+		}
+	}
+
+	if e, err := rc.get(conditionsKey+"1", time.Hour, load(true)); err != nil || e.value != 1 {
+		t.Fatalf("first get: %v, %v", e.value, err)
+	}
+	if e, _ := rc.get(conditionsKey+"1", time.Hour, load(false)); e.value != 2 {
+		t.Errorf("stale value %v was cached", e.value)
+	}
+	if e, _ := rc.get(conditionsKey+"1", time.Hour, load(false)); e.value != 2 {
+		t.Errorf("value %v, want the cached 2", e.value)
+	}
+}
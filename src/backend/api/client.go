@@ -1,30 +1,61 @@
 package meteo
 
 import (
+	"Go_surf_redesign/src/backend/archive"
+	"Go_surf_redesign/src/backend/logging"
+	"Go_surf_redesign/src/backend/metrics"
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/config"
 	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
+// Paths are joined to the NDBC and NWS base URLs in the upstream config.
 const (
-	// ndbcBouyDataUrl to access real time bouy data from NOAA.
-	rtNDBCBouyDataURL = "https://www.ndbc.noaa.gov/data/realtime2/%s.txt"
-	// rtWeatherUrl to access real-time Weather data from Weather.gov
-	rtWeatherURL = "https://api.weather.gov/stations/%s/observations/latest"
+	// rtNDBCBouyDataPath to access real time bouy data from NOAA.
+	rtNDBCBouyDataPath = "/data/realtime2/%s.txt"
+	// rtWeatherPath to access real-time Weather data from Weather.gov
+	rtWeatherPath = "/stations/%s/observations/latest"
+	// nwsPointsPath resolves a "lat,lon" pair to its NWS forecast grid.
+	nwsPointsPath = "/points/%s"
 )
 
+// ErrNoObservations is returned when a buoy file holds no data rows.
+var ErrNoObservations = errors.New("no observations in file")
+
 type Client struct {
 	httpClient *http.Client
+	pointsURL  string
+	userAgent  string
+	workers    int
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	limiter    *hostLimiter
+	validators *validatorStore
+	logger     *slog.Logger
+	// archive receives every payload fetched, when set. ndbcURL and
+	// nwsURL tell the payloads' sources apart.
+	archive archive.Archive
+	ndbcURL string
+	nwsURL  string
 
 	RTBouy    *RTBouyService
 	RTWeather *RTWeatherService
+	Forecast  *ForecastService
 }
 
 type service struct {
@@ -40,23 +71,68 @@ type RTWeatherService struct {
 	*service
 }
 
-// NewClient returns a new API client.
-func NewClient() *Client {
+// Option customises a Client.
+type Option func(*Client)
+
+// WithTransport sends every request through rt instead of
+// http.DefaultTransport, for example a fixture replay transport in tests.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = rt
+	}
+}
+
+// WithArchive stores the body of every successful response in a.
+func WithArchive(a archive.Archive) Option {
+	return func(c *Client) {
+		c.archive = a
+	}
+}
+
+// NewClient returns a new API client configured by cfg.
+func NewClient(cfg config.UpstreamConfig, opts ...Option) *Client {
+	ndbcURL := strings.TrimRight(cfg.NDBCURL, "/")
+	nwsURL := strings.TrimRight(cfg.NWSURL, "/")
+
 	c := &Client{
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: cfg.Timeout.Duration,
 		},
+		pointsURL:  nwsURL + nwsPointsPath,
+		userAgent:  cfg.UserAgent,
+		workers:    cfg.Workers,
+		retries:    cfg.Retries,
+		backoff:    cfg.Backoff.Duration,
+		maxBackoff: cfg.MaxBackoff.Duration,
+		limiter:    newHostLimiter(cfg.HostInterval.Duration, cfg.HostBurst),
+		logger:     slog.Default().With("component", "upstream"),
+		ndbcURL:    ndbcURL,
+		nwsURL:     nwsURL,
+	}
+	validators, err := loadValidators(cfg.ValidatorFile)
+	if err != nil {
+		c.logger.Warn("starting with empty validator store", "error", err)
+	}
+	c.validators = validators
+	for _, opt := range opts {
+		opt(c)
 	}
 	c.RTBouy = &RTBouyService{
 		service: &service{
 			client:  c,
-			baseURL: rtNDBCBouyDataURL,
+			baseURL: ndbcURL + rtNDBCBouyDataPath,
 		},
 	}
 	c.RTWeather = &RTWeatherService{
 		service: &service{
 			client:  c,
-			baseURL: rtWeatherURL,
+			baseURL: nwsURL + rtWeatherPath,
+		},
+	}
+	c.Forecast = &ForecastService{
+		service: &service{
+			client:  c,
+			baseURL: nwsURL + nwsPointsPath,
 		},
 	}
 	return c
@@ -65,9 +141,77 @@ func NewClient() *Client {
 // get takes context and an id (either a stationId or a bouyId- this may be expanded upon later).
 // Sends an httml request to the designated baseURL.
 // get returns the raw data of the html request in a slice of bytes - type []byte.
+// The request is conditional: if the resource has not changed since the
+// last successful get, get returns ErrNotModified.
 func (s *service) get(ctx context.Context, id string) ([]byte, error) {
-	url := fmt.Sprintf(s.baseURL, id)
-	fmt.Println(url)
+	return s.client.do(ctx, s.url(id), !isUnconditional(ctx))
+}
+
+// url returns the URL of id.
+func (s *service) url(id string) string {
+	return fmt.Sprintf(s.baseURL, id)
+}
+
+// StatusError is returned when an upstream responds with a status other
+// than 200 OK.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// RetryAfter is the delay requested by a Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %s", e.Status)
+}
+
+// log returns the logger of the ingestion run ctx belongs to, so upstream
+// requests are logged with their run id, or the client's own.
+func (c *Client) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, c.logger)
+}
+
+// fetch sends a GET request to url and returns the response body.
+func (c *Client) fetch(ctx context.Context, url string) ([]byte, error) {
+	return c.do(ctx, url, false)
+}
+
+// do sends a GET request to url, conditional on the stored validators if
+// conditional is set. Requests are rate limited per host, and 5xx, 429 and
+// timeout failures are retried with exponential backoff.
+func (c *Client) do(ctx context.Context, url string, conditional bool) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		body, err := c.fetchOnce(ctx, url, conditional)
+		if err == nil {
+			return body, nil
+		}
+		if attempt >= c.retries || !retryable(ctx, err) {
+			return nil, err
+		}
+
+		delay := c.retryDelay(attempt)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+			if statusErr.RetryAfter > c.maxBackoff {
+				return nil, fmt.Errorf("%w (retry after %s)", err, statusErr.RetryAfter)
+			}
+			delay = max(delay, statusErr.RetryAfter)
+		}
+		c.log(ctx).Warn("retrying upstream request", "url", url, "attempt", attempt+1, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// fetchOnce sends a single GET request to url.
+func (c *Client) fetchOnce(ctx context.Context, url string, conditional bool) (body []byte, err error) {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
@@ -77,18 +221,122 @@ func (s *service) get(ctx context.Context, id string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	// fmt.Println("API get request urls: ", req.URL)
+	req.Header.Set("User-Agent", c.userAgent)
+	if conditional {
+		c.validators.apply(req, url)
+	}
+
+	if err := c.limiter.wait(ctx, req.URL.Host); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	host := req.URL.Host
+	defer func() {
+		metrics.UpstreamDuration.WithLabelValues(host).Observe(time.Since(start).Seconds())
+		result := "ok"
+		switch {
+		case errors.Is(err, ErrNotModified):
+			result = "not_modified"
+		case err != nil:
+			result = "error"
+		}
+		metrics.UpstreamRequests.WithLabelValues(host, result).Inc()
+	}()
 
-	resp, err := s.client.httpClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	c.log(ctx).Debug("upstream request", "url", url, "status", resp.StatusCode, "duration", time.Since(start))
 
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		return nil, ErrNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %s", resp.Status)
+		return nil, &StatusError{
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
-	return io.ReadAll(resp.Body)
+	c.archivePayload(ctx, url, body)
+	// Validators are only recorded for requests that may use them, so a
+	// full download elsewhere cannot hide a change from a conditional one.
+	if conditional || isUnconditional(ctx) {
+		v := responseValidator(resp.Header)
+		if pending := pendingValidators(ctx); pending != nil {
+			pending.add(c.validators, url, v)
+		} else if err := c.validators.update(url, v); err != nil {
+			c.log(ctx).Warn("could not save validators", "url", url, "error", err)
+		}
+	}
+	return body, nil
+}
+
+// archivePayload stores body in the archive, if there is one. A failure
+// is logged rather than returned so the data is still ingested.
+func (c *Client) archivePayload(ctx context.Context, url string, body []byte) {
+	if c.archive == nil {
+		return
+	}
+	source := archive.SourceNWS
+	if strings.HasPrefix(url, c.ndbcURL) {
+		source = archive.SourceNDBC
+	}
+	p := archive.Payload{Source: source, Key: url, FetchedAt: time.Now().UTC(), Body: body}
+	if err := c.archive.Put(ctx, p); err != nil {
+		c.log(ctx).Warn("could not archive payload", "url", url, "error", err)
+	}
+}
+
+// retryable reports whether a failed request is worth sending again.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// retryDelay returns the backoff before retry number attempt+1: the base
+// delay doubled per attempt, capped at maxBackoff, with the upper half
+// randomised so clients that failed together do not retry together.
+func (c *Client) retryDelay(attempt int) time.Duration {
+	d := c.backoff << attempt
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as
+// an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
 }
 
 type BouyObservation struct {
@@ -114,9 +362,30 @@ func (s *RTBouyService) getData(ctx context.Context, bouyId string) ([]byte, err
 // parseBouyObservation takes raw data as a byte slice that
 // is returned by a get() method and parses the data into a
 // BouyObservation struct. It returns a pointer to a BouyObservation struct and an error.
+// Only the newest row of the file is parsed.
 func (s *RTBouyService) parseBuoyObservation(data []byte, bouyId string) (*BouyObservation, error) {
+	history, err := parseBuoyRows(data, bouyId, 1)
+	if err != nil {
+		return &BouyObservation{}, err
+	}
+	if len(history) == 0 {
+		return nil, nil
+	}
+	return history[0], nil
+}
+
+// parseBuoyRows parses up to limit data rows of an NDBC realtime2 file,
+// newest first. A limit of 0 parses every row.
+func parseBuoyRows(data []byte, bouyId string, limit int) ([]*BouyObservation, error) {
 	scanner := bufio.NewScanner(bytes.NewReader(data))
-	var obs *BouyObservation
+	var rows []*BouyObservation
+
+	// bouyId convert to int
+	id, err := strconv.Atoi(bouyId)
+	if err != nil {
+		return nil, err
+	}
+	insertedAt := time.Now().UTC()
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -126,18 +395,15 @@ func (s *RTBouyService) parseBuoyObservation(data []byte, bouyId string) (*BouyO
 		}
 
 		fields := strings.Fields(line)
+		if len(fields) < 15 {
+			return nil, fmt.Errorf("buoy %s: expected 15+ columns, got %d", bouyId, len(fields))
+		}
 
 		// Format time.
 		timeLayout := "2006 01 02 15 04"
 		timestamp, err := time.Parse(timeLayout, strings.Join(fields[:5], " "))
 		if err != nil {
-			return &BouyObservation{}, err
-		}
-
-		// bouyId convert to string
-		id, err := strconv.Atoi(bouyId)
-		if err != nil {
-			return &BouyObservation{}, err
+			return nil, err
 		}
 
 		// safely parse datatypes
@@ -152,7 +418,7 @@ func (s *RTBouyService) parseBuoyObservation(data []byte, bouyId string) (*BouyO
 		waterTemperature, _ := parseDataFloat(fields[14])
 
 		// build BouyObservation
-		obs = &BouyObservation{
+		rows = append(rows, &BouyObservation{
 			BuoyID:                id,
 			RecordedAt:            timestamp,
 			WindDirectionDegT:     windDirection,
@@ -164,11 +430,19 @@ func (s *RTBouyService) parseBuoyObservation(data []byte, bouyId string) (*BouyO
 			MeanWaveDirectionDegT: meanWaveDirection,
 			AirTempDegC:           airTemperature,
 			WaterTempDegC:         waterTemperature,
-			InsertedAt:            time.Now().UTC(),
+			InsertedAt:            insertedAt,
+		})
+		if limit > 0 && len(rows) == limit {
+			break
 		}
-		break
 	}
-	return obs, nil
+	return rows, scanner.Err()
+}
+
+// ParseBuoyFile parses every data row of an NDBC realtime2 file for
+// bouyId, newest first.
+func ParseBuoyFile(data []byte, bouyId string) ([]*BouyObservation, error) {
+	return parseBuoyRows(data, bouyId, 0)
 }
 
 // GetObservation takes context.Context and a string.
@@ -180,19 +454,34 @@ func (s *RTBouyService) GetObservation(ctx context.Context, bouyId string) (*Bou
 		return &BouyObservation{}, err
 	}
 	obs, err := s.parseBuoyObservation(data, bouyId)
+	if err != nil || obs == nil {
+		discardValidators(ctx, s.url(bouyId))
+	}
 	if err != nil {
 		return &BouyObservation{}, err
 	}
 	return obs, nil
 }
 
+// GetHistory returns every observation in the buoy's realtime2 file
+// (roughly the last 45 days), newest first.
+func (s *RTBouyService) GetHistory(ctx context.Context, bouyId string) ([]*BouyObservation, error) {
+	data, err := s.client.fetch(ctx, fmt.Sprintf(s.baseURL, bouyId))
+	if err != nil {
+		return nil, err
+	}
+	return parseBuoyRows(data, bouyId, 0)
+}
+
 type WeatherObservation struct {
-	Properties properties `json:"properties"`
+	// Geometry is the position of the station, as [longitude, latitude].
+	Geometry   models.PointGeometry `json:"geometry"`
+	Properties properties           `json:"properties"`
 	RecordedAt time.Time
 }
 
 type properties struct {
-	Timestamp     string       `josn:"timestamp"`
+	Timestamp     string       `json:"timestamp"`
 	Temperature   Value        `json:"temperature"`
 	WindSpeed     Value        `json:"windSpeed"`
 	WindDirection Value        `json:"windDirection"`
@@ -214,14 +503,21 @@ func (s *RTWeatherService) getData(ctx context.Context, stationId string) ([]byt
 	return s.get(ctx, stationId)
 }
 
-// parseWeatherObservation takes raw data as a byte slice that
+// ParseWeatherObservation takes raw data as a byte slice that
 // is returned by a get() method and parses the json into a
 // WeatherObservation struct. It returns a WeatherObservation struct and an error.
-func parseWeatherObservation(data []byte) (*WeatherObservation, error) {
+func ParseWeatherObservation(data []byte) (*WeatherObservation, error) {
 	var obs WeatherObservation
 	if err := json.Unmarshal(data, &obs); err != nil {
 		return &WeatherObservation{}, err
 	}
+	if obs.Properties.Timestamp != "" {
+		recordedAt, err := time.Parse(time.RFC3339, obs.Properties.Timestamp)
+		if err != nil {
+			return &WeatherObservation{}, fmt.Errorf("invalid observation timestamp: %w", err)
+		}
+		obs.RecordedAt = recordedAt.UTC()
+	}
 	return &obs, nil
 }
 
@@ -233,8 +529,9 @@ func (s *RTWeatherService) GetObservation(ctx context.Context, stationId string)
 	if err != nil {
 		return &WeatherObservation{}, err
 	}
-	obs, err := parseWeatherObservation(data)
+	obs, err := ParseWeatherObservation(data)
 	if err != nil {
+		discardValidators(ctx, s.url(stationId))
 		return &WeatherObservation{}, err
 	}
 
@@ -1,20 +1,227 @@
 package meteo
 
 import (
+	"Go_surf_redesign/src/backend/api/meteotest"
+	"Go_surf_redesign/src/config"
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
-// takes an input file with bouy ids.
-// If pass: returns true
-// If fail: returns false and an error
+// GetObservation parses the newest row of a recorded realtime2 file.
+// Re-record the fixture with GOSURF_RECORD=1 to check against the live
+// server.
 func TestRTBouyGetObservation(t *testing.T) {
 	ctx := context.Background()
 	bouyId := "46086"
 
-	client := NewClient()
-	_, err := client.RTBouy.GetObservation(ctx, bouyId)
+	cfg := config.Default().Upstream
+	cfg.ValidatorFile = ""
+	client := NewClient(cfg, WithTransport(meteotest.Transport(t, "testdata/replay")))
+	obs, err := client.RTBouy.GetObservation(ctx, bouyId)
 	if err != nil {
-		t.Errorf("meteo.RTBouy.GetObservation failed: %s", err)
+		t.Fatalf("meteo.RTBouy.GetObservation failed: %s", err)
+	}
+	if obs == nil || obs.BuoyID != 46086 || obs.RecordedAt.IsZero() {
+		t.Fatalf("unexpected observation %+v", obs)
+	}
+	if os.Getenv(meteotest.RecordEnv) == "" {
+		want := time.Date(2025, 6, 1, 12, 50, 0, 0, time.UTC)
+		if !obs.RecordedAt.Equal(want) || obs.WaveHeightM == nil || *obs.WaveHeightM != 1.2 {
+			t.Errorf("got recorded_at %v, wave height %v; want %v, 1.2", obs.RecordedAt, obs.WaveHeightM, want)
+		}
+	}
+}
+
+// Batch fetches report each buoy's outcome separately: missing values
+// parse as nil, and 404s, exhausted 5xx retries and empty files are errors.
+func TestRTBouyGetObservationsAgainstFake(t *testing.T) {
+	ndbc := meteotest.NewNDBC(t)
+	ndbc.SetBuoy("46086", meteotest.BuoyFile())
+	ndbc.SetBuoy("46222", "#YY  MM DD hh mm\n#yr  mo dy hr mn\n")
+	ndbc.SetBuoy("46225", meteotest.BuoyFile())
+	ndbc.Fail(meteotest.BuoyPath("46225"), http.StatusBadGateway)
+
+	client := NewClient(meteotest.Upstream(ndbc, nil))
+	obs, errs := client.RTBouy.GetObservations(context.Background(), []string{"46086", "46222", "46225", "46999"})
+
+	if len(obs) != 1 || obs["46086"] == nil {
+		t.Fatalf("want one observation, got %v (errors %v)", obs, errs)
+	}
+	if !errors.Is(errs["46222"], ErrNoObservations) {
+		t.Errorf("46222: want ErrNoObservations, got %v", errs["46222"])
+	}
+	var statusErr *StatusError
+	if !errors.As(errs["46225"], &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("46225: want a 502 StatusError, got %v", errs["46225"])
+	}
+	if n := ndbc.Requests(meteotest.BuoyPath("46225")); n != 4 {
+		t.Errorf("46225: want 1 request and 3 retries, got %d requests", n)
+	}
+	if !errors.As(errs["46999"], &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("46999: want a 404 StatusError, got %v", errs["46999"])
+	}
+
+	history, err := client.RTBouy.GetHistory(context.Background(), "46086")
+	if err != nil || len(history) != 4 {
+		t.Fatalf("want 4 history rows, got %d (%v)", len(history), err)
+	}
+	if history[2].WaveHeightM != nil {
+		t.Errorf("missing wave height parsed as %v", *history[2].WaveHeightM)
+	}
+}
+
+// Station lookup, observations and forecasts resolve through the NWS
+// points API of the fake.
+func TestNWSAgainstFake(t *testing.T) {
+	nws := meteotest.NewNWS(t)
+	nws.AddStation(meteotest.Station{ID: "KFAR", Lat: 34.5, Lon: -119.5}, meteotest.Observation())
+	nws.AddStation(meteotest.Station{ID: "KNEAR", Lat: 33.8, Lon: -118.2}, meteotest.Observation())
+
+	client := NewClient(meteotest.Upstream(nil, nws))
+	ctx := context.Background()
+
+	station, err := client.RTWeather.NearestStation(ctx, 33.77, -118.19)
+	if err != nil || station != "KNEAR" {
+		t.Fatalf("NearestStation = %q, %v; want KNEAR", station, err)
+	}
+
+	obs, err := client.RTWeather.GetObservation(ctx, station)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obs.Properties.WindSpeed.Value == nil || *obs.Properties.WindSpeed.Value != 14.8 {
+		t.Errorf("unexpected wind speed %v", obs.Properties.WindSpeed.Value)
+	}
+
+	forecast, err := client.Forecast.GetHourlyForecast(ctx, 33.77, -118.19)
+	if err != nil || len(forecast.Properties.Periods) != 2 {
+		t.Fatalf("want 2 forecast periods, got %v (%v)", forecast, err)
+	}
+}
+
+// fetch retries 5xx responses, sends the configured User-Agent and gives up
+// at once on other errors.
+func TestFetchRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "gosurf-test" {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case calls.Add(1) < 3:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.Default().Upstream
+	cfg.UserAgent = "gosurf-test"
+	cfg.Backoff = config.Duration{Duration: time.Millisecond}
+	cfg.HostInterval = config.Duration{}
+	client := NewClient(cfg)
+
+	body, err := client.fetch(context.Background(), srv.URL+"/flaky")
+	if err != nil || string(body) != "ok" || calls.Load() != 3 {
+		t.Fatalf("got %q, %v after %d calls; want ok after 3", body, err, calls.Load())
+	}
+
+	_, err = client.fetch(context.Background(), srv.URL+"/missing")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("want a 404 StatusError, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("404 was retried")
+	}
+}
+
+// A conditional get sends the stored validators, maps 304 to ErrNotModified
+// and keeps the validators across clients through the validator file.
+func TestConditionalGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("data"))
+	}))
+	defer srv.Close()
+
+	cfg := config.Default().Upstream
+	cfg.ValidatorFile = t.TempDir() + "/validators.json"
+	s := &service{client: NewClient(cfg), baseURL: srv.URL + "/%s"}
+
+	ctx := context.Background()
+	if _, err := s.get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.get(ctx, "a"); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("second get: want ErrNotModified, got %v", err)
+	}
+
+	s.client = NewClient(cfg)
+	if _, err := s.get(ctx, "a"); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("after restart: want ErrNotModified, got %v", err)
+	}
+	if body, err := s.get(Unconditional(ctx), "a"); err != nil || string(body) != "data" {
+		t.Fatalf("unconditional get: got %q, %v", body, err)
+	}
+}
+
+// Deferred validators are only recorded on Commit, and not at all for a
+// response that failed to parse.
+func TestDeferredValidators(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.URL.Path == "/bad" {
+			w.Write([]byte("not json"))
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	cfg := config.Default().Upstream
+	cfg.ValidatorFile = t.TempDir() + "/validators.json"
+	c := NewClient(cfg)
+	weather := &RTWeatherService{service: &service{client: c, baseURL: srv.URL + "/%s"}}
+	ctx := context.Background()
+
+	deferred, pending := DeferValidators(ctx)
+	if _, err := weather.GetObservation(deferred, "good"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := weather.GetObservation(deferred, "bad"); err == nil {
+		t.Fatal("want a parse error")
+	}
+	again, _ := DeferValidators(ctx)
+	if _, err := weather.GetObservation(again, "good"); err != nil {
+		t.Fatalf("before commit: %v", err)
+	}
+
+	if err := pending.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := weather.GetObservation(ctx, "good"); !errors.Is(err, ErrNotModified) {
+		t.Errorf("after commit: want ErrNotModified, got %v", err)
+	}
+	if _, err := weather.GetObservation(ctx, "bad"); errors.Is(err, ErrNotModified) {
+		t.Error("validators of an unparsable response were recorded")
 	}
 }
@@ -0,0 +1,115 @@
+package meteo
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	playground "github.com/go-playground/validator/v10"
+)
+
+// Error codes sent in apiError.Code. Clients should branch on the code,
+// not on the message.
+const (
+	codeInvalidArgument = "invalid_argument"
+	codeUnauthenticated = "unauthenticated"
+	codeNotFound        = "not_found"
+	codeInternal        = "internal"
+)
+
+// apiError is the body of every error response, wrapped in an object
+// under "error".
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Details holds the invalid fields of a request that failed
+	// validation.
+	Details   []fieldError `json:"details,omitempty"`
+	RequestID string       `json:"requestId"`
+}
+
+type apiErrorResponse struct {
+	Error apiError `json:"error"`
+}
+
+// fieldError describes one invalid path or query parameter.
+type fieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// abortWithError ends the request with an error response.
+func abortWithError(c *gin.Context, status int, code, message string, details ...fieldError) {
+	c.AbortWithStatusJSON(status, apiErrorResponse{Error: apiError{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: c.GetString(requestIDKey),
+	}})
+}
+
+// internalError ends the request with a 500. err is attached to the
+// request for the log; the client only sees message.
+func internalError(c *gin.Context, err error, message string) {
+	c.Error(err)
+	abortWithError(c, http.StatusInternalServerError, codeInternal, message)
+}
+
+// notFound ends the request with a 404.
+func notFound(c *gin.Context, message string) {
+	abortWithError(c, http.StatusNotFound, codeNotFound, message)
+}
+
+// invalidRequest ends the request with a 400 describing err, the error
+// returned by binding the request's parameters.
+func invalidRequest(c *gin.Context, err error) {
+	var verrs playground.ValidationErrors
+	if !errors.As(err, &verrs) {
+		// The parameter could not be parsed at all, for example a
+		// non-numeric id.
+		abortWithError(c, http.StatusBadRequest, codeInvalidArgument, "malformed request parameter")
+		return
+	}
+	details := make([]fieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		details = append(details, fieldError{Field: fe.Field(), Reason: validationReason(fe)})
+	}
+	abortWithError(c, http.StatusBadRequest, codeInvalidArgument, "invalid request parameters", details...)
+}
+
+// validationReason describes a failed validation rule.
+func validationReason(fe playground.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "datetime":
+		return "must be a date like " + fe.Param()
+	}
+	return "failed the " + fe.Tag() + " check"
+}
+
+func init() {
+	// Report fields by their parameter names rather than their Go names.
+	if v, ok := binding.Validator.Engine().(*playground.Validate); ok {
+		v.RegisterTagNameFunc(paramName)
+	}
+}
+
+// paramName returns the uri or form name of a struct field.
+func paramName(f reflect.StructField) string {
+	for _, tag := range []string{"uri", "form"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
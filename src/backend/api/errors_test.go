@@ -0,0 +1,75 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Every error is an envelope carrying a code, the invalid fields and the
+// request ID sent in the X-Request-ID header.
+func TestErrorEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(memory.Demo(), adminConfig(), nil)
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		code   string
+		field  string
+	}{
+		{"/v1/surfspots/abc", http.StatusBadRequest, codeInvalidArgument, ""},
+		{"/v1/surfspots/0", http.StatusBadRequest, codeInvalidArgument, "cityID"},
+		{"/v1/admin/ingestion?limit=500", http.StatusBadRequest, codeInvalidArgument, "limit"},
+		{"/v1/tides?date=01/02/2026", http.StatusBadRequest, codeInvalidArgument, "date"},
+		{"/v1/surfforecast/current/999", http.StatusNotFound, codeNotFound, ""},
+		{"/v2/cities", http.StatusNotFound, codeNotFound, ""},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, adminRequest(tc.path))
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.path, rec.Code, tc.status)
+			continue
+		}
+		var body apiErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v", tc.path, err)
+		}
+		e := body.Error
+		if e.Code != tc.code || e.Message == "" || e.RequestID != rec.Header().Get(requestIDHeader) {
+			t.Errorf("%s: %+v", tc.path, e)
+		}
+		if tc.field != "" && (len(e.Details) != 1 || e.Details[0].Field != tc.field) {
+			t.Errorf("%s: details %+v, want field %s", tc.path, e.Details, tc.field)
+		}
+	}
+}
+
+// The unversioned routes still work but point clients at /v1.
+func TestDeprecatedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(memory.Demo(), config.Default(), nil)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/surfspots/4", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if rec.Header().Get("Deprecation") == "" || rec.Header().Get("Sunset") == "" {
+		t.Errorf("missing deprecation headers: %v", rec.Header())
+	}
+	if link := rec.Header().Get("Link"); link != `</v1/surfspots/4>; rel="successor-version"` {
+		t.Errorf("Link %q", link)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/surfspots/4", nil))
+	if rec.Header().Get("Deprecation") != "" {
+		t.Error("/v1 is marked deprecated")
+	}
+}
@@ -0,0 +1,35 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type ForecastService struct {
+	*service
+}
+
+// GetHourlyForecast resolves the NWS forecast grid for a coordinate and
+// returns the hourly forecast for that grid (about seven days of periods).
+func (s *ForecastService) GetHourlyForecast(ctx context.Context, lat, lon float64) (*models.HourlyWeatherForecast, error) {
+	point, err := s.client.getPoint(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	if point.Properties.ForecastHourly == "" {
+		return nil, fmt.Errorf("no hourly forecast for %.4f,%.4f", lat, lon)
+	}
+
+	data, err := s.client.fetch(ctx, point.Properties.ForecastHourly)
+	if err != nil {
+		return nil, err
+	}
+
+	var forecast models.HourlyWeatherForecast
+	if err := json.Unmarshal(data, &forecast); err != nil {
+		return nil, fmt.Errorf("could not parse hourly forecast: %w", err)
+	}
+	return &forecast, nil
+}
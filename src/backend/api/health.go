@@ -0,0 +1,182 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readyTimeout bounds the database checks behind /readyz.
+const readyTimeout = 2 * time.Second
+
+// getHealthz - reports that the process is up. It never touches the store.
+func (h *Handler) getHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// getReadyz - reports whether the store is reachable and, for stores with
+// a schema, fully migrated. It responds 503 otherwise. Store errors are
+// logged rather than sent, since /readyz is public.
+func (h *Handler) getReadyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyTimeout)
+	defer cancel()
+
+	if err := h.store.Ping(ctx); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "unavailable",
+			"error":  "store unreachable",
+		})
+		return
+	}
+	if migrator, ok := h.store.(store.Migrator); ok {
+		current, latest, err := migrator.SchemaVersion(ctx)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status": "unavailable",
+				"error":  "could not read schema version",
+			})
+			return
+		}
+		if current < latest {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":        "unavailable",
+				"error":         "schema is behind",
+				"schemaVersion": current,
+				"latestVersion": latest,
+			})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+type apiSourceStatus struct {
+	Source     string     `json:"source"`
+	Newest     *time.Time `json:"newest"`
+	AgeMinutes *int       `json:"ageMinutes"`
+	MaxAge     string     `json:"maxAge"`
+	// Reporting counts the buoys, stations or spots with data newer than
+	// MaxAge, out of Total.
+	Reporting int  `json:"reporting"`
+	Total     int  `json:"total"`
+	Stale     bool `json:"stale"`
+}
+
+type apiDataStatus struct {
+	Status    string            `json:"status"`
+	CheckedAt time.Time         `json:"checkedAt"`
+	Sources   []apiSourceStatus `json:"sources"`
+}
+
+// sourceStatus summarises the observation times of one source.
+func sourceStatus(source string, times []time.Time, total int, maxAge time.Duration, now time.Time) apiSourceStatus {
+	s := apiSourceStatus{Source: source, MaxAge: maxAge.String(), Total: total}
+	for _, t := range times {
+		if s.Newest == nil || t.After(*s.Newest) {
+			newest := t.UTC()
+			s.Newest = &newest
+		}
+		if now.Sub(t) <= maxAge {
+			s.Reporting++
+		}
+	}
+	if s.Newest != nil {
+		age := int(now.Sub(*s.Newest).Minutes())
+		s.AgeMinutes = &age
+	}
+	s.Stale = s.Newest == nil || now.Sub(*s.Newest) > maxAge
+	return s
+}
+
+// getDataStatus - reports the newest observation of the buoy, weather and
+// conditions data and how many buoys, stations and spots are reporting.
+// It responds 503 when any source is older than its configured max age.
+func (h *Handler) getDataStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	fail := func(err error) {
+		internalError(c, err, "failed to read data status")
+	}
+	buoys, err := h.store.Buoys(ctx)
+	if err != nil {
+		fail(err)
+		return
+	}
+	cities, err := h.store.Cities(ctx)
+	if err != nil {
+		fail(err)
+		return
+	}
+	spots, err := h.store.Spots(ctx)
+	if err != nil {
+		fail(err)
+		return
+	}
+	conditions, err := h.store.AllConditions(ctx)
+	if err != nil {
+		fail(err)
+		return
+	}
+	buoyObs, err := h.store.LatestBuoyObservations(ctx)
+	if err != nil {
+		fail(err)
+		return
+	}
+	weather, err := h.store.LatestWeatherObservations(ctx)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	var buoyTimes []time.Time
+	for _, obs := range buoyObs {
+		buoyTimes = append(buoyTimes, obs.RecordedAt)
+	}
+
+	// Cities sharing a station share its observation, so count stations.
+	stations := make(map[string]bool)
+	for _, city := range cities {
+		if city.WeatherStation != nil {
+			stations[*city.WeatherStation] = true
+		}
+	}
+	stationTimes := make(map[string]time.Time)
+	for _, obs := range weather {
+		if obs.RecordedAt.After(stationTimes[obs.Station]) {
+			stationTimes[obs.Station] = obs.RecordedAt
+		}
+	}
+	var weatherTimes []time.Time
+	for _, t := range stationTimes {
+		weatherTimes = append(weatherTimes, t)
+	}
+
+	var conditionTimes []time.Time
+	for _, c := range conditions {
+		conditionTimes = append(conditionTimes, c.RecordedAt)
+	}
+
+	now := time.Now()
+	limits := h.cfg.Status
+	status := apiDataStatus{
+		Status:    "ok",
+		CheckedAt: now.UTC(),
+		Sources: []apiSourceStatus{
+			sourceStatus("buoys", buoyTimes, len(buoys), limits.BuoysMaxAge.Duration, now),
+			sourceStatus("weather", weatherTimes, len(stations), limits.WeatherMaxAge.Duration, now),
+			sourceStatus("conditions", conditionTimes, len(spots), limits.ConditionsMaxAge.Duration, now),
+		},
+	}
+	code := http.StatusOK
+	for _, s := range status.Sources {
+		if s.Stale {
+			status.Status = "stale"
+			code = http.StatusServiceUnavailable
+		}
+	}
+	c.JSON(code, status)
+}
@@ -0,0 +1,81 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHealthAndReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(memory.Demo(), config.Default(), nil)
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if code := get(t, router, path, nil); code != http.StatusOK {
+			t.Errorf("%s: status %d", path, code)
+		}
+	}
+
+	// The store's error is logged, not sent.
+	rec := httptest.NewRecorder()
+	NewRouter(failingStore{memory.Demo()}, config.Default(), nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable || strings.Contains(rec.Body.String(), "10.0.0.5") {
+		t.Errorf("unreachable store: %d %s", rec.Code, rec.Body)
+	}
+}
+
+// The demo data is ten minutes old: fresh by default, stale once the
+// thresholds are lowered below that.
+func TestDataStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st := memory.Demo()
+
+	var status apiDataStatus
+	if code := get(t, NewRouter(st, config.Default(), nil), "/v1/status/data", &status); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if status.Status != "ok" || len(status.Sources) != 3 {
+		t.Fatalf("got %+v", status)
+	}
+	for _, s := range status.Sources {
+		if s.Newest == nil || s.Reporting == 0 || s.Reporting > s.Total {
+			t.Errorf("%s: %+v", s.Source, s)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Status.BuoysMaxAge = config.Duration{Duration: time.Minute}
+	if code := get(t, NewRouter(st, cfg, nil), "/v1/status/data", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("with a one minute limit: status %d, want 503", code)
+	}
+}
+
+// failingStore is the demo store with a broken conditions table and
+// database connection.
+type failingStore struct {
+	*memory.Store
+}
+
+func (failingStore) Ping(context.Context) error {
+	return errors.New("dial tcp 10.0.0.5:5432: connection refused")
+}
+
+func (failingStore) AllConditions(context.Context) ([]models.CurrentSurfSpotConditions, error) {
+	return nil, errors.New("relation current_surf_spot_conditions does not exist")
+}
+
+func TestDataStatusStoreError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(failingStore{memory.Demo()}, config.Default(), nil)
+	if code := get(t, router, "/v1/status/data", nil); code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500", code)
+	}
+}
@@ -0,0 +1,201 @@
+package meteo
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPageSize is the page size of the list endpoints without ?limit.
+const defaultPageSize = 100
+
+// Sort orders of the list endpoints. Distance needs ?near; score orders
+// spots best first.
+const (
+	sortName     = "name"
+	sortDistance = "distance"
+	sortScore    = "score"
+)
+
+// pageParams are the query parameters shared by the list endpoints.
+// Limit is at most 500. Cursor is the opaque position after which the page starts, taken from
+// the previous page's next link.
+type pageParams struct {
+	Limit  int    `form:"limit" binding:"min=1,max=500"`
+	Cursor string `form:"cursor"`
+}
+
+// listKey is the position of an item in a sorted list. Items are ordered
+// by Num, with nil last, then by Text and then by ID, so every item has
+// its own position.
+type listKey struct {
+	Num  *float64 `json:"n,omitempty"`
+	Text string   `json:"t,omitempty"`
+	ID   int      `json:"i"`
+}
+
+func (k listKey) compare(o listKey) int {
+	switch {
+	case k.Num == nil && o.Num != nil:
+		return 1
+	case k.Num != nil && o.Num == nil:
+		return -1
+	case k.Num != nil:
+		if c := cmp.Compare(*k.Num, *o.Num); c != 0 {
+			return c
+		}
+	}
+	if c := cmp.Compare(k.Text, o.Text); c != 0 {
+		return c
+	}
+	return cmp.Compare(k.ID, o.ID)
+}
+
+// cursor is the decoded form of a page's cursor parameter. Sort guards
+// against a cursor being replayed with a different ordering.
+type cursor struct {
+	Sort  string  `json:"s"`
+	After listKey `json:"a"`
+}
+
+func (cur cursor) encode() string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, bool) {
+	var cur cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &cur) != nil {
+		return cursor{}, false
+	}
+	return cur, true
+}
+
+// listed is a list item with its position in the requested order.
+type listed[T any] struct {
+	Item T
+	key  listKey
+}
+
+// sortListed orders items by their keys.
+func sortListed[T any](items []listed[T]) {
+	slices.SortFunc(items, func(a, b listed[T]) int { return a.key.compare(b.key) })
+}
+
+// listPage is one page of a sorted list. Next is the position of the
+// page's last item when more items follow.
+type listPage[T any] struct {
+	Items []T
+	Total int
+	Next  *listKey
+}
+
+// pageOf returns the page of the sorted items that starts after the
+// position after, or the first page when after is nil.
+func pageOf[T any](items []listed[T], after *listKey, limit int) listPage[T] {
+	start := 0
+	if after != nil {
+		start, _ = slices.BinarySearchFunc(items, *after, func(item listed[T], after listKey) int {
+			// Items at the cursor belong to the previous page.
+			if item.key.compare(after) <= 0 {
+				return -1
+			}
+			return 1
+		})
+	}
+	end := min(start+limit, len(items))
+	return keysetPage(items[start:end+min(1, len(items)-end)], limit, len(items))
+}
+
+// keysetPage returns the page made of the first limit items, given up to
+// one item more to tell whether another page follows.
+func keysetPage[T any](items []listed[T], limit, total int) listPage[T] {
+	page := listPage[T]{Items: make([]T, 0, min(limit, len(items))), Total: total}
+	for _, item := range items[:min(limit, len(items))] {
+		page.Items = append(page.Items, item.Item)
+	}
+	if len(items) > limit {
+		page.Next = &items[limit-1].key
+	}
+	return page
+}
+
+// bindCursor decodes the cursor parameter of a list in sort order, nil
+// for the first page. An invalid cursor ends the request with a 400 and
+// returns false.
+func bindCursor(c *gin.Context, s, sort string) (*listKey, bool) {
+	if s == "" {
+		return nil, true
+	}
+	cur, ok := decodeCursor(s)
+	if !ok || cur.Sort != sort {
+		abortWithError(c, http.StatusBadRequest, codeInvalidArgument, "invalid request parameters",
+			fieldError{Field: "cursor", Reason: "is not a cursor for this list and sort"})
+		return nil, false
+	}
+	return &cur.After, true
+}
+
+// respondPage sends page, setting X-Total-Count to the number of items in
+// the list and, when more follow, adding a Link to the next page.
+func respondPage[T any](c *gin.Context, page listPage[T], sort string) {
+	c.Header("X-Total-Count", strconv.Itoa(page.Total))
+	if page.Next != nil {
+		next := *c.Request.URL
+		query := next.Query()
+		query.Set("cursor", cursor{Sort: sort, After: *page.Next}.encode())
+		next.RawQuery = query.Encode()
+		// Add, since deprecated versions already link their successor.
+		c.Writer.Header().Add("Link", "<"+next.RequestURI()+`>; rel="next"`)
+	}
+	c.JSON(http.StatusOK, page.Items)
+}
+
+// point is a position given as a "lat,lon" query parameter.
+type point struct {
+	Latitude, Longitude float64
+}
+
+// parseNear parses the near parameter. An empty value returns nil.
+func parseNear(s string) (*point, bool) {
+	if s == "" {
+		return nil, true
+	}
+	lat, lon, ok := strings.Cut(s, ",")
+	if !ok {
+		return nil, false
+	}
+	var p point
+	var err error
+	if p.Latitude, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil || p.Latitude < -90 || p.Latitude > 90 {
+		return nil, false
+	}
+	if p.Longitude, err = strconv.ParseFloat(strings.TrimSpace(lon), 64); err != nil || p.Longitude < -180 || p.Longitude > 180 {
+		return nil, false
+	}
+	return &p, true
+}
+
+// bindNear parses the near parameter and checks that a distance sort has
+// one. On failure it ends the request with a 400 and returns false.
+func bindNear(c *gin.Context, near, sort string) (*point, bool) {
+	p, ok := parseNear(near)
+	if !ok {
+		abortWithError(c, http.StatusBadRequest, codeInvalidArgument, "invalid request parameters",
+			fieldError{Field: "near", Reason: `must be "latitude,longitude"`})
+		return nil, false
+	}
+	if p == nil && sort == sortDistance {
+		abortWithError(c, http.StatusBadRequest, codeInvalidArgument, "invalid request parameters",
+			fieldError{Field: "near", Reason: "is required to sort by distance"})
+		return nil, false
+	}
+	return p, true
+}
@@ -0,0 +1,174 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var nextLink = regexp.MustCompile(`<([^>]+)>; rel="next"`)
+
+// Following the next links pages through the whole list once.
+func TestListPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(memory.Demo(), config.Default(), nil)
+
+	var names []string
+	path := "/v1/spots?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("too many pages")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, rec.Code)
+		}
+		if total := rec.Header().Get("X-Total-Count"); total != "3" {
+			t.Errorf("%s: X-Total-Count %q, want 3", path, total)
+		}
+		var page []apiSpot
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		for _, spot := range page {
+			names = append(names, spot.Name)
+		}
+
+		path = ""
+		if m := nextLink.FindStringSubmatch(rec.Header().Get("Link")); m != nil {
+			path = m[1]
+		}
+	}
+	want := []string{"Huntington Beach Pier", "Newport Pier", "The Wedge"}
+	if !slices.Equal(names, want) {
+		t.Errorf("names %v, want %v", names, want)
+	}
+
+	// A cursor only fits the sort it was made for.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/spots?limit=1", nil))
+	next := nextLink.FindStringSubmatch(rec.Header().Get("Link"))[1]
+	for _, path := range []string{next + "&sort=score", "/v1/spots?cursor=nonsense"} {
+		if code := get(t, router, path, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", path, code)
+		}
+	}
+}
+
+func TestListFiltersAndSorts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(memory.Demo(), config.Default(), nil)
+
+	spotIDs := func(path string) []int {
+		t.Helper()
+		var spots []apiSpot
+		if code := get(t, router, path, &spots); code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, code)
+		}
+		var ids []int
+		for _, spot := range spots {
+			ids = append(ids, spot.ID)
+		}
+		return ids
+	}
+	for path, want := range map[string][]int{
+		"/v1/spots?break_type=WEDGE":                       {10},
+		"/v1/spots?county=orange+county&state=california":  {5, 7, 10},
+		"/v1/spots?state=oregon":                           nil,
+		"/v1/spots?has_tide_station=false":                 nil,
+		"/v1/surfspots/4?sort=distance&near=33.59,-117.88": {10, 7},
+	} {
+		if got := spotIDs(path); !slices.Equal(got, want) {
+			t.Errorf("%s: %v, want %v", path, got, want)
+		}
+	}
+
+	var ranked []apiSpot
+	get(t, router, "/v1/spots?sort=score", &ranked)
+	if len(ranked) != 3 {
+		t.Fatalf("sort=score: %+v", ranked)
+	}
+	for i, spot := range ranked {
+		if spot.Score == nil || *spot.Score < 0 || *spot.Score > 10 {
+			t.Fatalf("sort=score: spot %d score %v", spot.ID, spot.Score)
+		}
+		if i > 0 && *spot.Score > *ranked[i-1].Score {
+			t.Errorf("sort=score: spot %d scores above spot %d", spot.ID, ranked[i-1].ID)
+		}
+	}
+
+	var cities []apiCity
+	get(t, router, "/v1/cities?sort=distance&near=33.62,-117.93", &cities)
+	if len(cities) != 2 || cities[0].Name != "Newport Beach" || cities[0].DistanceKm == nil {
+		t.Errorf("cities by distance: %+v", cities)
+	}
+
+	for _, path := range []string{
+		"/v1/cities?sort=distance",
+		"/v1/cities?sort=score",
+		"/v1/cities?near=north",
+		"/v1/spots?limit=501",
+	} {
+		if code := get(t, router, path, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", path, code)
+		}
+	}
+}
+
+// countingStore counts the spot lists read from the store.
+type countingStore struct {
+	*memory.Store
+	lists int
+}
+
+func (s *countingStore) ListSpots(ctx context.Context, f store.ListFilter, page store.Keyset) ([]store.Spot, int, error) {
+	s.lists++
+	return s.Store.ListSpots(ctx, f, page)
+}
+
+// Each page is cached with its own ETag, and lists with distances are not
+// cached at all.
+func TestListCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st := &countingStore{Store: memory.Demo()}
+	router := NewRouter(st, config.Default(), nil)
+
+	etag := func(path string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, rec.Code)
+		}
+		return rec.Header().Get("ETag")
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/spots?limit=2", nil))
+	first := rec.Header().Get("ETag")
+	second := etag(nextLink.FindStringSubmatch(rec.Header().Get("Link"))[1])
+	if first == "" || first == second {
+		t.Errorf("page ETags %q and %q", first, second)
+	}
+	if etag("/v1/spots?limit=2") != first || st.lists != 2 {
+		t.Errorf("first page read %d times, want it cached", st.lists)
+	}
+
+	st.lists = 0
+	for _, near := range []string{"33.6,-117.9", "33.6,-117.9", "33.61,-117.9"} {
+		etag("/v1/spots?near=" + near)
+	}
+	if st.lists != 3 {
+		t.Errorf("near lists read %d times, want 3", st.lists)
+	}
+}
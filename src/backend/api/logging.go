@@ -0,0 +1,97 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/logging"
+	"crypto/rand"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader carries the request ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDKey stores the request ID in the gin context.
+const requestIDKey = "requestID"
+
+// maxRequestIDLength bounds the request IDs accepted from clients.
+const maxRequestIDLength = 64
+
+// quietRoutes are polled by probes and scrapers, so they are only logged
+// at debug level.
+var quietRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// logRequests gives every request an ID, returned in the X-Request-ID
+// header, and a logger carrying it in the request context. An ID sent by
+// the client or a proxy is kept, so one ID follows a request across
+// services. The request is logged once it completes, with any errors the
+// handlers attached to it.
+func (h *Handler) logRequests(c *gin.Context) {
+	start := time.Now()
+	id := c.GetHeader(requestIDHeader)
+	if !validRequestID(id) {
+		id = rand.Text()
+	}
+	c.Header(requestIDHeader, id)
+	c.Set(requestIDKey, id)
+	logger := h.logger.With("request_id", id)
+	c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), logger))
+
+	c.Next()
+
+	status := c.Writer.Status()
+	attrs := []any{
+		"method", c.Request.Method,
+		"path", c.Request.URL.Path,
+		"route", c.FullPath(),
+		"status", status,
+		"duration", time.Since(start),
+		"bytes", c.Writer.Size(),
+		"client", c.ClientIP(),
+	}
+	if len(c.Errors) > 0 {
+		attrs = append(attrs, "error", strings.Join(c.Errors.Errors(), "; "))
+	}
+	level := slog.LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case quietRoutes[c.FullPath()]:
+		level = slog.LevelDebug
+	}
+	logger.Log(c.Request.Context(), level, "request", attrs...)
+}
+
+// recoverPanics turns a panicking handler into a 500 and logs the panic
+// with its stack.
+func (h *Handler) recoverPanics(c *gin.Context, recovered any) {
+	logging.FromContext(c.Request.Context(), h.logger).Error("panic serving request",
+		"panic", recovered,
+		"stack", string(debug.Stack()),
+	)
+	abortWithError(c, http.StatusInternalServerError, codeInternal, "internal error")
+}
+
+// validRequestID reports whether id is safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A usable request ID from the client is echoed back; a missing or unsafe
+// one is replaced with a generated ID.
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(memory.Demo(), config.Default(), nil)
+
+	for _, tc := range []struct {
+		sent string
+		keep bool
+	}{
+		{"", false},
+		{"edge-4f1c.2", true},
+		{"bad id\nwith newline", false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		if tc.sent != "" {
+			req.Header.Set(requestIDHeader, tc.sent)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		got := rec.Header().Get(requestIDHeader)
+		switch {
+		case tc.keep && got != tc.sent:
+			t.Errorf("sent %q: got %q", tc.sent, got)
+		case !tc.keep && (got == "" || got == tc.sent):
+			t.Errorf("sent %q: got %q, want a generated ID", tc.sent, got)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Package meteotest provides fake NDBC and NWS servers and a record/replay
+// transport so the upstream client and ingestion can be tested offline.
+package meteotest
+
+import (
+	"Go_surf_redesign/src/config"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	//go:embed testdata/buoy_realtime2.txt
+	buoyFile string
+	//go:embed testdata/observation.json
+	observationFile string
+	//go:embed testdata/forecast_hourly.json
+	forecastFile string
+)
+
+// BuoyFile returns a sample NDBC realtime2 file with four rows, newest
+// first. The third row has missing ("MM") wave values.
+func BuoyFile() string { return buoyFile }
+
+// Observation returns a sample api.weather.gov latest observation.
+func Observation() string { return observationFile }
+
+// HourlyForecast returns a sample api.weather.gov hourly forecast with two
+// periods.
+func HourlyForecast() string { return forecastFile }
+
+// Upstream returns the default upstream config pointed at the fakes, with
+// retry delays and rate limits small enough for tests. Either fake may be
+// nil to keep the real URL.
+func Upstream(ndbc *NDBC, nws *NWS) config.UpstreamConfig {
+	cfg := config.Default().Upstream
+	if ndbc != nil {
+		cfg.NDBCURL = ndbc.URL
+	}
+	if nws != nil {
+		cfg.NWSURL = nws.URL
+	}
+	cfg.Backoff = config.Duration{Duration: time.Millisecond}
+	cfg.MaxBackoff = config.Duration{Duration: 10 * time.Millisecond}
+	cfg.HostInterval = config.Duration{}
+	cfg.ValidatorFile = ""
+	return cfg
+}
+
+// responses is the state shared by both fakes: canned bodies, injected
+// failures and per-path request counts.
+type responses struct {
+	mu       sync.Mutex
+	failures map[string]int
+	requests map[string]int
+}
+
+func newResponses() responses {
+	return responses{failures: make(map[string]int), requests: make(map[string]int)}
+}
+
+// Fail makes every request for path answer with status until Recover is
+// called.
+func (r *responses) Fail(path string, status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures[path] = status
+}
+
+// Recover clears a failure set by Fail.
+func (r *responses) Recover(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.failures, path)
+}
+
+// Requests returns how many requests were made for path.
+func (r *responses) Requests(path string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests[path]
+}
+
+// begin counts the request and reports whether an injected failure was
+// written.
+func (r *responses) begin(w http.ResponseWriter, req *http.Request) bool {
+	r.mu.Lock()
+	r.requests[req.URL.Path]++
+	status, failed := r.failures[req.URL.Path]
+	r.mu.Unlock()
+	if failed {
+		w.WriteHeader(status)
+	}
+	return failed
+}
+
+// serve writes body with an ETag, answering 304 when the client already
+// has it.
+func serve(w http.ResponseWriter, req *http.Request, contentType, body string) {
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(body)))
+	w.Header().Set("ETag", etag)
+	if req.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	fmt.Fprint(w, body)
+}
+
+// NDBC is a fake www.ndbc.noaa.gov serving realtime2 buoy files.
+type NDBC struct {
+	*httptest.Server
+	responses
+
+	files       map[string]string
+	defaultFile string
+}
+
+// NewNDBC starts a fake NDBC server that is closed when the test ends.
+// Unknown buoys return 404 until SetBuoy or SetDefault is called.
+func NewNDBC(t testing.TB) *NDBC {
+	s := &NDBC{responses: newResponses(), files: make(map[string]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /data/realtime2/{file}", s.realtime2)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetBuoy serves file as the realtime2 data of buoy id.
+func (s *NDBC) SetBuoy(id, file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[id] = file
+}
+
+// SetDefault serves file for every buoy without its own file.
+func (s *NDBC) SetDefault(file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultFile = file
+}
+
+// BuoyPath returns the request path of a buoy's realtime2 file, for use
+// with Fail and Requests.
+func BuoyPath(id string) string {
+	return "/data/realtime2/" + id + ".txt"
+}
+
+func (s *NDBC) realtime2(w http.ResponseWriter, req *http.Request) {
+	if s.begin(w, req) {
+		return
+	}
+	id, ok := strings.CutSuffix(req.PathValue("file"), ".txt")
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	s.mu.Lock()
+	file, found := s.files[id]
+	if !found {
+		file, found = s.defaultFile, s.defaultFile != ""
+	}
+	s.mu.Unlock()
+	if !found {
+		http.NotFound(w, req)
+		return
+	}
+	serve(w, req, "text/plain", file)
+}
+
+// Station is an NWS observation station.
+type Station struct {
+	ID  string
+	Lat float64
+	Lon float64
+}
+
+// NWS is a fake api.weather.gov. Every point resolves to the same grid,
+// which lists every added station and serves one hourly forecast.
+type NWS struct {
+	*httptest.Server
+	responses
+
+	stations     []Station
+	observations map[string]string
+	forecast     string
+}
+
+// NewNWS starts a fake NWS server that is closed when the test ends. It
+// serves HourlyForecast until SetForecast is called.
+func NewNWS(t testing.TB) *NWS {
+	s := &NWS{
+		responses:    newResponses(),
+		observations: make(map[string]string),
+		forecast:     forecastFile,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /points/{point}", s.points)
+	mux.HandleFunc("GET /gridpoints/TST/1,1/stations", s.stationList)
+	mux.HandleFunc("GET /gridpoints/TST/1,1/forecast/hourly", s.hourly)
+	mux.HandleFunc("GET /stations/{id}/observations/latest", s.latest)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// AddStation lists st at every point and serves observation as its latest
+// observation.
+func (s *NWS) AddStation(st Station, observation string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stations = append(s.stations, st)
+	s.observations[st.ID] = observation
+}
+
+// SetForecast replaces the hourly forecast served for every point.
+func (s *NWS) SetForecast(forecast string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forecast = forecast
+}
+
+// ObservationPath returns the request path of a station's latest
+// observation, for use with Fail and Requests.
+func ObservationPath(id string) string {
+	return "/stations/" + id + "/observations/latest"
+}
+
+func (s *NWS) points(w http.ResponseWriter, req *http.Request) {
+	if s.begin(w, req) {
+		return
+	}
+	grid := s.URL + "/gridpoints/TST/1,1"
+	writeJSON(w, map[string]any{
+		"id": s.URL + req.URL.Path,
+		"properties": map[string]any{
+			"gridId":              "TST",
+			"gridX":               1,
+			"gridY":               1,
+			"forecastHourly":      grid + "/forecast/hourly",
+			"observationStations": grid + "/stations",
+		},
+	})
+}
+
+func (s *NWS) stationList(w http.ResponseWriter, req *http.Request) {
+	if s.begin(w, req) {
+		return
+	}
+	s.mu.Lock()
+	features := make([]any, len(s.stations))
+	for i, st := range s.stations {
+		features[i] = map[string]any{
+			"geometry":   map[string]any{"type": "Point", "coordinates": []float64{st.Lon, st.Lat}},
+			"properties": map[string]any{"stationIdentifier": st.ID},
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{"type": "FeatureCollection", "features": features})
+}
+
+func (s *NWS) hourly(w http.ResponseWriter, req *http.Request) {
+	if s.begin(w, req) {
+		return
+	}
+	s.mu.Lock()
+	forecast := s.forecast
+	s.mu.Unlock()
+	serve(w, req, "application/geo+json", forecast)
+}
+
+func (s *NWS) latest(w http.ResponseWriter, req *http.Request) {
+	if s.begin(w, req) {
+		return
+	}
+	s.mu.Lock()
+	obs, ok := s.observations[req.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	serve(w, req, "application/geo+json", obs)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(v)
+}
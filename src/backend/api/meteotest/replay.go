@@ -0,0 +1,125 @@
+package meteotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// RecordEnv is the environment variable that switches Transport from
+// replaying fixtures to recording them from the live servers:
+//
+//	GOSURF_RECORD=1 go test ./src/backend/api/
+const RecordEnv = "GOSURF_RECORD"
+
+// fixture is one recorded response, stored as JSON.
+type fixture struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Transport returns a RoundTripper that answers requests from the
+// fixtures in dir. A request without a fixture fails the test. When
+// GOSURF_RECORD is set, requests go to the live servers instead and the
+// responses are written to dir, replacing the previous fixtures.
+func Transport(t testing.TB, dir string) http.RoundTripper {
+	if os.Getenv(RecordEnv) != "" {
+		return &recorder{t: t, dir: dir, next: http.DefaultTransport}
+	}
+	return &replayer{t: t, dir: dir}
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fixturePath names the fixture of req after its host, path and query.
+func fixturePath(dir string, req *http.Request) string {
+	name := req.Method + "_" + req.URL.Host + req.URL.Path
+	if req.URL.RawQuery != "" {
+		name += "_" + req.URL.RawQuery
+	}
+	return filepath.Join(dir, unsafeChars.ReplaceAllString(name, "_")+".json")
+}
+
+type replayer struct {
+	t   testing.TB
+	dir string
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := fixturePath(r.dir, req)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		r.t.Errorf("no fixture for %s %s; record one with %s=1", req.Method, req.URL, RecordEnv)
+		return nil, fmt.Errorf("no fixture %s: %w", path, err)
+	}
+
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("could not parse fixture %s: %w", path, err)
+	}
+	return &http.Response{
+		StatusCode:    f.Status,
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header,
+		Body:          io.NopCloser(bytes.NewBufferString(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}, nil
+}
+
+type recorder struct {
+	t    testing.TB
+	dir  string
+	next http.RoundTripper
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Record full responses, never a 304 for a copy the fixture lacks.
+	req = req.Clone(req.Context())
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(fixture{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	path := fixturePath(r.dir, req)
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, err
+	}
+	r.t.Logf("recorded %s %s to %s", req.Method, req.URL, path)
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
@@ -0,0 +1,40 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/metrics"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordMetrics counts each request and its latency under the route
+// pattern it matched, so /surfspots/1 and /surfspots/2 share a series.
+func recordMetrics(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	method := c.Request.Method
+	metrics.HTTPRequests.WithLabelValues(route, method, strconv.Itoa(c.Writer.Status())).Inc()
+	metrics.HTTPDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+}
+
+// buoyObservationTimes returns when each buoy's latest stored observation
+// was recorded, for the buoy age gauge.
+func (h *Handler) buoyObservationTimes(ctx context.Context) (map[int]time.Time, error) {
+	latest, err := h.store.LatestBuoyObservations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read buoy observations: %w", err)
+	}
+	times := make(map[int]time.Time, len(latest))
+	for _, obs := range latest {
+		times[obs.BuoyID] = obs.RecordedAt
+	}
+	return times, nil
+}
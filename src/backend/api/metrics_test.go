@@ -0,0 +1,36 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Requests are counted under their route pattern, not the raw path.
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(memory.Demo(), config.Default(), nil)
+	if code := get(t, router, "/v1/surfspots/1", nil); code != http.StatusOK {
+		t.Fatalf("/v1/surfspots/1: status %d", code)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics: status %d", rec.Code)
+	}
+	for _, want := range []string{
+		`gosurf_http_requests_total{code="200",method="GET",route="/v1/surfspots/:cityID"}`,
+		// Read from the store, though this process never ingested.
+		`gosurf_buoy_observation_age_seconds{buoy="46253"}`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package meteo
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec describes every route. openapi_test.go checks it against the
+// router and the responses it sends.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// getOpenAPI - serves the OpenAPI document of the API.
+func (h *Handler) getOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
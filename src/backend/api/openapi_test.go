@@ -0,0 +1,251 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unversionedRoutes are the documented routes outside /v1.
+var unversionedRoutes = []string{"/healthz", "/readyz", "/metrics", "/openapi.json"}
+
+// schema is the subset of an OpenAPI schema the tests check responses
+// against.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 any                `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+	Enum                 []any              `json:"enum"`
+	OneOf                []*schema          `json:"oneOf"`
+}
+
+type openAPIDoc struct {
+	Paths map[string]map[string]struct {
+		Responses map[string]struct {
+			Ref     string `json:"$ref"`
+			Content map[string]struct {
+				Schema *schema `json:"schema"`
+			} `json:"content"`
+		} `json:"responses"`
+	} `json:"paths"`
+	Components struct {
+		Schemas   map[string]*schema `json:"schemas"`
+		Responses map[string]struct {
+			Content map[string]struct {
+				Schema *schema `json:"schema"`
+			} `json:"content"`
+		} `json:"responses"`
+	} `json:"components"`
+}
+
+func loadOpenAPI(t *testing.T) *openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	return &doc
+}
+
+// responseSchema returns the JSON schema of the status response of the
+// operation method path.
+func (d *openAPIDoc) responseSchema(path, method string, status int) (*schema, error) {
+	op, ok := d.Paths[path][strings.ToLower(method)]
+	if !ok {
+		return nil, fmt.Errorf("%s %s is not documented", method, path)
+	}
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		return nil, fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+	}
+	content := resp.Content
+	if name, ok := strings.CutPrefix(resp.Ref, "#/components/responses/"); ok {
+		content = d.Components.Responses[name].Content
+	}
+	media, ok := content["application/json"]
+	if !ok {
+		return nil, fmt.Errorf("%s %s: status %d has no JSON body", method, path, status)
+	}
+	return media.Schema, nil
+}
+
+// check reports the ways v does not match s. Objects may not carry
+// properties the schema does not list.
+func (d *openAPIDoc) check(s *schema, v any, at string) []string {
+	if name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/"); ok {
+		ref, ok := d.Components.Schemas[name]
+		if !ok {
+			return []string{at + ": unknown schema " + name}
+		}
+		return d.check(ref, v, at)
+	}
+	if s.OneOf != nil {
+		for _, alt := range s.OneOf {
+			if len(d.check(alt, v, at)) == 0 {
+				return nil
+			}
+		}
+		return []string{at + ": matches none of oneOf"}
+	}
+
+	var types []string
+	switch t := s.Type.(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, name := range t {
+			types = append(types, name.(string))
+		}
+	}
+	if len(types) > 0 && !slices.Contains(types, jsonType(v)) &&
+		!(jsonType(v) == "integer" && slices.Contains(types, "number")) {
+		return []string{fmt.Sprintf("%s: %s, want %v", at, jsonType(v), types)}
+	}
+	if s.Enum != nil && v != nil && !slices.Contains(s.Enum, v) {
+		return []string{fmt.Sprintf("%s: %v is not one of %v", at, v, s.Enum)}
+	}
+
+	var problems []string
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				problems = append(problems, at+": missing "+name)
+			}
+		}
+		for name, value := range v {
+			prop, ok := s.Properties[name]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			if prop == nil {
+				if s.Properties != nil {
+					problems = append(problems, at+": undocumented property "+name)
+				}
+				continue
+			}
+			problems = append(problems, d.check(prop, value, at+"."+name)...)
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				problems = append(problems, d.check(s.Items, item, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	}
+	return problems
+}
+
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+var ginParam = regexp.MustCompile(`:(\w+)`)
+
+// Every /v1 and unversioned route is documented and every documented path
+// is routed.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	doc := loadOpenAPI(t)
+	router := NewRouter(memory.Demo(), config.Default(), nil)
+
+	routed := make(map[string]bool)
+	for _, r := range router.Routes() {
+		if !strings.HasPrefix(r.Path, currentVersion+"/") && !slices.Contains(unversionedRoutes, r.Path) {
+			continue
+		}
+		path := ginParam.ReplaceAllString(r.Path, "{$1}")
+		routed[r.Method+" "+path] = true
+		if _, ok := doc.Paths[path][strings.ToLower(r.Method)]; !ok {
+			t.Errorf("%s %s is not documented", r.Method, path)
+		}
+	}
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if !routed[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s is documented but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+// The demo store's responses match their documented schemas.
+func TestOpenAPIResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	doc := loadOpenAPI(t)
+	router := NewRouter(memory.Demo(), adminConfig(), nil, WithIngestion(fakeIngestion{}))
+
+	for _, tc := range []struct {
+		path, documented string
+	}{
+		{"/v1/cities", "/v1/cities"},
+		{"/v1/surfspots/4", "/v1/surfspots/{cityID}"},
+		{"/v1/surfspots/0", "/v1/surfspots/{cityID}"},
+		{"/v1/spots?sort=score&near=33.6,-117.9", "/v1/spots"},
+		{"/v1/spots?cursor=nonsense", "/v1/spots"},
+		{"/v1/cities?sort=distance&near=33.6,-117.9", "/v1/cities"},
+		{"/v1/cities/4/conditions?sort=wind", "/v1/cities/{cityID}/conditions"},
+		{"/v1/cities/999/conditions", "/v1/cities/{cityID}/conditions"},
+		{"/v1/conditions?county=Orange+County", "/v1/conditions"},
+		{"/v1/conditions?sort=best", "/v1/conditions"},
+		{"/v1/surfforecast/current/10", "/v1/surfforecast/current/{spotID}"},
+		{"/v1/surfforecast/current/999", "/v1/surfforecast/current/{spotID}"},
+		{"/v1/tides", "/v1/tides"},
+		{"/v1/tides?date=2026-13-01", "/v1/tides"},
+		{"/v1/stream/spots", "/v1/stream/spots"},
+		{"/v1/admin/ingestion?limit=5", "/v1/admin/ingestion"},
+		{"/admin/ingestion", "/v1/admin/ingestion"},
+		{"/v1/status/data", "/v1/status/data"},
+		{"/healthz", "/healthz"},
+		{"/readyz", "/readyz"},
+		{"/openapi.json", "/openapi.json"},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.path != "/admin/ingestion" {
+			req = adminRequest(tc.path)
+		}
+		router.ServeHTTP(rec, req)
+		s, err := doc.responseSchema(tc.documented, http.MethodGet, rec.Code)
+		if err != nil {
+			t.Errorf("%s: %v", tc.path, err)
+			continue
+		}
+		var body any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: %v", tc.path, err)
+			continue
+		}
+		for _, problem := range doc.check(s, body, "body") {
+			t.Errorf("%s: %s", tc.path, problem)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package meteo
+
+import (
+	"strings"
+)
+
+// The functions below recover what an archived payload holds from the URL
+// it was fetched from, for reprocessing.
+
+// BuoyIDFromURL returns the buoy id of an NDBC realtime2 file URL.
+func BuoyIDFromURL(u string) (string, bool) {
+	_, file, ok := strings.Cut(u, "/data/realtime2/")
+	if !ok {
+		return "", false
+	}
+	id, ok := strings.CutSuffix(file, ".txt")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
+
+// StationFromObservationURL returns the station of an api.weather.gov
+// latest observation URL.
+func StationFromObservationURL(u string) (string, bool) {
+	_, rest, ok := strings.Cut(u, "/stations/")
+	if !ok {
+		return "", false
+	}
+	station, ok := strings.CutSuffix(rest, "/observations/latest")
+	if !ok || station == "" || strings.Contains(station, "/") {
+		return "", false
+	}
+	return station, true
+}
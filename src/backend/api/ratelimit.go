@@ -0,0 +1,74 @@
+package meteo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hostLimiter keeps a token bucket per host so one slow or strict upstream
+// does not throttle requests to the others.
+type hostLimiter struct {
+	interval time.Duration
+	burst    int
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newHostLimiter(interval time.Duration, burst int) *hostLimiter {
+	return &hostLimiter{
+		interval: interval,
+		burst:    burst,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// wait blocks until a request to host may be sent or ctx is done.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	if l.interval <= 0 {
+		return nil
+	}
+	for {
+		delay := l.reserve(host)
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token for host if one is available and otherwise returns
+// how long until the next one is.
+func (l *hostLimiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[host] = b
+	}
+
+	b.tokens += float64(now.Sub(b.last)) / float64(l.interval)
+	b.tokens = min(b.tokens, float64(l.burst))
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(l.interval))
+}
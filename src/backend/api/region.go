@@ -0,0 +1,191 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/store"
+	"cmp"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sort orders of the conditions lists, besides sortScore. Height orders
+// biggest swell first and wind lightest first.
+const (
+	sortHeight = "height"
+	sortWind   = "wind"
+)
+
+// apiSpotConditions is a spot with its score and current conditions,
+// which are null when the spot has none.
+type apiSpotConditions struct {
+	Spot       apiSpot                           `json:"spot"`
+	Score      *float64                          `json:"score"`
+	Conditions *models.CurrentSurfSpotConditions `json:"conditions"`
+}
+
+type conditionsListParams struct {
+	Sort string `form:"sort" binding:"omitempty,oneof=score height wind"`
+}
+
+type regionParams struct {
+	conditionsListParams
+	State  string `form:"state"`
+	County string `form:"county"`
+}
+
+// getCityConditions - takes cityID, returns json of the current conditions
+// of every surf spot in the city, ranked by ?sort=score|height|wind.
+func (h *Handler) getCityConditions(c *gin.Context) {
+	var params cityParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	var query conditionsListParams
+	if err := c.ShouldBindQuery(&query); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	h.respondRegionConditions(c, store.RegionFilter{CityID: params.CityID}, query.Sort)
+}
+
+// getRegionConditions - returns json of the current conditions of every
+// surf spot in the cities matching ?state and ?county, ranked by
+// ?sort=score|height|wind.
+func (h *Handler) getRegionConditions(c *gin.Context) {
+	var params regionParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	h.respondRegionConditions(c, store.RegionFilter{State: params.State, County: params.County}, params.Sort)
+}
+
+// respondRegionConditions responds with the spots matching f and their
+// conditions in the order sort names, best score first by default.
+func (h *Handler) respondRegionConditions(c *gin.Context, f store.RegionFilter, sort string) {
+	sort = cmp.Or(sort, sortScore)
+	key := rankedKey + "region?" + url.Values{
+		"city":   {strconv.Itoa(f.CityID)},
+		"state":  {f.State},
+		"county": {f.County},
+		"sort":   {sort},
+	}.Encode()
+	entry, err := h.cache.get(key, h.cfg.Server.Cache.ConditionsTTL.Duration, func() (any, time.Time, error) {
+		stored, err := h.store.RegionConditions(c.Request.Context(), f)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if len(stored) == 0 && f.CityID != 0 {
+			if known, err := h.cityExists(c, f.CityID); err != nil || !known {
+				return nil, time.Time{}, cmp.Or(err, store.ErrNotFound)
+			}
+		}
+		spots, lastModified := rankConditions(stored, sort)
+		return spots, lastModified, nil
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			notFound(c, "no such city")
+			return
+		}
+		internalError(c, err, "failed to fetch surf conditions")
+		return
+	}
+	if h.notModified(c, entry) {
+		return
+	}
+
+	// Ages are reported as of the request, as for a single spot. The
+	// conditions are copied so the cached values keep theirs.
+	cached := entry.value.([]apiSpotConditions)
+	spots := make([]apiSpotConditions, len(cached))
+	now := time.Now()
+	for i, spot := range cached {
+		if spot.Conditions != nil {
+			conditions := *spot.Conditions
+			conditions.Provenance.UpdateFreshness(now, h.cfg.QC.MaxAge.Duration)
+			spot.Conditions = &conditions
+		}
+		spots[i] = spot
+	}
+	c.JSON(http.StatusOK, spots)
+}
+
+// cityExists reports whether the store has the city cityID.
+func (h *Handler) cityExists(c *gin.Context, cityID int) (bool, error) {
+	cities, err := h.store.Cities(c.Request.Context())
+	if err != nil {
+		return false, err
+	}
+	for _, city := range cities {
+		if city.ID == cityID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// rankConditions scores the spots and orders them by sort, with spots
+// missing the sorted value last. It also returns when the newest of the
+// conditions was recorded.
+func rankConditions(stored []store.SpotWithConditions, sort string) ([]apiSpotConditions, time.Time) {
+	var lastModified time.Time
+	ranked := make([]listed[apiSpotConditions], 0, len(stored))
+	for _, s := range stored {
+		item := listed[apiSpotConditions]{
+			Item: apiSpotConditions{
+				Spot: apiSpot{
+					StaticSurfSpot: models.StaticSurfSpot{
+						ID:          s.Spot.ID,
+						Name:        s.Spot.Name,
+						Latitude:    s.Spot.Latitude,
+						Longitude:   s.Spot.Longitude,
+						CityID:      s.Spot.CityID,
+						NearestBuoy: s.Spot.NearestBuoy,
+					},
+					BreakType: s.Spot.BreakType,
+				},
+				Conditions: s.Conditions,
+			},
+			key: listKey{Text: s.Spot.Name, ID: s.Spot.ID},
+		}
+		if cond := s.Conditions; cond != nil {
+			item.Item.Score = cond.Score(s.Spot.Orientation)
+			if cond.RecordedAt.After(lastModified) {
+				lastModified = cond.RecordedAt
+			}
+			switch sort {
+			case sortScore:
+				item.key.Num = negate(item.Item.Score)
+			case sortHeight:
+				item.key.Num = negate(cond.DomSwellHeightM)
+			case sortWind:
+				item.key.Num = cond.WindSpeed()
+			}
+		}
+		ranked = append(ranked, item)
+	}
+	sortListed(ranked)
+
+	spots := make([]apiSpotConditions, len(ranked))
+	for i, item := range ranked {
+		spots[i] = item.Item
+	}
+	return spots, lastModified
+}
+
+// negate returns -v, or nil when v is nil, to sort the largest values
+// first.
+func negate(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	n := -*v
+	return &n
+}
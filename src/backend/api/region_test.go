@@ -0,0 +1,95 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegionConditions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st := memory.Demo()
+	router := NewRouter(st, config.Default(), nil)
+
+	list := func(path string) []apiSpotConditions {
+		t.Helper()
+		var spots []apiSpotConditions
+		if code := get(t, router, path, &spots); code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, code)
+		}
+		return spots
+	}
+
+	spots := list("/v1/cities/4/conditions")
+	if len(spots) != 2 {
+		t.Fatalf("city 4: %+v", spots)
+	}
+	for _, s := range spots {
+		if s.Spot.CityID != 4 || s.Score == nil || s.Conditions == nil || s.Conditions.SpotId != s.Spot.ID {
+			t.Errorf("city 4: %+v", s)
+		}
+		if p := s.Conditions.Provenance; p.Status != models.Fresh || p.Buoy.AgeMinutes == nil {
+			t.Errorf("spot %d provenance: %+v", s.Spot.ID, p)
+		}
+	}
+	if *spots[0].Score < *spots[1].Score {
+		t.Errorf("sort=score: %v before %v", *spots[0].Score, *spots[1].Score)
+	}
+
+	// Spots without conditions are listed, last.
+	all, _ := st.AllConditions(context.Background())
+	st.ReplaceConditions(context.Background(), slices.DeleteFunc(all, func(c models.CurrentSurfSpotConditions) bool {
+		return c.SpotId == 5
+	}))
+	// A new router, so the cached conditions are not reused.
+	fresh := NewRouter(st, config.Default(), nil)
+	var region []apiSpotConditions
+	if code := get(t, fresh, "/v1/conditions?county=orange+county&sort=height", &region); code != http.StatusOK {
+		t.Fatalf("region: status %d", code)
+	}
+	if len(region) != 3 || region[2].Spot.ID != 5 || region[2].Conditions != nil || region[2].Score != nil {
+		t.Errorf("region: %+v", region)
+	}
+
+	if code := get(t, router, "/v1/cities/999/conditions", nil); code != http.StatusNotFound {
+		t.Errorf("unknown city: status %d, want 404", code)
+	}
+	if code := get(t, router, "/v1/conditions?sort=name", nil); code != http.StatusBadRequest {
+		t.Errorf("sort=name: status %d, want 400", code)
+	}
+}
+
+// Wind sorts lightest first and height biggest first, with unknown values
+// last.
+func TestRankConditions(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+	str := func(s string) *string { return &s }
+	spots := []store.SpotWithConditions{
+		{Spot: store.Spot{ID: 1, Name: "A"}, Conditions: &models.CurrentSurfSpotConditions{DomSwellHeightM: ptr(2), WindSpeedMph: str("12")}},
+		{Spot: store.Spot{ID: 2, Name: "B"}, Conditions: &models.CurrentSurfSpotConditions{DomSwellHeightM: ptr(1), WindSpeedMph: str("4")}},
+		{Spot: store.Spot{ID: 3, Name: "C"}, Conditions: &models.CurrentSurfSpotConditions{WindSpeedMph: str("NA")}},
+	}
+	ids := func(sort string) []int {
+		ranked, _ := rankConditions(spots, sort)
+		var ids []int
+		for _, s := range ranked {
+			ids = append(ids, s.Spot.ID)
+		}
+		return ids
+	}
+	for sort, want := range map[string][]int{
+		sortWind:   {2, 1, 3},
+		sortHeight: {1, 2, 3},
+	} {
+		if got := ids(sort); !slices.Equal(got, want) {
+			t.Errorf("sort=%s: %v, want %v", sort, got, want)
+		}
+	}
+}
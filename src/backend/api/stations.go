@@ -0,0 +1,64 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/spacial"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// getPoint resolves a coordinate to its NWS grid point metadata.
+func (c *Client) getPoint(ctx context.Context, lat, lon float64) (*models.SpotWeather, error) {
+	// NWS rejects points with more than four decimal places.
+	data, err := c.fetch(ctx, fmt.Sprintf(c.pointsURL, fmt.Sprintf("%.4f,%.4f", lat, lon)))
+	if err != nil {
+		return nil, err
+	}
+
+	var point models.SpotWeather
+	if err := json.Unmarshal(data, &point); err != nil {
+		return nil, fmt.Errorf("could not parse points response: %w", err)
+	}
+	return &point, nil
+}
+
+// NearestStation returns the id of the NWS observation station closest to
+// a coordinate.
+func (s *RTWeatherService) NearestStation(ctx context.Context, lat, lon float64) (string, error) {
+	point, err := s.client.getPoint(ctx, lat, lon)
+	if err != nil {
+		return "", err
+	}
+	if point.Properties.ObservationStations == "" {
+		return "", fmt.Errorf("no observation stations for %.4f,%.4f", lat, lon)
+	}
+
+	data, err := s.client.fetch(ctx, point.Properties.ObservationStations)
+	if err != nil {
+		return "", err
+	}
+	var stations models.ObservationStationCollection
+	if err := json.Unmarshal(data, &stations); err != nil {
+		return "", fmt.Errorf("could not parse observation stations: %w", err)
+	}
+
+	// out of all the features coordinates, find the one closest to the point.
+	distance := math.MaxFloat64
+	var nearestStation string
+	for _, f := range stations.Features {
+		if len(f.Geometry.Coordinates) < 2 {
+			continue
+		}
+		current := spacial.Haversine(lat, lon, f.Geometry.Coordinates[1], f.Geometry.Coordinates[0])
+		if current < distance {
+			distance = current
+			nearestStation = f.Properties.StationIdentifier
+		}
+	}
+	if nearestStation == "" {
+		return "", fmt.Errorf("no observation stations for %.4f,%.4f", lat, lon)
+	}
+	return nearestStation, nil
+}
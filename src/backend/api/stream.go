@@ -0,0 +1,156 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/events"
+	"Go_surf_redesign/src/backend/logging"
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/store"
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStreamSpots bounds how many spots one stream follows.
+const maxStreamSpots = 50
+
+// spotStream wakes a stream when ingestion rebuilds a followed spot.
+type spotStream struct {
+	ids  []int
+	wake chan struct{}
+}
+
+func (s *spotStream) notify(e events.Event) {
+	if e.Source != events.SourceConditions || !slices.ContainsFunc(s.ids, e.Affects) {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// streamSpots - streams the current conditions of the spots in ?ids=1,2,3
+// as server-sent "conditions" events, one per spot each time ingestion
+// rebuilds its row. Event IDs are the rows' rebuild numbers, which grow
+// across all spots, and a batch of events only carries the ID once every
+// row up to it is sent. A new stream starts with every spot's current
+// row, and a client reconnecting with Last-Event-ID gets only the rows
+// rebuilt since.
+func (h *Handler) streamSpots(c *gin.Context) {
+	ids, err := parseSpotIDs(c.Query("ids"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidArgument, err.Error(),
+			fieldError{Field: "ids", Reason: err.Error()})
+		return
+	}
+
+	var since int64
+	if last := c.GetHeader("Last-Event-ID"); last != "" {
+		if n, err := strconv.ParseInt(last, 10, 64); err == nil {
+			since = n
+		}
+	}
+	// sent holds the rebuild of the last row sent for each spot.
+	sent := make(map[int]int64, len(ids))
+	for _, id := range ids {
+		sent[id] = since
+	}
+
+	s := &spotStream{ids: ids, wake: make(chan struct{}, 1)}
+	if h.events != nil {
+		defer h.events.Subscribe(s.notify)()
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	ctx := c.Request.Context()
+	send := func() {
+		var rebuilt []models.CurrentSurfSpotConditions
+		for _, id := range ids {
+			conditions, err := h.store.SpotConditions(ctx, id)
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				logging.FromContext(ctx, h.logger).Warn("could not load conditions for a stream", "spot", id, "error", err)
+				continue
+			}
+			if conditions.Rebuild > sent[id] {
+				rebuilt = append(rebuilt, conditions)
+			}
+		}
+		// Oldest rebuild first, so a client that drops mid-batch resumes
+		// before the rows it missed.
+		slices.SortStableFunc(rebuilt, func(a, b models.CurrentSurfSpotConditions) int {
+			return cmp.Compare(a.Rebuild, b.Rebuild)
+		})
+
+		now := time.Now()
+		for i, conditions := range rebuilt {
+			conditions.Provenance.UpdateFreshness(now, h.cfg.QC.MaxAge.Duration)
+			event := sse.Event{Event: "conditions", Data: conditions}
+			if i == len(rebuilt)-1 || rebuilt[i+1].Rebuild != conditions.Rebuild {
+				event.Id = strconv.FormatInt(conditions.Rebuild, 10)
+			}
+			c.Render(-1, event)
+			sent[conditions.SpotId] = conditions.Rebuild
+		}
+		c.Writer.Flush()
+	}
+	send()
+
+	heartbeat := time.NewTicker(h.cfg.Server.StreamHeartbeat.Duration)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.shutdown:
+			return
+		case <-s.wake:
+			send()
+		case <-heartbeat.C:
+			// A comment line keeps proxies from closing an idle stream.
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// parseSpotIDs parses a comma separated list of spot IDs.
+func parseSpotIDs(list string) ([]int, error) {
+	var ids []int
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spot id %q", field)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("ids is required")
+	}
+	if len(ids) > maxStreamSpots {
+		return nil, fmt.Errorf("at most %d spots may be streamed at once", maxStreamSpots)
+	}
+	return ids, nil
+}
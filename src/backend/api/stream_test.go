@@ -0,0 +1,200 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/events"
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type streamEvent struct {
+	id, event, data string
+}
+
+// readEvent reads the next event from an SSE stream, skipping comments.
+func readEvent(t *testing.T, r *bufio.Reader) streamEvent {
+	t.Helper()
+	var e streamEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if e.event != "" {
+				return e
+			}
+		case strings.HasPrefix(line, "id:"):
+			e.id = strings.TrimPrefix(line, "id:")
+		case strings.HasPrefix(line, "event:"):
+			e.event = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			e.data += strings.TrimPrefix(line, "data:")
+		}
+	}
+}
+
+// openStream connects to the spot stream, resuming after lastEventID when
+// it is not empty.
+func openStream(t *testing.T, srv *httptest.Server, ids, lastEventID string) *bufio.Reader {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/stream/spots?ids="+ids, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// rebuildID returns the event ID of spot's current conditions in st.
+func rebuildID(t *testing.T, st *memory.Store, spot int) string {
+	t.Helper()
+	conditions, err := st.SpotConditions(context.Background(), spot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strconv.FormatInt(conditions.Rebuild, 10)
+}
+
+// A stream starts with the current conditions, pushes a rebuilt row when
+// ingestion reports it, and resumes from Last-Event-ID.
+func TestStreamSpots(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	st := memory.Demo()
+	bus := events.NewBus()
+	srv := httptest.NewServer(NewRouter(st, config.Default(), bus))
+	t.Cleanup(srv.Close)
+
+	stream := openStream(t, srv, "10", "")
+	first := readEvent(t, stream)
+	var conditions models.CurrentSurfSpotConditions
+	if err := json.Unmarshal([]byte(first.data), &conditions); err != nil {
+		t.Fatal(err)
+	}
+	if first.event != "conditions" || conditions.SpotId != 10 || first.id != rebuildID(t, st, 10) {
+		t.Fatalf("first event: %+v", first)
+	}
+
+	// A rebuild pushes the row even when the buoy has not reported since.
+	all, err := st.AllConditions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.ReplaceConditions(ctx, all); err != nil {
+		t.Fatal(err)
+	}
+	// Other spots' rebuilds do not wake the stream.
+	bus.Publish(events.Event{Source: events.SourceConditions, IDs: []int{11}})
+	bus.Publish(events.Event{Source: events.SourceConditions, IDs: []int{10}})
+	second := readEvent(t, stream)
+	if second.id != rebuildID(t, st, 10) || second.id == first.id {
+		t.Errorf("second event id %s, want the rebuilt row's", second.id)
+	}
+
+	// Resuming from the first event only sends the rebuilt row.
+	resumed := readEvent(t, openStream(t, srv, "10", first.id))
+	if resumed.id != second.id {
+		t.Errorf("resumed event id %s, want %s", resumed.id, second.id)
+	}
+}
+
+// Resuming does not depend on when each spot's buoy last reported: a spot
+// whose buoy is older than another's, or has never reported, is sent and
+// resent like any other.
+func TestStreamSpotsResumeAcrossBuoys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	st := memory.Demo()
+	bus := events.NewBus()
+	srv := httptest.NewServer(NewRouter(st, config.Default(), bus))
+	t.Cleanup(srv.Close)
+
+	all, err := st.AllConditions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range all {
+		switch all[i].SpotId {
+		case 5:
+			all[i].RecordedAt = time.Time{} // no buoy observation
+		case 10:
+			all[i].RecordedAt = time.Now()
+		}
+	}
+	if err := st.ReplaceConditions(ctx, all); err != nil {
+		t.Fatal(err)
+	}
+
+	spots := func(r *bufio.Reader) (ids []int, last string) {
+		t.Helper()
+		for range 2 {
+			e := readEvent(t, r)
+			var conditions models.CurrentSurfSpotConditions
+			if err := json.Unmarshal([]byte(e.data), &conditions); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, conditions.SpotId)
+			if e.id != "" {
+				last = e.id
+			}
+		}
+		return ids, last
+	}
+	ids, last := spots(openStream(t, srv, "10,5", ""))
+	if !slices.Equal(ids, []int{10, 5}) || last != rebuildID(t, st, 10) {
+		t.Fatalf("first batch: spots %v, last id %q", ids, last)
+	}
+
+	// Only the weather changes; the buoy times stay as they were.
+	wind := "15"
+	for i := range all {
+		all[i].WindSpeedMph = &wind
+	}
+	if err := st.ReplaceConditions(ctx, all); err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(events.Event{Source: events.SourceConditions})
+
+	// Reconnecting with the newer spot's ID still sends both rebuilt rows.
+	ids, resumed := spots(openStream(t, srv, "10,5", last))
+	if !slices.Equal(ids, []int{10, 5}) || resumed != rebuildID(t, st, 5) {
+		t.Errorf("resumed: spots %v, last id %q", ids, resumed)
+	}
+}
+
+func TestStreamSpotsRejectsBadIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(memory.Demo(), config.Default(), nil)
+	for _, path := range []string{"/v1/stream/spots", "/v1/stream/spots?ids=1,x"} {
+		if code := get(t, router, path, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", path, code)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package meteo
+
+import (
+	"Go_surf_redesign/src/backend/archive"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// * Work backwards through these steps.
+// * This is per city / file.
+// 1. Load xml tide data file.
+// 2. Access the <data> tag.
+// 3. For each item, add to database.
+// 4. Convert the <date> tag to time.Date() format. (replace the "/" with "-".)
+// 5. Build tideDateItem.
+// 6. Add it to the cityXML struct for that city.
+// 7. Update the static tide db table for each cityXML.
+
+// TideChart is a data structure for parsing xml tide data into a usable format.
+type TideChart struct {
+	XMLName     xml.Name       `xml:"datainfo"`
+	Origin      string         `xml:"origin"`
+	StationName string         `xml:"stationname"`
+	CountyName  string         `xml:"countyname"`
+	TideRegion  string         `xml:"tideregion"`
+	State       string         `xml:"state"`
+	BeginDate   string         `xml:"BeginDate"`
+	EndDate     string         `xml:"EndDate"`
+	TideData    []TideDataItem `xml:"data>item"`
+}
+
+type TideDataItem struct {
+	Date     string  `xml:"date"`
+	Day      string  `xml:"day"`
+	Time     string  `xml:"time"`
+	Heightft float64 `xml:"pred_in_ft"`
+	Highlow  string  `xml:"highlow"`
+}
+
+func parseXMLDateFmt(data string) string {
+	fmtData := strings.ReplaceAll(data, "/", "-")
+	return fmtData
+}
+
+// ISODate returns the item's date as YYYY-MM-DD.
+func (i TideDataItem) ISODate() string {
+	return parseXMLDateFmt(i.Date)
+}
+
+// Clock returns the item's time as 24-hour HH:MM, or as given when it is
+// not in the "3:04 PM" form NOAA uses.
+func (i TideDataItem) Clock() string {
+	t, err := time.Parse("3:04 PM", i.Time)
+	if err != nil {
+		return i.Time
+	}
+	return t.Format("15:04")
+}
+
+// ParseTideChart parses one NOAA annual tide prediction xml file.
+func ParseTideChart(data []byte) (TideChart, error) {
+	var chart TideChart
+	err := xml.Unmarshal(data, &chart)
+	return chart, err
+}
+
+// ReadTideCharts parses every NOAA annual tide prediction xml file in dir.
+// Each file is stored in arc first when arc is not nil.
+func ReadTideCharts(ctx context.Context, dir string, arc archive.Archive) ([]TideChart, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build directory path for tide data: %w", err)
+	}
+
+	var tideCharts []TideChart
+	// loop through files
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name())
+		// read file
+		dataFile, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read tide file %s: %w", file.Name(), err)
+		}
+		if arc != nil {
+			p := archive.Payload{Source: archive.SourceTides, Key: file.Name(), FetchedAt: time.Now().UTC(), Body: dataFile}
+			if err := arc.Put(ctx, p); err != nil {
+				return nil, fmt.Errorf("could not archive tide file %s: %w", file.Name(), err)
+			}
+		}
+		chart, err := ParseTideChart(dataFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse xml tide file %s: %w", file.Name(), err)
+		}
+		tideCharts = append(tideCharts, chart)
+	}
+	return tideCharts, nil
+}
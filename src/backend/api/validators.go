@@ -0,0 +1,206 @@
+package meteo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrNotModified is returned when an upstream answers a conditional
+// request with 304: the resource has not changed since it was last fetched,
+// so there is nothing new to store.
+var ErrNotModified = errors.New("not modified")
+
+// validator holds the cache validators of one response.
+type validator struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// validatorStore remembers the validators of each URL, optionally
+// persisting them to a JSON file.
+type validatorStore struct {
+	path string
+
+	mu    sync.Mutex
+	byURL map[string]validator
+}
+
+// loadValidators reads the store at path. A missing file starts an empty
+// store; an empty path keeps the store in memory.
+func loadValidators(path string) (*validatorStore, error) {
+	s := &validatorStore{path: path, byURL: make(map[string]validator)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("could not read validator file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.byURL); err != nil {
+		return s, fmt.Errorf("could not parse validator file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// apply adds conditional headers for url to req.
+func (s *validatorStore) apply(req *http.Request, url string) {
+	s.mu.Lock()
+	v, ok := s.byURL[url]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// responseValidator returns the validators of a response.
+func responseValidator(header http.Header) validator {
+	return validator{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
+
+// update stores the validators of a 200 response to url. It returns an
+// error only if persisting the store failed.
+func (s *validatorStore) update(url string, v validator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.set(url, v) {
+		return nil
+	}
+	return s.save()
+}
+
+// set records v for url and reports whether that changed the store. The
+// caller must hold s.mu.
+func (s *validatorStore) set(url string, v validator) bool {
+	if s.byURL[url] == v {
+		return false
+	}
+	if v == (validator{}) {
+		delete(s.byURL, url)
+	} else {
+		s.byURL[url] = v
+	}
+	return true
+}
+
+// save writes the store to its file. The caller must hold s.mu.
+func (s *validatorStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.byURL, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	// Write then rename so a crash never leaves a truncated file.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// PendingValidators holds the validators of responses fetched with a
+// context from DeferValidators until Commit records them. Recording them
+// only once the fetched data is stored keeps a failed parse or store write
+// from turning every later request for the same data into a 304.
+type PendingValidators struct {
+	mu    sync.Mutex
+	byURL map[string]pendingValidator
+}
+
+type pendingValidator struct {
+	store *validatorStore
+	v     validator
+}
+
+type pendingKey struct{}
+
+// DeferValidators returns a context whose requests hold their validators in
+// the returned PendingValidators instead of recording them straight away.
+func DeferValidators(ctx context.Context) (context.Context, *PendingValidators) {
+	p := &PendingValidators{byURL: make(map[string]pendingValidator)}
+	return context.WithValue(ctx, pendingKey{}, p), p
+}
+
+func pendingValidators(ctx context.Context) *PendingValidators {
+	p, _ := ctx.Value(pendingKey{}).(*PendingValidators)
+	return p
+}
+
+func (p *PendingValidators) add(s *validatorStore, url string, v validator) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.byURL[url] = pendingValidator{store: s, v: v}
+}
+
+// discardValidators drops the pending validators of url, if ctx defers
+// them, so the next request for url downloads it again.
+func discardValidators(ctx context.Context, url string) {
+	if p := pendingValidators(ctx); p != nil {
+		p.mu.Lock()
+		delete(p.byURL, url)
+		p.mu.Unlock()
+	}
+}
+
+// Commit records the pending validators, saving each validator file once.
+func (p *PendingValidators) Commit() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changed := make(map[*validatorStore]bool)
+	for url, pv := range p.byURL {
+		pv.store.mu.Lock()
+		if pv.store.set(url, pv.v) {
+			changed[pv.store] = true
+		}
+		pv.store.mu.Unlock()
+	}
+	clear(p.byURL)
+
+	var errs []error
+	for s := range changed {
+		s.mu.Lock()
+		errs = append(errs, s.save())
+		s.mu.Unlock()
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("could not save validators: %w", err)
+	}
+	return nil
+}
+
+type unconditionalKey struct{}
+
+// Unconditional returns a context whose requests ignore stored validators
+// and always download the full response. Callers use it when they have no
+// stored copy of the data a 304 would refer to.
+func Unconditional(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unconditionalKey{}, true)
+}
+
+func isUnconditional(ctx context.Context) bool {
+	v, _ := ctx.Value(unconditionalKey{}).(bool)
+	return v
+}
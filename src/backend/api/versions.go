@@ -0,0 +1,55 @@
+package meteo
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentVersion is the path prefix of the newest API version. The
+// frontend's API_BASE points at it.
+const currentVersion = "/v1"
+
+// apiVersion is a path prefix the API routes are served under. A
+// deprecated version keeps working until its sunset, but its responses
+// carry Deprecation, Sunset and successor-version Link headers so clients
+// can move to the current version first.
+type apiVersion struct {
+	prefix       string
+	deprecatedAt time.Time
+	sunset       time.Time
+}
+
+func (v apiVersion) deprecated() bool {
+	return !v.deprecatedAt.IsZero()
+}
+
+// apiVersions lists every served version. The unversioned paths predate
+// /v1 and are kept for clients that have not moved yet.
+var apiVersions = []apiVersion{
+	{
+		prefix:       "",
+		deprecatedAt: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+		sunset:       time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC),
+	},
+	{prefix: currentVersion},
+}
+
+// deprecation adds the headers announcing that v is deprecated to every
+// response, following RFC 9745 and RFC 8594.
+func deprecation(v apiVersion) gin.HandlerFunc {
+	deprecatedAt := "@" + strconv.FormatInt(v.deprecatedAt.Unix(), 10)
+	sunset := v.sunset.Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecatedAt)
+		if !v.sunset.IsZero() {
+			c.Header("Sunset", sunset)
+		}
+		successor := currentVersion + strings.TrimPrefix(c.Request.URL.Path, v.prefix)
+		c.Header("Link", "<"+successor+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
@@ -0,0 +1,244 @@
+// Package apiclient is a typed client for the Go_surf REST API described
+// by /openapi.json. It calls the current API version.
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version is the API version the client calls.
+const version = "/v1"
+
+// Client calls the API at a base URL such as http://localhost:8080.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+	adminToken string
+}
+
+// Option customises a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests through hc instead of http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithAdminToken sends token to the endpoints under /admin.
+func WithAdminToken(token string) Option {
+	return func(c *Client) {
+		c.adminToken = token
+	}
+}
+
+// New returns a client for the API served at baseURL.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + version,
+		httpClient: http.DefaultClient,
+		userAgent:  "gosurf-apiclient",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error codes returned in Error.Code.
+const (
+	CodeInvalidArgument = "invalid_argument"
+	CodeUnauthenticated = "unauthenticated"
+	CodeNotFound        = "not_found"
+	CodeInternal        = "internal"
+)
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int          `json:"-"`
+	Code       string       `json:"code"`
+	Message    string       `json:"message"`
+	Details    []FieldError `json:"details"`
+	RequestID  string       `json:"requestId"`
+}
+
+// FieldError describes one invalid request parameter.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gosurf api: %s (%s, request %s)", e.Message, e.Code, e.RequestID)
+}
+
+// get sends a GET for path and decodes the JSON response into v,
+// returning the response headers. Statuses in ok are decoded as
+// successes; any other status is returned as an *Error.
+func (c *Client) get(ctx context.Context, path string, query url.Values, v any, ok ...int) (http.Header, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.adminToken != "" && strings.HasPrefix(path, "/admin/") {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || slices.Contains(ok, resp.StatusCode) {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return nil, fmt.Errorf("could not decode %s: %w", path, err)
+		}
+		return resp.Header, nil
+	}
+
+	var body struct {
+		Error *Error `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == nil {
+		return nil, &Error{StatusCode: resp.StatusCode, Code: CodeInternal, Message: resp.Status}
+	}
+	body.Error.StatusCode = resp.StatusCode
+	return nil, body.Error
+}
+
+// Cities returns every city, ordered by name.
+func (c *Client) Cities(ctx context.Context) ([]City, error) {
+	return all(func(cursor string) (*Page[City], error) {
+		return c.ListCities(ctx, ListOptions{}, cursor)
+	})
+}
+
+// ListCities returns the page of cities matching opts that starts at
+// cursor, or the first page when cursor is empty. BreakType and SortScore
+// do not apply to cities.
+func (c *Client) ListCities(ctx context.Context, opts ListOptions, cursor string) (*Page[City], error) {
+	return getPage[City](ctx, c, "/cities", opts, cursor)
+}
+
+// SurfSpots returns the surf spots of a city.
+func (c *Client) SurfSpots(ctx context.Context, cityID int) ([]SurfSpot, error) {
+	return all(func(cursor string) (*Page[SurfSpot], error) {
+		return c.ListSpots(ctx, cityID, ListOptions{}, cursor)
+	})
+}
+
+// ListSpots returns the page of surf spots in cityID, or in every city
+// when cityID is 0, matching opts and starting at cursor, or the first
+// page when cursor is empty.
+func (c *Client) ListSpots(ctx context.Context, cityID int, opts ListOptions, cursor string) (*Page[SurfSpot], error) {
+	path := "/spots"
+	if cityID != 0 {
+		path = "/surfspots/" + strconv.Itoa(cityID)
+	}
+	return getPage[SurfSpot](ctx, c, path, opts, cursor)
+}
+
+// SpotConditions returns the current conditions of a surf spot. A spot
+// without conditions returns an *Error with Code CodeNotFound.
+func (c *Client) SpotConditions(ctx context.Context, spotID int) (*SpotConditions, error) {
+	var conditions SpotConditions
+	if _, err := c.get(ctx, "/surfforecast/current/"+strconv.Itoa(spotID), nil, &conditions); err != nil {
+		return nil, err
+	}
+	return &conditions, nil
+}
+
+// CityConditions returns every surf spot of a city with its score and
+// current conditions, ordered by sort (SortScore, SortHeight or SortWind;
+// empty means SortScore).
+func (c *Client) CityConditions(ctx context.Context, cityID int, sort string) ([]SpotWithConditions, error) {
+	return c.conditions(ctx, "/cities/"+strconv.Itoa(cityID)+"/conditions", url.Values{}, sort)
+}
+
+// RegionConditions is CityConditions for every city in a state and county.
+// Empty state or county match every city.
+func (c *Client) RegionConditions(ctx context.Context, state, county, sort string) ([]SpotWithConditions, error) {
+	query := url.Values{}
+	if state != "" {
+		query.Set("state", state)
+	}
+	if county != "" {
+		query.Set("county", county)
+	}
+	return c.conditions(ctx, "/conditions", query, sort)
+}
+
+func (c *Client) conditions(ctx context.Context, path string, query url.Values, sort string) ([]SpotWithConditions, error) {
+	if sort != "" {
+		query.Set("sort", sort)
+	}
+	var spots []SpotWithConditions
+	if _, err := c.get(ctx, path, query, &spots); err != nil {
+		return nil, err
+	}
+	return spots, nil
+}
+
+// Tides returns the predicted high and low tides of every tide station on
+// the day of date, in date's location, or on the server's today when date
+// is zero.
+func (c *Client) Tides(ctx context.Context, date time.Time) ([]Tide, error) {
+	query := url.Values{}
+	if !date.IsZero() {
+		query.Set("date", date.Format(time.DateOnly))
+	}
+	var tides []Tide
+	if _, err := c.get(ctx, "/tides", query, &tides); err != nil {
+		return nil, err
+	}
+	return tides, nil
+}
+
+// IngestionSummary returns the state of every ingestion source and the
+// limit most recent runs, or the server's default number when limit is 0.
+// The client needs the server's admin token; see WithAdminToken.
+func (c *Client) IngestionSummary(ctx context.Context, limit int) (*IngestionSummary, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var summary IngestionSummary
+	if _, err := c.get(ctx, "/admin/ingestion", query, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// DataStatus reports how fresh the stored data is. Stale data is not an
+// error: check DataStatus.Status.
+func (c *Client) DataStatus(ctx context.Context) (*DataStatus, error) {
+	var status DataStatus
+	if _, err := c.get(ctx, "/status/data", nil, &status, http.StatusServiceUnavailable); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
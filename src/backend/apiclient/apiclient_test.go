@@ -0,0 +1,131 @@
+package apiclient
+
+import (
+	meteo "Go_surf_redesign/src/backend/api"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The client decodes every response of a server on the demo store.
+func TestClientAgainstServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.Server.AdminToken = "secret"
+	srv := httptest.NewServer(meteo.NewRouter(memory.Demo(), cfg, nil))
+	defer srv.Close()
+	c := New(srv.URL, WithHTTPClient(srv.Client()), WithAdminToken("secret"))
+	ctx := context.Background()
+
+	cities, err := c.Cities(ctx)
+	if err != nil || len(cities) == 0 || cities[0].Name == "" {
+		t.Fatalf("Cities: %v, %+v", err, cities)
+	}
+	spots, err := c.SurfSpots(ctx, 4)
+	if err != nil || len(spots) == 0 || spots[0].CityID != 4 {
+		t.Fatalf("SurfSpots: %v, %+v", err, spots)
+	}
+	conditions, err := c.SpotConditions(ctx, 10)
+	if err != nil {
+		t.Fatalf("SpotConditions: %v", err)
+	}
+	if conditions.SpotID != 10 || conditions.DomSwellHeightM == nil || conditions.Provenance.Buoy.Status != Fresh {
+		t.Errorf("SpotConditions: %+v", conditions)
+	}
+	ranked, err := c.CityConditions(ctx, 4, SortWind)
+	if err != nil || len(ranked) != 2 || ranked[0].Conditions == nil || ranked[0].Spot.CityID != 4 {
+		t.Fatalf("CityConditions: %v, %+v", err, ranked)
+	}
+	region, err := c.RegionConditions(ctx, "California", "Orange County", "")
+	if err != nil || len(region) != 3 || region[0].Score == nil {
+		t.Fatalf("RegionConditions: %v, %+v", err, region)
+	}
+	tides, err := c.Tides(ctx, time.Time{})
+	if err != nil || len(tides) == 0 || tides[0].HighLow == "" {
+		t.Fatalf("Tides: %v, %+v", err, tides)
+	}
+	if tides, err := c.Tides(ctx, time.Now().AddDate(-1, 0, 0)); err != nil || len(tides) != 0 {
+		t.Errorf("Tides a year ago: %v, %+v", err, tides)
+	}
+	summary, err := c.IngestionSummary(ctx, 1)
+	if err != nil || len(summary.RecentRuns) != 1 {
+		t.Errorf("IngestionSummary: %v, %+v", err, summary)
+	}
+
+	// Stale data is reported, not returned as an error.
+	cfg = config.Default()
+	cfg.Status.BuoysMaxAge = config.Duration{Duration: 1}
+	stale := httptest.NewServer(meteo.NewRouter(memory.Demo(), cfg, nil))
+	defer stale.Close()
+	status, err := New(stale.URL).DataStatus(ctx)
+	if err != nil || status.Status != "stale" {
+		t.Errorf("DataStatus: %v, %+v", err, status)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.Server.AdminToken = "secret"
+	srv := httptest.NewServer(meteo.NewRouter(memory.Demo(), cfg, nil))
+	defer srv.Close()
+	c := New(srv.URL, WithAdminToken("secret"))
+
+	_, err := c.SpotConditions(context.Background(), 999)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Code != CodeNotFound || apiErr.RequestID == "" {
+		t.Errorf("unknown spot: %#v", err)
+	}
+
+	_, err = c.IngestionSummary(context.Background(), 1000)
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeInvalidArgument || len(apiErr.Details) != 1 || apiErr.Details[0].Field != "limit" {
+		t.Errorf("limit 1000: %#v", err)
+	}
+
+	_, err = New(srv.URL).IngestionSummary(context.Background(), 1)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != CodeUnauthenticated {
+		t.Errorf("no admin token: %#v", err)
+	}
+}
+
+// Lists are read a page at a time by following the next cursors.
+func TestClientPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(meteo.NewRouter(memory.Demo(), config.Default(), nil))
+	defer srv.Close()
+	c := New(srv.URL)
+	ctx := context.Background()
+
+	opts := ListOptions{Sort: SortScore, Near: &Point{33.6, -117.9}, Limit: 2}
+	var spots []SurfSpot
+	cursor := ""
+	for {
+		page, err := c.ListSpots(ctx, 0, opts, cursor)
+		if err != nil {
+			t.Fatalf("ListSpots: %v", err)
+		}
+		if page.Total != 3 {
+			t.Errorf("Total %d, want 3", page.Total)
+		}
+		spots = append(spots, page.Items...)
+		if cursor = page.Next; cursor == "" {
+			break
+		}
+	}
+	if len(spots) != 3 || spots[0].Score == nil || spots[0].DistanceKm == nil {
+		t.Errorf("ListSpots: %+v", spots)
+	}
+
+	tides := true
+	cities, err := c.ListCities(ctx, ListOptions{HasTideStation: &tides, Limit: 1}, "")
+	if err != nil || len(cities.Items) != 1 || cities.Total != 2 || cities.Next == "" {
+		t.Errorf("ListCities: %v, %+v", err, cities)
+	}
+}
@@ -0,0 +1,122 @@
+package apiclient
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Sort orders of ListOptions.Sort and of the conditions lists, which
+// take SortScore, SortHeight and SortWind.
+const (
+	SortName     = "name"
+	SortDistance = "distance" // needs ListOptions.Near
+	SortScore    = "score"    // spots only, best first
+	SortHeight   = "height"   // biggest swell first
+	SortWind     = "wind"     // lightest wind first
+)
+
+// Point is a position in decimal degrees.
+type Point struct {
+	Latitude, Longitude float64
+}
+
+// ListOptions filters and orders a list. Zero fields use the server's
+// defaults: no filter, sorted by name, 100 items a page.
+type ListOptions struct {
+	State          string
+	County         string
+	BreakType      string // spots only
+	HasTideStation *bool
+	// Near reports each item's distance from the point.
+	Near  *Point
+	Sort  string
+	Limit int
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			q.Set(name, value)
+		}
+	}
+	set("state", o.State)
+	set("county", o.County)
+	set("break_type", o.BreakType)
+	if o.HasTideStation != nil {
+		q.Set("has_tide_station", strconv.FormatBool(*o.HasTideStation))
+	}
+	if o.Near != nil {
+		q.Set("near", strconv.FormatFloat(o.Near.Latitude, 'f', -1, 64)+","+strconv.FormatFloat(o.Near.Longitude, 'f', -1, 64))
+	}
+	set("sort", o.Sort)
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
+// Page is one page of a list.
+type Page[T any] struct {
+	Items []T
+	// Total is the number of matching items across every page.
+	Total int
+	// Next is the cursor of the next page, empty on the last page.
+	Next string
+}
+
+// getPage fetches the page of the list at path starting at cursor.
+func getPage[T any](ctx context.Context, c *Client, path string, opts ListOptions, cursor string) (*Page[T], error) {
+	query := opts.query()
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	var page Page[T]
+	header, err := c.get(ctx, path, query, &page.Items)
+	if err != nil {
+		return nil, err
+	}
+	page.Total, _ = strconv.Atoi(header.Get("X-Total-Count"))
+	for _, link := range header.Values("Link") {
+		if next := nextCursor(link); next != "" {
+			page.Next = next
+		}
+	}
+	return &page, nil
+}
+
+var nextLink = regexp.MustCompile(`<([^>]*)>\s*;\s*rel="?next"?`)
+
+// nextCursor returns the cursor of the rel="next" link in a Link header.
+func nextCursor(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		m := nextLink.FindStringSubmatch(link)
+		if m == nil {
+			continue
+		}
+		if u, err := url.Parse(m[1]); err == nil {
+			return u.Query().Get("cursor")
+		}
+	}
+	return ""
+}
+
+// all collects every page that list returns, following the cursors.
+func all[T any](list func(cursor string) (*Page[T], error)) ([]T, error) {
+	var items []T
+	cursor := ""
+	for {
+		page, err := list(cursor)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.Next == "" {
+			return items, nil
+		}
+		cursor = page.Next
+	}
+}
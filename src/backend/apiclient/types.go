@@ -0,0 +1,154 @@
+package apiclient
+
+import "time"
+
+// The types below mirror the schemas in /openapi.json.
+
+type City struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	State      string   `json:"state"`
+	DistanceKm *float64 `json:"distanceKm"` // set with ListOptions.Near
+}
+
+type SurfSpot struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Latitude    float64  `json:"latitude"`
+	Longitude   float64  `json:"longitude"`
+	CityID      int      `json:"cityId"`
+	NearestBuoy int      `json:"nearestBuoy"`
+	BreakType   string   `json:"breakType"`
+	DistanceKm  *float64 `json:"distanceKm"` // set with ListOptions.Near
+	Score       *float64 `json:"score"`      // set when sorting by SortScore
+}
+
+// SpotWithConditions is a surf spot with its score from 0 to 10 and its
+// current conditions. Score and Conditions are nil when unknown.
+type SpotWithConditions struct {
+	Spot       SurfSpot        `json:"spot"`
+	Score      *float64        `json:"score"`
+	Conditions *SpotConditions `json:"conditions"`
+}
+
+// SpotConditions are the current conditions of a spot. Values that failed
+// quality control are nil and listed in QC.
+type SpotConditions struct {
+	ID                    int               `json:"ID"`
+	SpotID                int               `json:"SpotId"`
+	RecordedAt            time.Time         `json:"RecordedAt"`
+	DomSwellHeightM       *float64          `json:"DomSwellHeightM"`
+	DomSwellDir           *float64          `json:"DomSwellDir"`
+	WindSpeedMph          *string           `json:"WindSpeedMph"`
+	WindDirection         *string           `json:"WindDirection"`
+	AirTempDegC           *float64          `json:"AirTempDegC"`
+	WaterTempDegC         *float64          `json:"WaterTempDegC"`
+	Precipitation         *float64          `json:"Precipitation"`
+	CloudCoverage         *string           `json:"CloudCoverage"`
+	DominantWavePeriodSec *float64          `json:"DominantWavePeriodSec"`
+	NearestBuoy           int               `json:"NearestBuoy"`
+	QC                    map[string]string `json:"QC"`
+	Provenance            Provenance        `json:"Provenance"`
+}
+
+// Freshness values of Source.Status and Provenance.Status.
+const (
+	Fresh   = "fresh"
+	Stale   = "stale"
+	Missing = "missing"
+)
+
+// Provenance records where and when a spot's conditions were observed.
+type Provenance struct {
+	Buoy    Source `json:"Buoy"`
+	Weather Source `json:"Weather"`
+	Status  string `json:"Status"`
+}
+
+// Source is the buoy or weather station part of the conditions came from.
+type Source struct {
+	ID         string     `json:"ID"`
+	DistanceKm *float64   `json:"DistanceKm"`
+	ObservedAt *time.Time `json:"ObservedAt"`
+	AgeMinutes *int       `json:"AgeMinutes"`
+	Status     string     `json:"Status"`
+}
+
+// Tide is one predicted high or low tide. Time is 24-hour HH:MM in the
+// station's local time and HighLow is "H" or "L".
+type Tide struct {
+	Station  string  `json:"station"`
+	County   string  `json:"county"`
+	State    string  `json:"state"`
+	Region   string  `json:"region"`
+	Date     string  `json:"date"`
+	Time     string  `json:"time"`
+	HeightFt float64 `json:"heightFt"`
+	HighLow  string  `json:"highLow"`
+}
+
+type IngestionRun struct {
+	ID          int64             `json:"id"`
+	Source      string            `json:"source"`
+	Status      string            `json:"status"`
+	StartedAt   time.Time         `json:"startedAt"`
+	FinishedAt  *time.Time        `json:"finishedAt"`
+	RowsWritten int               `json:"rowsWritten"`
+	Failures    map[string]string `json:"failures"`
+	Error       *string           `json:"error"`
+}
+
+type IngestionSource struct {
+	Source        string        `json:"source"`
+	LastRun       *IngestionRun `json:"lastRun"`
+	LastSuccessAt *time.Time    `json:"lastSuccessAt"`
+	Runs24h       int           `json:"runs24h"`
+	Failures24h   int           `json:"failures24h"`
+	StaleAfter    string        `json:"staleAfter"`
+	Stale         bool          `json:"stale"`
+}
+
+type IngestionSummary struct {
+	Sources    []IngestionSource `json:"sources"`
+	RecentRuns []IngestionRun    `json:"recentRuns"`
+	// Scheduler is nil when ingestion does not run in the server process.
+	Scheduler *IngestionScheduler `json:"scheduler"`
+}
+
+// IngestionScheduler is the ingestion scheduler of the server process.
+// Jobs is empty while another instance is running ingestion.
+type IngestionScheduler struct {
+	Leading bool           `json:"leading"`
+	Jobs    []IngestionJob `json:"jobs"`
+}
+
+type IngestionJob struct {
+	Name         string     `json:"name"`
+	Running      bool       `json:"running"`
+	WaitingOn    []string   `json:"waitingOn"`
+	LastRun      *time.Time `json:"lastRun"`
+	LastSuccess  *time.Time `json:"lastSuccess"`
+	LastDuration string     `json:"lastDuration"`
+	NextRun      *time.Time `json:"nextRun"`
+	LastError    *string    `json:"lastError"`
+	Runs         int        `json:"runs"`
+	Failures     int        `json:"failures"`
+}
+
+type SourceStatus struct {
+	Source     string     `json:"source"`
+	Newest     *time.Time `json:"newest"`
+	AgeMinutes *int       `json:"ageMinutes"`
+	MaxAge     string     `json:"maxAge"`
+	Reporting  int        `json:"reporting"`
+	Total      int        `json:"total"`
+	Stale      bool       `json:"stale"`
+}
+
+// DataStatus reports how fresh the stored data is. Status is "ok" or
+// "stale".
+type DataStatus struct {
+	Status    string         `json:"status"`
+	CheckedAt time.Time      `json:"checkedAt"`
+	Sources   []SourceStatus `json:"sources"`
+}
@@ -0,0 +1,60 @@
+// Package archive keeps the raw payloads fetched from upstream sources, so
+// data a parser dropped can be recovered by parsing them again once the
+// parser is fixed.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"time"
+)
+
+// Sources of archived payloads.
+const (
+	SourceNDBC  = "ndbc"  // NDBC realtime2 text files
+	SourceNWS   = "nws"   // api.weather.gov JSON
+	SourceTides = "tides" // NOAA tide prediction XML files
+)
+
+// Payload is one raw upstream response.
+type Payload struct {
+	Source string
+	// Key is the URL the payload was fetched from, or the file name for
+	// payloads read from disk.
+	Key       string
+	FetchedAt time.Time
+	Body      []byte
+}
+
+// Archive stores payloads compressed and returns them by fetch time.
+type Archive interface {
+	Put(ctx context.Context, p Payload) error
+	// Scan calls fn with every payload fetched in [from, to), oldest
+	// first, and stops at the first error fn returns.
+	Scan(ctx context.Context, from, to time.Time, fn func(Payload) error) error
+}
+
+// Compress gzips body.
+func Compress(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress reverses Compress.
+func Decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
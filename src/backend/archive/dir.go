@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dayLayout names the per-day directories of a Dir.
+const dayLayout = "2006-01-02"
+
+// Dir archives payloads as gzip files on the local filesystem, laid out
+// as root/<source>/<day>/<fetch time in ns>-<key hash>.gz. The key and
+// fetch time are kept in the gzip header.
+type Dir struct {
+	root string
+}
+
+// NewDir returns an archive that writes under root.
+func NewDir(root string) *Dir {
+	return &Dir{root: root}
+}
+
+func (d *Dir) Put(ctx context.Context, p Payload) error {
+	fetched := p.FetchedAt.UTC()
+	dir := filepath.Join(d.root, p.Source, fetched.Format(dayLayout))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("could not create archive directory: %w", err)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(p.Key))
+	name := fmt.Sprintf("%d-%016x.gz", fetched.UnixNano(), h.Sum64())
+
+	// Write to a temporary file first so Scan never sees a partial one.
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not create archive file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	zw.Name = p.Key
+	zw.ModTime = fetched
+	if _, err := zw.Write(p.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write archive file: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write archive file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write archive file: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// dirEntry is an archived file found by Scan.
+type dirEntry struct {
+	source    string
+	path      string
+	fetchedAt time.Time
+}
+
+func (d *Dir) Scan(ctx context.Context, from, to time.Time, fn func(Payload) error) error {
+	sources, err := os.ReadDir(d.root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read archive: %w", err)
+	}
+
+	firstDay := from.UTC().Format(dayLayout)
+	lastDay := to.UTC().Format(dayLayout)
+	var entries []dirEntry
+	for _, source := range sources {
+		if !source.IsDir() {
+			continue
+		}
+		days, err := os.ReadDir(filepath.Join(d.root, source.Name()))
+		if err != nil {
+			return fmt.Errorf("could not read archive: %w", err)
+		}
+		for _, day := range days {
+			// Day names sort in date order, so whole days outside the
+			// range are skipped without listing them.
+			if !day.IsDir() || day.Name() < firstDay || day.Name() > lastDay {
+				continue
+			}
+			dayDir := filepath.Join(d.root, source.Name(), day.Name())
+			files, err := os.ReadDir(dayDir)
+			if err != nil {
+				return fmt.Errorf("could not read archive: %w", err)
+			}
+			for _, f := range files {
+				prefix, _, ok := strings.Cut(f.Name(), "-")
+				if !ok || !strings.HasSuffix(f.Name(), ".gz") {
+					continue
+				}
+				ns, err := strconv.ParseInt(prefix, 10, 64)
+				if err != nil {
+					continue
+				}
+				fetchedAt := time.Unix(0, ns).UTC()
+				if fetchedAt.Before(from) || !fetchedAt.Before(to) {
+					continue
+				}
+				entries = append(entries, dirEntry{source.Name(), filepath.Join(dayDir, f.Name()), fetchedAt})
+			}
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b dirEntry) int {
+		return a.fetchedAt.Compare(b.fetchedAt)
+	})
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p, err := readFile(e)
+		if err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(e dirEntry) (Payload, error) {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return Payload{}, fmt.Errorf("could not open archived payload: %w", err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return Payload{}, fmt.Errorf("could not read archived payload %s: %w", e.path, err)
+	}
+	defer zr.Close()
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return Payload{}, fmt.Errorf("could not read archived payload %s: %w", e.path, err)
+	}
+	return Payload{Source: e.source, Key: zr.Name, FetchedAt: e.fetchedAt, Body: body}, nil
+}
@@ -0,0 +1,54 @@
+package archive
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Payloads come back with their source, key and body, oldest first, and
+// only from the requested range.
+func TestDirRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	d := NewDir(t.TempDir())
+	base := time.Date(2025, 6, 1, 23, 50, 0, 0, time.UTC)
+
+	put := []Payload{
+		{Source: SourceNWS, Key: "https://api.weather.gov/stations/KTST/observations/latest", FetchedAt: base.Add(20 * time.Minute), Body: []byte(`{"a":1}`)},
+		{Source: SourceNDBC, Key: "https://www.ndbc.noaa.gov/data/realtime2/46222.txt", FetchedAt: base, Body: []byte("#YY MM\n2025 06 01")},
+		{Source: SourceNDBC, Key: "https://www.ndbc.noaa.gov/data/realtime2/46222.txt", FetchedAt: base.Add(-2 * time.Hour), Body: []byte("old")},
+	}
+	for _, p := range put {
+		if err := d.Put(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []Payload
+	err := d.Scan(ctx, base.Add(-time.Hour), base.Add(time.Hour), func(p Payload) error {
+		got = append(got, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d payloads, want 2", len(got))
+	}
+	for i, want := range []Payload{put[1], put[0]} {
+		if got[i].Source != want.Source || got[i].Key != want.Key || !got[i].FetchedAt.Equal(want.FetchedAt) || string(got[i].Body) != string(want.Body) {
+			t.Errorf("payload %d: got %+v, want %+v", i, got[i], want)
+		}
+	}
+}
+
+func TestScanMissingDir(t *testing.T) {
+	d := NewDir(t.TempDir() + "/missing")
+	err := d.Scan(context.Background(), time.Time{}, time.Now(), func(Payload) error {
+		t.Error("unexpected payload")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,43 @@
+package dbLib
+
+import (
+	meteo "Go_surf_redesign/src/backend/api"
+	"context"
+	"errors"
+	"maps"
+)
+
+// fetchChanged fetches ids with conditional requests so unchanged upstream
+// files are not downloaded again. Ids that have no stored row are fetched
+// unconditionally, since a 304 would leave them empty. Ids that have not
+// changed are returned separately from the failures.
+func fetchChanged[T any](
+	ctx context.Context,
+	ids []string,
+	stored map[string]bool,
+	fetch func(context.Context, []string) (map[string]T, map[string]error),
+) (results map[string]T, unchanged []string, failures map[string]error) {
+	var conditional, full []string
+	for _, id := range ids {
+		if stored[id] {
+			conditional = append(conditional, id)
+		} else {
+			full = append(full, id)
+		}
+	}
+
+	results, errs := fetch(ctx, conditional)
+	fullResults, fullErrs := fetch(meteo.Unconditional(ctx), full)
+	maps.Copy(results, fullResults)
+	maps.Copy(errs, fullErrs)
+
+	failures = make(map[string]error)
+	for id, err := range errs {
+		if errors.Is(err, meteo.ErrNotModified) {
+			unchanged = append(unchanged, id)
+			continue
+		}
+		failures[id] = err
+	}
+	return results, unchanged, failures
+}
@@ -2,510 +2,295 @@ package dbLib
 
 import (
 	meteo "Go_surf_redesign/src/backend/api"
-	"Go_surf_redesign/src/backend/data"
+	"Go_surf_redesign/src/backend/events"
+	"Go_surf_redesign/src/backend/logging"
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/provider"
 	"Go_surf_redesign/src/backend/spacial"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/config"
 	"context"
-	"database/sql"
-	"encoding/csv"
-	"encoding/xml"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"math"
-	"net/http"
-	"os"
-	"path/filepath"
+	"slices"
 	"strconv"
-	"strings"
 	"time"
-
-	_ "github.com/lib/pq"
 )
 
 const (
-	conntStr   = "user=postgres password=pass dbname=surfdb sslmode=disable"
-	psqlDriver = "postgres"
-
 	dbBuoysList    = "buoys.csv"
 	dbCitiesList   = "cities.csv"
 	dbSurfSpotList = "surfspots.csv"
 
 	rtBuoyDataURL = "https://www.ndbc.noaa.gov/data/realtime2/%s.txt"
-
-	tideDataDir = "tides"
 )
 
+// DataClient runs ingestion against a store.
 type DataClient struct {
-	DB *sql.DB
-}
+	store  store.Store
+	events *events.Bus
+	logger *slog.Logger
 
-func NewDBClient() *DataClient {
-	DB, err := sql.Open(psqlDriver, conntStr)
-	if err != nil {
-		log.Fatalf("Failed to instantiate database client: %v", err)
-		return nil
-	}
-	c := &DataClient{
-		DB: DB,
-	}
-	return c
+	cfg *config.Config
 }
 
-func (c *DataClient) PingDB() error {
-	if err := c.DB.Ping(); err != nil {
-		return err
+// NewDataClient returns a client that reads and writes st.
+func NewDataClient(st store.Store, cfg *config.Config) *DataClient {
+	return &DataClient{
+		store:  st,
+		logger: slog.Default().With("component", "ingestion"),
+		cfg:    cfg,
 	}
-	return nil
 }
 
-func (c *DataClient) Close() error {
-	fmt.Println("Database disconnected.")
-	return c.DB.Close()
+// log returns the logger of the run ctx belongs to, or the client's own.
+func (c *DataClient) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, c.logger)
 }
 
-func (c *DataClient) LoadStaticData() {
-	err := c.UpdateStaticCitiesTable()
-	if err != nil {
-		log.Println("Error: ", err)
-	}
+// SetEvents makes the client publish an event on bus whenever an
+// ingestion run or a static data load rewrites stored data.
+func (c *DataClient) SetEvents(bus *events.Bus) {
+	c.events = bus
+}
 
-	err = c.UpdateStaticBuoyTable()
-	if err != nil {
-		log.Println("Error: ", err)
-	}
+// Events returns the bus set with SetEvents, or nil.
+func (c *DataClient) Events() *events.Bus {
+	return c.events
+}
 
-	err = c.UpdateStaticSurfSpotTable()
-	if err != nil {
-		log.Println("Error: ", err)
-	}
+// publish reports that source's data changed, for the entities ids or,
+// without ids, for all of them.
+func (c *DataClient) publish(source string, ids ...int) {
+	c.events.Publish(events.Event{Source: source, IDs: ids, At: time.Now()})
+}
 
-	err = c.UpdateStaticTideData()
-	if err != nil {
-		log.Println("Error: ", err)
-	}
+// Store returns the store the client writes to.
+func (c *DataClient) Store() store.Store {
+	return c.store
+}
 
+func (c *DataClient) Close() error {
+	return c.store.Close()
 }
 
-func (c *DataClient) UpdateStaticBuoyTable() error {
-	sqlStmnt, err := c.DB.Prepare(`
-		INSERT INTO buoys (id, name, latitude, longitude)
-		VALUES ($1, $2, $3, $4)
-	`)
-	if err != nil {
-		return fmt.Errorf("Error preparing sql statement in UpdateStaticBuoyTable(): %w", err)
-	}
-	defer sqlStmnt.Close()
+// UpdateRTBuoyData fetches the latest observation for every buoy and then
+// saves them in one step. Buoys whose fetch failed keep their previous
+// observation; observations of removed buoys are dropped.
+func (c *DataClient) UpdateRTBuoyData(ctx context.Context, waves provider.WaveObservationProvider) (RunReport, error) {
+	var report RunReport
 
-	_, err = c.DB.Exec(`TRUNCATE TABLE buoys RESTART IDENTITY CASCADE`)
+	// read bouy ids from static buoy table
+	ids, err := c.GetBuoyIds(ctx)
 	if err != nil {
-		return fmt.Errorf("Error truncating static buoys table in UpdateStaticBuoyTable(): %w", err)
+		return report, fmt.Errorf("could net get buoy ids: %w", err)
 	}
 
-	file, err := os.Open(filepath.Join(data.FilePathBuilder(), dbBuoysList))
+	latest, err := c.store.LatestBuoyObservations(ctx)
 	if err != nil {
-		return fmt.Errorf("could not open dbBuoysList in UpdateStaticBuoyTable(): %w", err)
+		return report, fmt.Errorf("could not read stored buoy data: %w", err)
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	linenumber := 0
-	for {
-		record, err := reader.Read()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("Error reading csv reader in UpdateStaticBuoyTable(): %w", err)
-
-		}
-		if linenumber == 0 {
-			linenumber++
-			continue
-		}
-
-		id, err := strconv.Atoi(record[0])
-		if err != nil {
-			return fmt.Errorf("invalid id on linenumber %d: %w", linenumber, err)
-		}
-
-		lat, err := strconv.ParseFloat(record[2], 64)
-		if err != nil {
-			return fmt.Errorf("invalid latitude on linenumber %d: %w", linenumber, err)
-		}
-
-		lon, err := strconv.ParseFloat(record[3], 64)
-		if err != nil {
-			return fmt.Errorf("invalid longitude on linenumber %d: %w", linenumber, err)
-		}
-
-		_, err = sqlStmnt.Exec(id, record[1], lat, lon)
-		if err != nil {
-			return fmt.Errorf("Could not update record %d: %w", id, err)
-		}
-		linenumber++
+	stored := make(map[string]bool, len(latest))
+	previous := make(map[int]store.BuoyObservation, len(latest))
+	for _, obs := range latest {
+		stored[strconv.Itoa(obs.BuoyID)] = true
+		previous[obs.BuoyID] = obs
 	}
-	return nil
-}
 
-func (c *DataClient) UpdateStaticCitiesTable() error {
-	sqlStmnt, err := c.DB.Prepare(`
-			INSERT INTO cities (id, name, latitude, longitude, country, state, county)
-			VALUES($1, $2, $3, $4, $5, $6, $7)
-		`)
-	if err != nil {
-		log.Fatal(err)
+	// fetch every buoy concurrently, skipping files that have not changed
+	strIds := make([]string, len(ids))
+	for i, id := range ids {
+		strIds[i] = strconv.Itoa(id)
 	}
-	defer sqlStmnt.Close()
-
-	_, err = c.DB.Exec(`TRUNCATE TABLE cities RESTART IDENTITY CASCADE`)
-	if err != nil {
-		log.Fatal(err)
+	// The files' validators are recorded only once their rows are saved.
+	fetchCtx, validators := meteo.DeferValidators(ctx)
+	observations, unchanged, failures := fetchChanged(fetchCtx, strIds, stored, waves.GetObservations)
+	for id, err := range failures {
+		report.fail(id, err)
 	}
-
-	file, err := os.Open(filepath.Join(data.FilePathBuilder(), dbCitiesList))
-	if err != nil {
-		return fmt.Errorf("Error opening file in UpdaateStaticCitiesTable(): %w", err)
+	report.Unchanged = len(unchanged)
+	if len(ids) > 0 && len(observations) == 0 && len(unchanged) == 0 {
+		return report, fmt.Errorf("could not fetch data for any of %d buoys", len(ids))
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	linenumber := 0
 
-	for {
-		record, err := reader.Read()
+	now := time.Now()
+	rows := make([]store.BuoyObservation, 0, len(observations))
+	for buoyId, obs := range observations {
+		id, err := strconv.Atoi(buoyId)
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("Error reading file ")
-		}
-		if linenumber == 0 {
-			linenumber++
-			continue
+			return report, fmt.Errorf("invalid buoy id %q: %w", buoyId, err)
 		}
-		id, err := strconv.Atoi(record[0])
-		lat, err := strconv.ParseFloat(record[2], 64)
-		lon, err := strconv.ParseFloat(record[3], 64)
-
-		_, err = sqlStmnt.Exec(id, record[1], lat, lon, record[4], record[5], record[6])
-		if err != nil {
-			return fmt.Errorf("Could not execute sql statement in UpdateStaticCitiesTable(): %w", err)
+		row := buoyObservation(id, obs)
+		var prev *store.BuoyObservation
+		if p, ok := previous[id]; ok {
+			prev = &p
 		}
-		linenumber++
+		c.checkBuoyObservation(&row, prev, now)
+		rows = append(rows, row)
 	}
-
-	if err := updateCityWeatherStationId(c); err != nil {
-		return fmt.Errorf("Error updating weatherstation id in UpdateStaticCitiesTable(): %w", err)
+	if err := c.store.SaveBuoyObservations(ctx, rows); err != nil {
+		return report, fmt.Errorf("could not refresh real_time_buoy_data_points: %w", err)
 	}
-	return nil
+	c.commitValidators(ctx, validators)
+	report.RowsWritten = len(rows)
+	return report, nil
 }
 
-func (c *DataClient) UpdateStaticSurfSpotTable() error {
-	sqlStmnt, err := c.DB.Prepare(`
-			INSERT INTO surfspot (id, name, latitude, longitude, city_id, break_type, orientation, nearest_buoy, tide_region_id)
-			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		`)
-	if err != nil {
-		return err
+// commitValidators records the validators of the upstream files whose data
+// was just saved. A failure only costs a full download next run.
+func (c *DataClient) commitValidators(ctx context.Context, validators *meteo.PendingValidators) {
+	if err := validators.Commit(); err != nil {
+		c.log(ctx).Warn("could not save validators", "error", err)
 	}
-	defer sqlStmnt.Close()
+}
 
-	_, err = c.DB.Exec(`TRUNCATE TABLE surfspot RESTART IDENTITY CASCADE`)
-	if err != nil {
-		return err
+// buoyObservation converts an NDBC observation of buoy id for the store.
+func buoyObservation(id int, obs *meteo.BouyObservation) store.BuoyObservation {
+	return store.BuoyObservation{
+		BuoyID:                id,
+		RecordedAt:            obs.RecordedAt,
+		WindDirectionDegT:     obs.WindDirectionDegT,
+		WindSpeedMetersPerSec: obs.WindSpeedMetersPerSec,
+		WindGustMetersPerSec:  obs.WindGustMetersPerSec,
+		WaveHeightM:           obs.WaveHeightM,
+		DominantWavePeriodSec: obs.DominantWavePeriodSec,
+		AvgWavePeriodSec:      obs.AvgWavePeriodSec,
+		MeanWaveDirectionDegT: obs.MeanWaveDirectionDegT,
+		AirTempDegC:           obs.AirTempDegC,
+		WaterTempDegC:         obs.WaterTempDegC,
+		InsertedAt:            obs.InsertedAt,
 	}
+}
 
-	file, err := os.Open(filepath.Join(data.FilePathBuilder(), dbSurfSpotList))
+// BackfillBuoyHistory stores every observation newer than since from each
+// buoy's realtime2 file in buoy_data_history. Rows that are already stored
+// are skipped, so the backfill can be re-run safely. It returns the number
+// of rows written.
+func (c *DataClient) BackfillBuoyHistory(ctx context.Context, waves provider.WaveObservationProvider, since time.Time) (int, error) {
+	ids, err := c.GetBuoyIds(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return 0, fmt.Errorf("could not get buoy ids: %w", err)
 	}
-	reader := csv.NewReader(file)
-	linenumber := 0
 
-	for {
-		record, err := reader.Read()
+	written := 0
+	failed := 0
+	for _, id := range ids {
+		history, err := waves.GetHistory(ctx, strconv.Itoa(id))
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
-		if linenumber == 0 {
-			linenumber++
+			c.log(ctx).Warn("could not get buoy history", "buoy", id, "error", err)
+			failed++
 			continue
 		}
-
-		id, err := strconv.Atoi(record[0])
-		lat, err := strconv.ParseFloat(record[2], 64)
-		lon, err := strconv.ParseFloat(record[3], 64)
-		city_id, err := strconv.Atoi(record[4])
-		orientation, err := strconv.ParseFloat(record[6], 64)
-
-		nearestBuoy := spacial.NearestBuoy(lat, lon, c.DB)
-
-		_, err = sqlStmnt.Exec(id, record[1], lat, lon, city_id, record[5], orientation, nearestBuoy, record[7])
-		if err != nil {
-			return fmt.Errorf("line %d: insert failed: %w", linenumber, err)
+		// history is newest first; check it oldest first so each row is
+		// compared with the one before it.
+		var rows []store.BuoyObservation
+		var prev *store.BuoyObservation
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].RecordedAt.Before(since) {
+				continue
+			}
+			row := buoyObservation(id, history[i])
+			c.checkBuoyObservation(&row, prev, history[i].RecordedAt)
+			rows = append(rows, row)
+			prev = &rows[len(rows)-1]
 		}
-		linenumber++
-	}
-	return nil
-}
-
-// * Work backwards through these steps.
-// * This is per city / file.
-// 1. Load xml tide data file.
-// 2. Access the <data> tag.
-// 3. For each item, add to database.
-// 4. Convert the <date> tag to time.Date() format. (replace the "/" with "-".)
-// 5. Build tideDateItem.
-// 6. Add it to the cityXML struct for that city.
-// 7. Update the static tide db table for each cityXML.
-
-// cityXml is a data structure for parsing xml tide data into a usable format.
-type tideChartsXML struct {
-	XMLName     xml.Name       `xml:"datainfo"`
-	Origin      string         `xml:"origin"`
-	StationName string         `xml:"stationname"`
-	CountyName  string         `xml:"countyname"`
-	TideRegion  string         `xml:"tideregion"`
-	State       string         `xml:"state"`
-	BeginDate   string         `xml:"BeginDate"`
-	EndDate     string         `xml:"EndDate"`
-	TideData    []tideDataItem `xml:"data>item"`
-}
-
-type tideDataItem struct {
-	Date     string  `xml:"date"`
-	Day      string  `xml:"day"`
-	Time     string  `xml:"time"`
-	Heightft float64 `xml:"pred_in_ft"`
-	Highlow  string  `xml:"highlow"`
-}
-
-func parseXMLDateFmt(data string) string {
-	fmtData := strings.ReplaceAll(data, "/", "-")
-	return fmtData
-}
-
-func (c *DataClient) insertTideData(chart tideChartsXML) error {
-	sqlStmnt, err := c.DB.Prepare(`
-		INSERT INTO tide_data(
-			station_name,
-			county_name,
-			state_code,
-			measurement_date,
-			measurement_time,
-			water_level,
-			tidal_state,
-			tide_region
-		)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8)
-	`)
-	if err != nil {
-		return fmt.Errorf("could not prepare statement: %w", err)
-	}
-	defer sqlStmnt.Close()
-
-	station_name := chart.StationName
-	county_name := chart.CountyName
-	state_code := chart.State
-	region := chart.TideRegion
-	for _, entry := range chart.TideData {
-		_, err = sqlStmnt.Exec(
-			station_name,
-			county_name,
-			state_code,
-			entry.Date,
-			entry.Time,
-			entry.Heightft,
-			entry.Highlow,
-			region,
-		)
+		n, err := c.store.AddBuoyHistory(ctx, rows)
+		written += n
 		if err != nil {
-			return err
+			return written, err
 		}
 	}
-	return nil
+	if failed == len(ids) && failed > 0 {
+		return written, fmt.Errorf("could not fetch history for any of %d buoys", failed)
+	}
+	return written, nil
 }
 
-func (c *DataClient) UpdateStaticTideData() error {
-	// tideData
-	dataDir := data.FilePathBuilder(tideDataDir)
-	files, err := os.ReadDir(dataDir)
+// GetBuoyIds returns all the static buoy table ids in a slice.
+func (c *DataClient) GetBuoyIds(ctx context.Context) ([]int, error) {
+	buoys, err := c.store.Buoys(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to build directory path for tide data: %w", err)
-	}
-
-	var tideCharts []tideChartsXML
-	// loop through files
-	for _, file := range files {
-		fileName := file.Name()
-		path := filepath.Join(dataDir, fileName)
-		// read file
-		dataFile, err := os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("failed to build file path for tide data xml path: %w", err)
-		}
-		var chart tideChartsXML
-		if err := xml.Unmarshal(dataFile, &chart); err != nil {
-			return fmt.Errorf("could not parse xml tide file: %w", err)
-		}
-		tideCharts = append(tideCharts, chart)
+		return nil, err
 	}
 
-	// clear table for new data.
-	_, err = c.DB.Exec(`TRUNCATE tide_data RESTART IDENTITY CASCADE`)
-	if err != nil {
-		fmt.Printf("could not truncate tide_data: %v", err)
+	ids := make([]int, len(buoys))
+	for i, b := range buoys {
+		ids[i] = b.ID
 	}
-	for _, chart := range tideCharts {
-		if err := c.insertTideData(chart); err != nil {
-			fmt.Printf("error inserting tide data into table: %v", err)
-		}
-	}
-	fmt.Println("tide data insertion complete.")
-	return nil
+	return ids, nil
 }
 
-func (c *DataClient) UpdateRTBuoyData(ctx context.Context, api *meteo.Client) error {
-	// read bouy ids from static buoy table
-	ids, err := c.GetBuoyIds()
+// UpdateRTWeatherData fetches the latest observation for every city's
+// weather station and then saves them in one step. Cities whose fetch
+// failed keep their previous observation.
+func (c *DataClient) UpdateRTWeatherData(ctx context.Context, weather provider.WeatherObservationProvider) (RunReport, error) {
+	var report RunReport
+
+	// iterate through each city for weather station
+	weatherStations, err := c.GetWeatherStations(ctx)
 	if err != nil {
-		return fmt.Errorf("could net get buoy ids: %w", err)
+		return report, fmt.Errorf("could not get weather stations: %w", err)
 	}
 
-	// Truncate table to prepare for new data.
-	_, err = c.DB.Exec(`TRUNCATE real_time_buoy_data_points`)
+	latest, err := c.store.LatestWeatherObservations(ctx)
 	if err != nil {
-		return err
+		return report, fmt.Errorf("could not read stored weather data: %w", err)
 	}
-
-	// for each bouy id, fetch buoy data
-	idsMap := make(map[int]*meteo.BouyObservation)
-	for _, id := range ids {
-		obs, err := api.RTBouy.GetObservation(ctx, strconv.Itoa(id))
-		if err != nil {
-			continue
-		}
-		idsMap[id] = obs
+	previous := make(map[int]store.WeatherObservation, len(latest))
+	for _, obs := range latest {
+		previous[obs.CityID] = obs
 	}
 
-	// read new data into DB. Use a seperate helper function.
-	for buoyId, obs := range idsMap {
-		if err := c.insertRTBouyData(strconv.Itoa(buoyId), obs); err != nil {
-			fmt.Println("could not insert buoy data: ", err)
-			continue
+	// fetch each station once, even when several cities share it, and only
+	// conditionally when every city of the station has a stored row
+	var stations []string
+	stored := make(map[string]bool)
+	for _, ws := range weatherStations {
+		if !slices.Contains(stations, ws.station) {
+			stations = append(stations, ws.station)
+			stored[ws.station] = true
 		}
+		_, hasWeather := previous[ws.cityId]
+		stored[ws.station] = stored[ws.station] && hasWeather
 	}
-	fmt.Println("Realtime buoy data updated.")
-	return nil
-}
-
-func (c *DataClient) insertRTBouyData(buoyId string, obs *meteo.BouyObservation) error {
-	sqlStmnt, err := c.DB.Prepare(`
-		INSERT INTO real_time_buoy_data_points (
-			buoy_id,
-			recorded_at,
-			winddir_degt,
-			windspeed_m_pers,
-			windgust_m_pers,
-			waveh_m,
-			domwp_sec,
-			avgwavep_sec,
-			meanwavedir_degt,
-			airt_degc,
-			watert_degc,
-			inserted_at
-		)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-	`)
-	if err != nil {
-		return fmt.Errorf("could not prepare statement: %w", err)
-	}
-	defer sqlStmnt.Close()
-
-	_, err = sqlStmnt.Exec(
-		buoyId,
-		obs.RecordedAt,
-		obs.WindDirectionDegT,
-		obs.WindSpeedMetersPerSec,
-		obs.WindGustMetersPerSec,
-		obs.WaveHeightM,
-		obs.DominantWavePeriodSec,
-		obs.AvgWavePeriodSec,
-		obs.MeanWaveDirectionDegT,
-		obs.AirTempDegC,
-		obs.WaterTempDegC,
-		obs.InsertedAt,
-	)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// GetBuoyIds returns all the static buoy table ids in a slice.
-func (c *DataClient) GetBuoyIds() ([]int, error) {
-	rows, err := c.DB.Query(`SELECT id FROM buoys`)
-	if err != nil {
-		return nil, err
+	fetchCtx, validators := meteo.DeferValidators(ctx)
+	byStation, unchanged, failures := fetchChanged(fetchCtx, stations, stored, weather.GetObservations)
+	for station, err := range failures {
+		c.log(ctx).Warn("could not get weather observation", "station", station, "error", err)
+		report.fail(station, err)
 	}
-	defer rows.Close()
-
-	var ids []int
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
-		}
-		ids = append(ids, id)
+	report.Unchanged = len(unchanged)
+	if len(stations) > 0 && len(byStation) == 0 && len(unchanged) == 0 {
+		return report, fmt.Errorf("could not fetch weather for any of %d stations", len(stations))
 	}
-	return ids, nil
-}
 
-func (c *DataClient) UpdateRTWeatherData(ctx context.Context, api *meteo.Client) {
-	// iterate through each city for weather station
-	weatherStations, err := c.GetWeatherStations()
-	if err != nil {
-		fmt.Printf("could not get weather stations: %v", err)
-	}
-	// clear rt weather data from table
-	_, err = c.DB.Exec(`TRUNCATE current_weather`)
-	if err != nil {
-		fmt.Printf("could not truncate current_weather: %v", err)
-	}
-	// for each station get data from api &
-	// insert into table
-	for _, weatherStations := range weatherStations {
-		obs, err := api.RTWeather.GetObservation(ctx, weatherStations.station)
-		if err = c.insertRTWeatherData(weatherStations.cityId, obs); err != nil {
-			fmt.Printf("could not insert current weather observations into table: %v", err)
+	now := time.Now()
+	var rows []store.WeatherObservation
+	for _, ws := range weatherStations {
+		obs, ok := byStation[ws.station]
+		if !ok {
 			continue
 		}
+		row := weatherObservation(ws.cityId, ws.station, obs)
+		var prev *store.WeatherObservation
+		if p, ok := previous[ws.cityId]; ok {
+			prev = &p
+		}
+		c.checkWeatherObservation(&row, prev, now)
+		rows = append(rows, row)
 	}
-	fmt.Println("Realtime weather data updated.")
-}
-
-func (c *DataClient) insertRTWeatherData(cityId int, obs *meteo.WeatherObservation) error {
-	sqlStmnt, err := c.DB.Prepare(`
-		INSERT INTO current_weather(
-			city_id,
-			recorded_at,
-			wind_speed,
-			wind_direction,
-			air_temp_c,
-			precipitation,
-			cloud_coverage,
-			observed_at
-		)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8)
-	`)
-	if err != nil {
-		return fmt.Errorf("could not prepare statement: %w", err)
+	if err := c.store.SaveWeatherObservations(ctx, rows); err != nil {
+		return report, fmt.Errorf("could not refresh current_weather: %w", err)
 	}
+	c.commitValidators(ctx, validators)
+	report.RowsWritten = len(rows)
+	return report, nil
+}
 
+// weatherObservation converts an NWS observation from station for the
+// store.
+func weatherObservation(cityId int, station string, obs *meteo.WeatherObservation) store.WeatherObservation {
 	// Check for empty values.
 	var cloudLayersAmount string
 	if len(obs.Properties.CloudLayers) > 0 {
@@ -524,20 +309,23 @@ func (c *DataClient) insertRTWeatherData(cityId int, obs *meteo.WeatherObservati
 		strWindSpeed = strconv.Itoa(int(fWindSpeedMPH))
 	}
 
-	_, err = sqlStmnt.Exec(
-		cityId,
-		obs.RecordedAt,
-		strWindSpeed,
-		obs.Properties.WindDirection.Value,
-		obs.Properties.Temperature.Value,
-		obs.Properties.Precipitation.Value,
-		cloudLayersAmount,
-		obs.Properties.Timestamp,
-	)
-	if err != nil {
-		return err
+	row := store.WeatherObservation{
+		CityID:        cityId,
+		RecordedAt:    obs.RecordedAt,
+		WindSpeedMph:  strWindSpeed,
+		WindDirection: obs.Properties.WindDirection.Value,
+		AirTempC:      obs.Properties.Temperature.Value,
+		Precipitation: obs.Properties.Precipitation.Value,
+		CloudCoverage: cloudLayersAmount,
+		ObservedAt:    obs.Properties.Timestamp,
+		Station:       station,
+	}
+	// GeoJSON positions are [longitude, latitude].
+	if coords := obs.Geometry.Coordinates; len(coords) >= 2 {
+		row.StationLongitude = &coords[0]
+		row.StationLatitude = &coords[1]
 	}
-	return nil
+	return row
 }
 
 type cityWeatherStation struct {
@@ -545,229 +333,138 @@ type cityWeatherStation struct {
 	station string
 }
 
-func (c *DataClient) GetWeatherStations() ([]cityWeatherStation, error) {
-	rows, err := c.DB.Query(`SELECT id, weather_station FROM cities`)
+// GetWeatherStations returns the station of every city that has one.
+func (c *DataClient) GetWeatherStations(ctx context.Context) ([]cityWeatherStation, error) {
+	cities, err := c.store.Cities(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	var weatherStations []cityWeatherStation
-	for rows.Next() {
-		var id int
-		var station string
-		if err := rows.Scan(&id, &station); err != nil {
-			return nil, err
+	for _, city := range cities {
+		if city.WeatherStation == nil {
+			continue
 		}
-		cityWeatherStation := cityWeatherStation{id, station}
-		weatherStations = append(weatherStations, cityWeatherStation)
+		weatherStations = append(weatherStations, cityWeatherStation{city.ID, *city.WeatherStation})
 	}
 	return weatherStations, nil
 }
 
-type CurrentSurfSpotConditions struct {
-	SpotId                int
-	RecordedAt            time.Time
-	DomSwellHeightM       *float64 // from buoy data
-	DomSwellDir           *float64 // from buoy data
-	WindSpeedMph          *string  // from city weather data
-	WindDirection         *string  // from city weather data
-	AirTempDegC           *float64
-	WaterTempDegC         *float64 // from buoy data
-	Precipitation         *float64 // from city weather data
-	CloudCoverage         *string  // from city weather data
-	DominantWavePeriodSec *float64 // from buoy data
-	BuoyId                int
-}
+// UpdateCurrentSurfConditions rebuilds every spot's current conditions
+// from the latest buoy and weather observations and replaces them in one
+// step, so readers never see them half rebuilt.
+func (c *DataClient) UpdateCurrentSurfConditions(ctx context.Context) (RunReport, error) {
+	var report RunReport
 
-func (c *DataClient) UpdateCurrentSurfConditions(api *meteo.Client) {
 	// Iterate through surfspots
-	surfSpots, err := c.GetSurfSpots()
-	if err != nil {
-		fmt.Printf("could not get surfspot ids: %v", err)
-	}
-	// clear current conditions data from table
-	_, err = c.DB.Exec(`TRUNCATE current_surf_spot_conditions`)
+	surfSpots, err := c.store.Spots(ctx)
 	if err != nil {
-		fmt.Printf("could not truncate current_surf_spot_conditions: %v", err)
+		return report, fmt.Errorf("could not get surfspot ids: %w", err)
 	}
 
 	// Build []CuurentSurfSpotConditions from surfSpots.
-	conditions, err := c.buildCurrentConditions(surfSpots)
+	conditions, err := c.buildCurrentConditions(ctx, surfSpots)
 	if err != nil {
-		fmt.Printf("could not build current surf conditions: %v", err)
+		return report, fmt.Errorf("could not build current surf conditions: %w", err)
 	}
 
-	// For each data set of conditions, insert into database
-	for _, data := range conditions {
-		if err = c.insertCurrentSurfConditions(data); err != nil {
-			fmt.Printf("could not insert surf conditions data into table: %v", err)
-		}
+	if err := c.store.ReplaceConditions(ctx, conditions); err != nil {
+		return report, fmt.Errorf("could not refresh current_surf_spot_conditions: %w", err)
+	}
+	report.RowsWritten = len(conditions)
+	for _, cond := range conditions {
+		report.Changed = append(report.Changed, cond.SpotId)
 	}
-	fmt.Println("Current surf conditions updated.")
+	return report, nil
 }
 
-func (c *DataClient) insertCurrentSurfConditions(data CurrentSurfSpotConditions) error {
-	sqlStmnt, err := c.DB.Prepare(`
-		INSERT INTO current_surf_spot_conditions (
-		spot_id,
-		recorded_at,
-		dom_swell_height_m,
-		dom_swell_dir,
-		wind_speed_mph,
-		wind_direction,
-		air_temp_deg_c,
-		water_temp_deg_c,
-		precipitation,
-		cloud_coverage,
-		domwp_sec,
-		nearest_buoy
-		)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-	`)
+func (c *DataClient) buildCurrentConditions(ctx context.Context, surfSpots []store.Spot) ([]models.CurrentSurfSpotConditions, error) {
+	buoyObs, err := c.store.LatestBuoyObservations(ctx)
 	if err != nil {
-		return fmt.Errorf("could not prepare statment %w", err)
-	}
-	defer sqlStmnt.Close()
-
-	_, err = sqlStmnt.Exec(
-		data.SpotId,
-		data.RecordedAt,
-		data.DomSwellHeightM,
-		data.DomSwellDir,
-		data.WindSpeedMph,
-		data.WindDirection,
-		data.AirTempDegC,
-		data.WaterTempDegC,
-		data.Precipitation,
-		data.CloudCoverage,
-		data.DominantWavePeriodSec,
-		data.BuoyId,
-	)
+		return nil, err
+	}
+	buoys := make(map[int]store.BuoyObservation, len(buoyObs))
+	for _, obs := range buoyObs {
+		buoys[obs.BuoyID] = obs
+	}
+
+	weatherObs, err := c.store.LatestWeatherObservations(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	weather := make(map[int]store.WeatherObservation, len(weatherObs))
+	for _, obs := range weatherObs {
+		weather[obs.CityID] = obs
+	}
+
+	storedBuoys, err := c.store.Buoys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	buoyLocations := make(map[int]store.Buoy, len(storedBuoys))
+	for _, b := range storedBuoys {
+		buoyLocations[b.ID] = b
 	}
-	return nil
-}
 
-func (c *DataClient) buildCurrentConditions(surfSpots []surfSpot) ([]CurrentSurfSpotConditions, error) {
-	var conditionsSlice []CurrentSurfSpotConditions
-	var conditions CurrentSurfSpotConditions
+	now := time.Now()
+	maxAge := c.cfg.QC.MaxAge.Duration
 
+	var conditionsSlice []models.CurrentSurfSpotConditions
 	for _, surfSpot := range surfSpots {
 		// for each surfspot, get all the correlating data to build a current surf spot conditions struct.
-		conditions.SpotId = surfSpot.ID
-		conditions.BuoyId = surfSpot.NearestBuoy
-		buoyQuery := `
-	 		SELECT recorded_at, waveh_m, meanwavedir_degt, domwp_sec, watert_degc
-			FROM real_time_buoy_data_points
-			WHERE buoy_id = $1
-		`
-		// use surfSpot.NearestBuoy to get current buoy data.
-		buoyData, err := c.DB.Query(buoyQuery, surfSpot.NearestBuoy)
-		if err != nil {
-			return nil, err
+		conditions := models.CurrentSurfSpotConditions{
+			SpotId:      surfSpot.ID,
+			NearestBuoy: surfSpot.NearestBuoy,
 		}
-		defer buoyData.Close()
-
-		var recordedAt time.Time
-		var domSwellHeightM *float64
-		var domSwellDir *float64
-		var domWavePeriod *float64
-		var waterTemp *float64
-
-		for buoyData.Next() {
-			if err := buoyData.Scan(
-				&recordedAt,
-				&domSwellHeightM,
-				&domSwellDir,
-				&domWavePeriod,
-				&waterTemp,
-			); err != nil {
-				fmt.Printf("could not scan rows for buoyData while building CurrentSurfSpotConditions: %v", err)
-				continue
-			}
 
-			conditions.RecordedAt = recordedAt.UTC()
-			conditions.DomSwellHeightM = domSwellHeightM
-			conditions.DomSwellDir = domSwellDir
-			conditions.DominantWavePeriodSec = domWavePeriod
-			conditions.WaterTempDegC = waterTemp
+		// use surfSpot.NearestBuoy to get current buoy data.
+		// Values that failed quality control are left out; their flags
+		// are kept so clients can tell why.
+		if surfSpot.NearestBuoy != 0 {
+			conditions.Provenance.Buoy.ID = strconv.Itoa(surfSpot.NearestBuoy)
+		}
+		if loc, ok := buoyLocations[surfSpot.NearestBuoy]; ok {
+			d := spacial.Haversine(surfSpot.Latitude, surfSpot.Longitude, loc.Latitude, loc.Longitude)
+			conditions.Provenance.Buoy.DistanceKm = &d
+		}
+		if b, ok := buoys[surfSpot.NearestBuoy]; ok {
+			stale := now.Sub(b.RecordedAt) > maxAge
+			recordedAt := b.RecordedAt.UTC()
+			conditions.RecordedAt = recordedAt
+			conditions.Provenance.Buoy.ObservedAt = &recordedAt
+			conditions.DomSwellHeightM = carry(&conditions.QC, "DomSwellHeightM", b.QC, "WaveHeightM", stale, b.WaveHeightM)
+			conditions.DomSwellDir = carry(&conditions.QC, "DomSwellDir", b.QC, "MeanWaveDirectionDegT", stale, b.MeanWaveDirectionDegT)
+			conditions.DominantWavePeriodSec = carry(&conditions.QC, "DominantWavePeriodSec", b.QC, "DominantWavePeriodSec", stale, b.DominantWavePeriodSec)
+			conditions.WaterTempDegC = carry(&conditions.QC, "WaterTempDegC", b.QC, "WaterTempDegC", stale, b.WaterTempDegC)
 		}
-
-		weatherQuery := `
-			SELECT wind_speed, wind_direction, air_temp_c, precipitation, cloud_coverage
-			FROM current_weather
-			WHERE city_id = $1
-		`
 
 		// use surfSpot.CityId to get current weather data
-		weatherData, err := c.DB.Query(weatherQuery, surfSpot.CityId)
-		if err != nil {
-			return nil, err
-		}
-		defer weatherData.Close()
-
-		var windSpeed *string
-		var windDir *string
-		var airTemp *float64
-		var precipitation *float64
-		var cloudCoverage *string
-
-		for weatherData.Next() {
-			if err := weatherData.Scan(
-				&windSpeed,
-				&windDir,
-				&airTemp,
-				&precipitation,
-				&cloudCoverage,
-			); err != nil {
-				fmt.Printf("could not scan rows for weatherData while building CurrentSurfSpotConditions: %v", err)
-				continue
+		if w, ok := weather[surfSpot.CityID]; ok {
+			stale := now.Sub(w.RecordedAt) > maxAge
+			recordedAt := w.RecordedAt.UTC()
+			conditions.Provenance.Weather.ID = w.Station
+			conditions.Provenance.Weather.ObservedAt = &recordedAt
+			if w.StationLatitude != nil && w.StationLongitude != nil {
+				d := spacial.Haversine(surfSpot.Latitude, surfSpot.Longitude, *w.StationLatitude, *w.StationLongitude)
+				conditions.Provenance.Weather.DistanceKm = &d
+			}
+			if carry(&conditions.QC, "WindSpeedMph", w.QC, "WindSpeedMph", stale, windSpeed(w.WindSpeedMph)) != nil {
+				conditions.WindSpeedMph = &w.WindSpeedMph
+			}
+			if windDirection := carry(&conditions.QC, "WindDirection", w.QC, "WindDirection", stale, w.WindDirection); windDirection != nil {
+				windDir := strconv.FormatFloat(*windDirection, 'f', -1, 64)
+				conditions.WindDirection = &windDir
 			}
-			conditions.WindSpeedMph = windSpeed
-			conditions.WindDirection = windDir
-			conditions.AirTempDegC = airTemp
-			conditions.Precipitation = precipitation
-			conditions.CloudCoverage = cloudCoverage
+			conditions.AirTempDegC = carry(&conditions.QC, "AirTempDegC", w.QC, "AirTempC", stale, w.AirTempC)
+			conditions.Precipitation = carry(&conditions.QC, "Precipitation", w.QC, "Precipitation", stale, w.Precipitation)
+			conditions.CloudCoverage = &w.CloudCoverage
 		}
+		conditions.Provenance.UpdateFreshness(now, maxAge)
 		conditionsSlice = append(conditionsSlice, conditions)
 	}
 	return conditionsSlice, nil
 }
 
-type surfSpot struct {
-	ID          int
-	Name        string
-	CityId      int
-	NearestBuoy int
-	TideRegion  int
-}
-
-func (c *DataClient) GetSurfSpots() ([]surfSpot, error) {
-	rows, err := c.DB.Query(`SELECT id, name, city_id, nearest_buoy, tide_region_id FROM surfspot`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var surfSpots []surfSpot
-	var id int
-	var name string
-	var cityId int
-	var buoy int
-	var tide_region int
-
-	for rows.Next() {
-		if err := rows.Scan(&id, &name, &cityId, &buoy, &tide_region); err != nil {
-			return nil, err
-		}
-		surfSpot := surfSpot{id, name, cityId, buoy, tide_region}
-		surfSpots = append(surfSpots, surfSpot)
-	}
-	return surfSpots, nil
-}
-
 type tidePrediction struct {
 	Time  time.Time
 	Value float32
@@ -777,29 +474,11 @@ type tidePrediction struct {
 // GetCurrentSurfSpotTideData returns the current day's high and low tide predictions for the provided
 // surf spot.
 func (c *DataClient) GetCurrentSurfSpotTideData() ([]tidePrediction, error) {
-	now := time.Now().Format("2006-01-02")
-	fmt.Println(now)
-
 	return nil, nil
 }
 
 // UTILITY FUNCITONS
 
-// These functions may be used in other files within dbLib
-func fetchURL(url string) ([]byte, error) {
-	response, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed request to %s: %w", url, err)
-	}
-	defer response.Body.Close()
-
-	data, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response from %s: %w", url, err)
-	}
-	return data, nil
-}
-
 func KMHToMPH(kmh float64) float64 {
 	return kmh * 0.621371
 }
@@ -1,63 +1,256 @@
 package dbLib
 
 import (
-	meteo "Go_surf_redesign/src/backend/api"
+	"Go_surf_redesign/src/backend/events"
+	"Go_surf_redesign/src/backend/provider"
+	"Go_surf_redesign/src/backend/scheduler"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/config"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 )
 
-func StartDataIngestion(ctx context.Context, db *DataClient, api *meteo.Client) error {
-	fmt.Println("Starting data ingestion.")
+// Ingestion sources, in the order they must run.
+const (
+	SourceBuoys      = events.SourceBuoys
+	SourceWeather    = events.SourceWeather
+	SourceConditions = events.SourceConditions
+	SourceTides      = events.SourceTides
+	SourceForecasts  = events.SourceForecasts
+)
 
-	go func() {
-		weatherReady := false
+// Sources lists every ingestion source in run order.
+var Sources = []string{SourceBuoys, SourceWeather, SourceConditions, SourceTides, SourceForecasts}
 
-		nextBuoy := time.Now()
-		nextWeather := time.Now()
-		nextSurf := time.Now()
+// sourceDependencies lists the sources each source reads from.
+var sourceDependencies = map[string][]string{
+	SourceConditions: {SourceBuoys, SourceWeather},
+}
 
-		for {
-			now := time.Now()
+// RunSource refreshes a single ingestion source once and records the run
+// in ingestion_runs.
+func (c *DataClient) RunSource(ctx context.Context, providers *provider.Set, source string) error {
+	var update func(ctx context.Context) (RunReport, error)
+	switch source {
+	case SourceBuoys:
+		update = func(ctx context.Context) (RunReport, error) { return c.UpdateRTBuoyData(ctx, providers.Waves) }
+	case SourceWeather:
+		update = func(ctx context.Context) (RunReport, error) { return c.UpdateRTWeatherData(ctx, providers.Weather) }
+	case SourceConditions:
+		update = c.UpdateCurrentSurfConditions
+	case SourceTides:
+		update = func(ctx context.Context) (RunReport, error) { return c.UpdateStaticTideData(ctx, providers.Tides) }
+	case SourceForecasts:
+		update = func(ctx context.Context) (RunReport, error) { return c.UpdateForecastData(ctx, providers.Forecast) }
+	default:
+		return fmt.Errorf("unknown ingestion source %q", source)
+	}
+	return c.recordRun(ctx, source, update)
+}
 
-			// 1. Buoy data
-			if now.After(nextBuoy) {
-				db.UpdateRTBuoyData(ctx, api)
-				fmt.Println("DataBase: updating current buoy data.")
-				nextBuoy = now.Add(15 * time.Minute)
-			}
+// jobConfig returns the configured schedule for source.
+func jobConfig(cfg config.IngestionConfig, source string) config.JobConfig {
+	switch source {
+	case SourceBuoys:
+		return cfg.Buoys
+	case SourceWeather:
+		return cfg.Weather
+	case SourceConditions:
+		return cfg.Conditions
+	case SourceTides:
+		return cfg.Tides
+	default:
+		return cfg.Forecasts
+	}
+}
 
-			// 2. Weather data
-			if now.After(nextWeather) {
-				db.UpdateRTWeatherData(ctx, api)
-				nextWeather = now.Add(time.Hour)
+// NewIngestionScheduler registers a job for each source (every source when
+// none are given) using the intervals in the ingestion config. Dependencies
+// on sources that are not selected are dropped, so an instance that only
+// builds conditions does not wait on buoy data it never fetches.
+func NewIngestionScheduler(db *DataClient, providers *provider.Set, sources ...string) (*scheduler.Scheduler, error) {
+	for _, source := range sources {
+		if !slices.Contains(Sources, source) {
+			return nil, fmt.Errorf("unknown ingestion source %q", source)
+		}
+	}
+	if len(sources) == 0 {
+		sources = Sources
+	}
 
-				weatherReady = true
-			}
+	s := scheduler.New()
+	for _, source := range Sources {
+		if !slices.Contains(sources, source) {
+			continue
+		}
 
-			// 3. Surf conditions
-			if weatherReady && now.After(nextSurf) {
-				db.UpdateCurrentSurfConditions(api)
-				nextSurf = now.Add(15 * time.Minute)
+		var deps []string
+		for _, dep := range sourceDependencies[source] {
+			if slices.Contains(sources, dep) {
+				deps = append(deps, dep)
 			}
+		}
+
+		cfg := jobConfig(db.cfg.Ingestion, source)
+		err := s.Register(scheduler.Job{
+			Name:      source,
+			Interval:  cfg.Interval.Duration,
+			Jitter:    cfg.Jitter.Duration,
+			Timeout:   cfg.Timeout.Duration,
+			DependsOn: deps,
+			Run: func(ctx context.Context) error {
+				return db.RunSource(ctx, providers, source)
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Ingestion is an ingestion loop started by StartDataIngestion. It
+// reports the state of the scheduler while this instance is running the
+// jobs.
+type Ingestion struct {
+	mu        sync.Mutex
+	scheduler *scheduler.Scheduler // nil while another instance leads
+}
+
+// Jobs reports whether this instance is running the ingestion jobs and,
+// when it is, the state of each job.
+func (i *Ingestion) Jobs() (leading bool, jobs []scheduler.Status) {
+	i.mu.Lock()
+	s := i.scheduler
+	i.mu.Unlock()
+	if s == nil {
+		return false, nil
+	}
+	return true, s.Status()
+}
+
+func (i *Ingestion) setScheduler(s *scheduler.Scheduler) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.scheduler = s
+}
 
-			time.Sleep(30 * time.Second)
+// StartDataIngestion starts RunIngestion in the background and returns a
+// handle reporting its jobs.
+func StartDataIngestion(ctx context.Context, db *DataClient, providers *provider.Set, sources ...string) (*Ingestion, error) {
+	// Fail on bad sources now rather than in the background.
+	if _, err := NewIngestionScheduler(db, providers, sources...); err != nil {
+		return nil, err
+	}
+
+	db.logger.Info("starting data ingestion")
+	ing := &Ingestion{}
+	go func() {
+		if err := ing.run(ctx, db, providers, sources); err != nil {
+			db.logger.Error("ingestion stopped", "error", err)
 		}
 	}()
+	return ing, nil
+}
+
+// ingestionLock names the lock held by the instance running ingestion.
+const ingestionLock = "ingestion"
+
+// ErrIngestionLocked is returned when another instance is running
+// ingestion.
+var ErrIngestionLocked = errors.New("another instance is running ingestion")
+
+// RunIngestion runs the ingestion scheduler until ctx is cancelled. When
+// the store is shared between processes, only the instance holding the
+// ingestion lock runs jobs. The others retry every leader_retry, so one
+// of them takes over when the holder stops or loses its database
+// connection.
+func RunIngestion(ctx context.Context, db *DataClient, providers *provider.Set, sources ...string) error {
+	return new(Ingestion).run(ctx, db, providers, sources)
+}
+
+func (i *Ingestion) run(ctx context.Context, db *DataClient, providers *provider.Set, sources []string) error {
+	if _, ok := db.store.(store.Locker); !ok {
+		s, err := NewIngestionScheduler(db, providers, sources...)
+		if err != nil {
+			return err
+		}
+		i.setScheduler(s)
+		return s.Run(ctx)
+	}
+
+	retry := time.NewTicker(db.cfg.Ingestion.LeaderRetry.Duration)
+	defer retry.Stop()
+	waiting := false
+	for {
+		lease, err := db.LockIngestion(ctx)
+		switch {
+		case err == nil:
+			waiting = false
+			db.logger.Info("took the ingestion lock")
+			if err := i.lead(ctx, db, lease, providers, sources); err != nil {
+				return err
+			}
+		case errors.Is(err, ErrIngestionLocked):
+			if !waiting {
+				db.logger.Info("another instance is running ingestion; waiting to take over")
+				waiting = true
+			}
+		default:
+			db.logger.Error("could not take the ingestion lock", "error", err)
+		}
 
-	fmt.Println("Data ingestion started.")
-	return nil
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-retry.C:
+		}
+	}
 }
 
-// tickerRunner manages job execution timing.
-// Job runs once when tickerRunner is called,
-// then runs again at time implementation.
-// func tickerRunner(interval time.Duration, job func()) {
-// 	job()
-// 	ticker := time.NewTicker(interval)
-// 	defer ticker.Stop()
+// lead runs the scheduler while lease is held and releases it after.
+func (i *Ingestion) lead(ctx context.Context, c *DataClient, lease store.Lease, providers *provider.Set, sources []string) error {
+	defer lease.Release()
+
+	s, err := NewIngestionScheduler(c, providers, sources...)
+	if err != nil {
+		return err
+	}
+	i.setScheduler(s)
+	defer i.setScheduler(nil)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lease.Lost():
+			c.logger.Warn("lost the ingestion lock; stopping jobs")
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+	return s.Run(runCtx)
+}
 
-// 	for range ticker.C {
-// 		job()
-// 	}
-// }
+// LockIngestion takes the ingestion lock, so jobs run outside the
+// scheduler do not overlap an instance running it. It returns
+// ErrIngestionLocked when another instance holds the lock, and a nil
+// lease when the store is not shared between processes.
+func (c *DataClient) LockIngestion(ctx context.Context) (store.Lease, error) {
+	locker, ok := c.store.(store.Locker)
+	if !ok {
+		return nil, nil
+	}
+	lease, ok, err := locker.TryLock(ctx, ingestionLock)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrIngestionLocked
+	}
+	return lease, nil
+}
@@ -0,0 +1,58 @@
+package dbLib
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/provider"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"fmt"
+)
+
+// UpdateForecastData replaces the hourly forecast of every city with the
+// latest NWS forecast. A city whose forecast cannot be fetched keeps its
+// previous forecast.
+func (c *DataClient) UpdateForecastData(ctx context.Context, forecasts provider.ForecastProvider) (RunReport, error) {
+	var report RunReport
+
+	cities, err := c.store.Cities(ctx)
+	if err != nil {
+		return report, fmt.Errorf("could not get cities: %w", err)
+	}
+
+	failed := 0
+	for _, city := range cities {
+		forecast, err := forecasts.GetHourlyForecast(ctx, city.Latitude, city.Longitude)
+		if err != nil {
+			c.log(ctx).Warn("could not get forecast", "city", city.ID, "error", err)
+			report.fail(fmt.Sprintf("city %d", city.ID), err)
+			failed++
+			continue
+		}
+		if err := c.store.ReplaceCityForecast(ctx, city.ID, forecastPeriods(forecast.Properties.Periods)); err != nil {
+			return report, fmt.Errorf("could not store forecast for city %d: %w", city.ID, err)
+		}
+		report.RowsWritten += len(forecast.Properties.Periods)
+	}
+	if failed > 0 && failed == len(cities) {
+		return report, fmt.Errorf("could not fetch a forecast for any of %d cities", failed)
+	}
+	return report, nil
+}
+
+// forecastPeriods converts NWS hourly periods for the store.
+func forecastPeriods(periods []models.HourlyPeriods) []store.ForecastPeriod {
+	converted := make([]store.ForecastPeriod, len(periods))
+	for i, p := range periods {
+		converted[i] = store.ForecastPeriod{
+			StartTime:           p.StartTime,
+			EndTime:             p.EndTime,
+			Temperature:         p.Temperature,
+			TemperatureUnit:     p.TemperatureUnit,
+			WindSpeed:           p.WindSpeed,
+			WindDirection:       p.WindDirection,
+			PrecipitationChance: p.ProbabilityOfPrecipitation.Value,
+			ShortForecast:       p.ShortForecast,
+		}
+	}
+	return converted
+}
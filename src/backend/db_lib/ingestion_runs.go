@@ -0,0 +1,107 @@
+package dbLib
+
+import (
+	"Go_surf_redesign/src/backend/logging"
+	"Go_surf_redesign/src/backend/metrics"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"time"
+)
+
+// RunReport is the outcome of a single ingestion run.
+type RunReport struct {
+	RowsWritten int
+	// Unchanged counts stations whose upstream data had not changed since
+	// the last run, so nothing was written for them.
+	Unchanged int
+	// Failures maps a station, buoy, spot or file to the error it hit.
+	Failures map[string]string
+	// Changed lists the IDs of the entities the run rewrote, when the
+	// source tracks them (surf spots for conditions).
+	Changed []int
+}
+
+// fail records a per-station failure without failing the whole run.
+func (r *RunReport) fail(id string, err error) {
+	if r.Failures == nil {
+		r.Failures = make(map[string]string)
+	}
+	r.Failures[id] = err.Error()
+}
+
+// runStatus classifies a run by its report and error.
+func runStatus(report RunReport, err error) string {
+	switch {
+	case err != nil:
+		return store.RunFailed
+	case len(report.Failures) > 0:
+		return store.RunPartial
+	}
+	return store.RunOK
+}
+
+// finishRun completes a run from its report and error.
+func (c *DataClient) finishRun(ctx context.Context, id int64, report RunReport, runErr error) error {
+	var errText *string
+	if runErr != nil {
+		msg := runErr.Error()
+		errText = &msg
+	}
+
+	finishedAt := time.Now().UTC()
+	return c.store.FinishRun(ctx, store.IngestionRun{
+		ID:          id,
+		Status:      runStatus(report, runErr),
+		FinishedAt:  &finishedAt,
+		RowsWritten: report.RowsWritten,
+		Failures:    report.Failures,
+		Error:       errText,
+	})
+}
+
+// recordRun runs fn and writes its outcome to the ingestion run log. Failing to
+// write the audit row is reported but never fails the ingestion itself. fn
+// gets a context carrying a logger tagged with the source and the run id,
+// so everything logged during the run can be traced back to its row.
+func (c *DataClient) recordRun(ctx context.Context, source string, fn func(ctx context.Context) (RunReport, error)) error {
+	// The audit row must be written even when the job's context has
+	// expired, so it uses a context that is not cancelled with the job.
+	auditCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	logger := c.log(ctx).With("source", source)
+	id, auditErr := c.store.StartRun(auditCtx, source, time.Now().UTC())
+	if auditErr != nil {
+		logger.Error("could not record start of ingestion run", "error", auditErr)
+	} else {
+		logger = logger.With("run", id)
+	}
+
+	start := time.Now()
+	report, err := fn(logging.NewContext(ctx, logger))
+	if err == nil {
+		c.publish(source, report.Changed...)
+	}
+	metrics.IngestionDuration.WithLabelValues(source).Observe(time.Since(start).Seconds())
+	metrics.IngestionRuns.WithLabelValues(source, runStatus(report, err)).Inc()
+	metrics.IngestionRows.WithLabelValues(source).Add(float64(report.RowsWritten))
+	attrs := []any{
+		"status", runStatus(report, err),
+		"duration", time.Since(start),
+		"rows", report.RowsWritten,
+		"unchanged", report.Unchanged,
+		"failures", len(report.Failures),
+	}
+	if err != nil {
+		logger.Error("ingestion run failed", append(attrs, "error", err)...)
+	} else {
+		logger.Info("ingestion run finished", attrs...)
+	}
+
+	if auditErr == nil {
+		if auditErr := c.finishRun(auditCtx, id, report, err); auditErr != nil {
+			logger.Error("could not record end of ingestion run", "error", auditErr)
+		}
+	}
+	return err
+}
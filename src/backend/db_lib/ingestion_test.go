@@ -0,0 +1,216 @@
+package dbLib
+
+import (
+	"Go_surf_redesign/src/backend/api/meteotest"
+	"Go_surf_redesign/src/backend/archive"
+	"Go_surf_redesign/src/backend/provider"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/backend/store/postgres"
+	"Go_surf_redesign/src/config"
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testDatabaseEnv names a Postgres database the integration tests may
+// wipe, for example postgres://localhost/gosurf_test?sslmode=disable.
+const testDatabaseEnv = "GOSURF_TEST_DATABASE_URL"
+
+// testStores returns the stores every ingestion test runs against: the
+// memory store, and Postgres when testDatabaseEnv is set.
+func testStores(t *testing.T) map[string]func(t *testing.T) store.Store {
+	stores := map[string]func(t *testing.T) store.Store{
+		config.StoreMemory: func(t *testing.T) store.Store { return memory.New() },
+	}
+	if url := os.Getenv(testDatabaseEnv); url != "" {
+		stores[config.StorePostgres] = func(t *testing.T) store.Store {
+			return newPostgresStore(t, url)
+		}
+	} else {
+		t.Logf("%s is not set; skipping the postgres store", testDatabaseEnv)
+	}
+	return stores
+}
+
+// newPostgresStore migrates the test database and clears every table.
+func newPostgresStore(t *testing.T, url string) store.Store {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Database.URL = url
+	st, err := postgres.Open(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	ctx := context.Background()
+	if _, err := st.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.DB().ExecContext(ctx, `
+		TRUNCATE buoys, cities, surfspot, tide_data, real_time_buoy_data_points,
+			buoy_data_history, current_weather, current_surf_spot_conditions,
+			city_forecast, ingestion_runs, raw_payloads
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+// newTestClient returns a client on st whose providers read from fake NDBC
+// and NWS servers and archive what they fetch in arc, if it is not nil.
+func newTestClient(t *testing.T, st store.Store, arc archive.Archive) (*DataClient, *provider.Set, *meteotest.NDBC, *meteotest.NWS) {
+	t.Helper()
+	ndbc := meteotest.NewNDBC(t)
+	ndbc.SetDefault(meteotest.BuoyFile())
+	nws := meteotest.NewNWS(t)
+	nws.AddStation(meteotest.Station{ID: "KTST", Lat: 33.8, Lon: -118.2}, meteotest.Observation())
+
+	cfg := config.Default()
+	cfg.Data.Dir = "../data"
+	cfg.Upstream = meteotest.Upstream(ndbc, nws)
+
+	providers, err := provider.New(cfg, arc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDataClient(st, cfg), providers, ndbc, nws
+}
+
+// A full ingestion cycle against the fakes fills every current table with
+// one row per buoy, city and spot, and records each run.
+func TestIngestionAgainstFakes(t *testing.T) {
+	for name, open := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			testIngestionAgainstFakes(t, open(t))
+		})
+	}
+}
+
+func testIngestionAgainstFakes(t *testing.T, st store.Store) {
+	dc, providers, ndbc, _ := newTestClient(t, st, nil)
+	ctx := context.Background()
+
+	if err := dc.LoadStaticData(ctx, providers); err != nil {
+		t.Fatal(err)
+	}
+	buoys, err := st.Buoys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cities, err := st.Cities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spots, err := st.Spots(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buoys) == 0 || len(cities) == 0 || len(spots) == 0 {
+		t.Fatalf("static data not loaded: %d buoys, %d cities, %d spots", len(buoys), len(cities), len(spots))
+	}
+	for _, city := range cities {
+		if city.WeatherStation == nil || *city.WeatherStation != "KTST" {
+			t.Errorf("city %d did not resolve to the fake station", city.ID)
+		}
+	}
+	for _, spot := range spots {
+		if spot.NearestBuoy == 0 {
+			t.Errorf("spot %d has no nearest buoy", spot.ID)
+		}
+	}
+
+	for _, source := range Sources {
+		if err := dc.RunSource(ctx, providers, source); err != nil {
+			t.Fatalf("%s: %v", source, err)
+		}
+	}
+
+	buoyObs, err := st.LatestBuoyObservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buoyObs) != len(buoys) {
+		t.Errorf("got %d buoy observations, want %d", len(buoyObs), len(buoys))
+	}
+	weather, err := st.LatestWeatherObservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weather) != len(cities) {
+		t.Errorf("got %d weather observations, want %d", len(weather), len(cities))
+	}
+	for _, spot := range spots {
+		conditions, err := st.SpotConditions(ctx, spot.ID)
+		if err != nil {
+			t.Errorf("spot %d: %v", spot.ID, err)
+			continue
+		}
+		if conditions.DomSwellHeightM == nil || *conditions.DomSwellHeightM != 1.2 {
+			t.Errorf("spot %d does not have the fake swell height", spot.ID)
+		}
+		if w := conditions.Provenance.Weather; w.ID != "KTST" || w.DistanceKm == nil {
+			t.Errorf("spot %d weather provenance: %+v", spot.ID, w)
+		}
+	}
+	if all, err := st.AllConditions(ctx); err != nil || len(all) != len(spots) {
+		t.Errorf("AllConditions: %d conditions, %v; want %d", len(all), err, len(spots))
+	}
+	citySpots, err := st.SpotsByCity(ctx, cities[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed, total, err := st.ListSpots(ctx, store.ListFilter{RegionFilter: store.RegionFilter{CityID: cities[0].ID}}, store.Keyset{Limit: 1})
+	if err != nil || total != len(citySpots) || len(listed) != 1 {
+		t.Fatalf("ListSpots: %d of %d spots, %v; want 1 of %d", len(listed), total, err, len(citySpots))
+	}
+	if total > 1 {
+		rest, _, err := st.ListSpots(ctx, store.ListFilter{RegionFilter: store.RegionFilter{CityID: cities[0].ID}},
+			store.Keyset{AfterName: listed[0].Name, AfterID: listed[0].ID})
+		if err != nil || len(rest) != total-1 || rest[0].Name < listed[0].Name {
+			t.Errorf("ListSpots after %q: %d spots, %v", listed[0].Name, len(rest), err)
+		}
+	}
+	if listedCities, total, err := st.ListCities(ctx, store.ListFilter{}, store.Keyset{}); err != nil || total != len(cities) || len(listedCities) != total {
+		t.Errorf("ListCities: %d of %d cities, %v; want %d", len(listedCities), total, err, len(cities))
+	}
+	region, err := st.RegionConditions(ctx, store.RegionFilter{CityID: cities[0].ID, State: strings.ToUpper(cities[0].State)})
+	if err != nil || len(region) != len(citySpots) {
+		t.Errorf("RegionConditions: %d spots, %v; want %d", len(region), err, len(citySpots))
+	}
+	for _, r := range region {
+		if r.Conditions == nil || r.Conditions.SpotId != r.Spot.ID || r.Conditions.Provenance.Buoy.ID == "" {
+			t.Errorf("RegionConditions: spot %d conditions %+v", r.Spot.ID, r.Conditions)
+		}
+	}
+	runs, err := st.RecentRuns(ctx, len(Sources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, run := range runs {
+		if run.Status != store.RunOK {
+			t.Errorf("%s run finished %s", run.Source, run.Status)
+		}
+	}
+
+	// A second run sees unchanged files, and a failing buoy keeps its row.
+	ndbc.Fail(meteotest.BuoyPath("46011"), http.StatusInternalServerError)
+	report, err := dc.UpdateRTBuoyData(ctx, providers.Waves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.RowsWritten != 0 || report.Unchanged != len(buoys)-1 || len(report.Failures) != 1 {
+		t.Errorf("second run: %+v", report)
+	}
+	buoyObs, err = st.LatestBuoyObservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buoyObs) != len(buoys) {
+		t.Errorf("after a failed fetch: got %d buoy observations, want %d", len(buoyObs), len(buoys))
+	}
+}
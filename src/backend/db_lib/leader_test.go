@@ -0,0 +1,115 @@
+package dbLib
+
+import (
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockingStore is a memory store with one lock, standing in for a
+// database shared with another instance.
+type lockingStore struct {
+	*memory.Store
+
+	mu     sync.Mutex
+	holder *testLease
+}
+
+type testLease struct {
+	s    *lockingStore
+	lost chan struct{}
+}
+
+func (s *lockingStore) TryLock(ctx context.Context, name string) (store.Lease, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.holder != nil {
+		return nil, false, nil
+	}
+	s.holder = &testLease{s: s, lost: make(chan struct{})}
+	return s.holder, true, nil
+}
+
+func (l *testLease) Lost() <-chan struct{} { return l.lost }
+
+func (l *testLease) Release() error {
+	l.s.mu.Lock()
+	defer l.s.mu.Unlock()
+	if l.s.holder == l {
+		l.s.holder = nil
+	}
+	return nil
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// An instance only ingests while it holds the ingestion lock, takes over
+// once the other holder lets go, and stops when its own lease is lost.
+func TestIngestionFailover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st := &lockingStore{Store: memory.New()}
+	dc, providers, _, _ := newTestClient(t, st, nil)
+	dc.cfg.Ingestion.LeaderRetry = config.Duration{Duration: 10 * time.Millisecond}
+	if err := dc.LoadStaticData(ctx, providers); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _, err := st.TryLock(ctx, ingestionLock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ing := new(Ingestion)
+	done := make(chan error, 1)
+	go func() { done <- ing.run(ctx, dc, providers, []string{SourceBuoys}) }()
+
+	runs := func() int {
+		r, err := st.RecentRuns(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(r)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := runs(); n != 0 {
+		t.Fatalf("%d runs while another instance held the lock", n)
+	}
+	if leading, jobs := ing.Jobs(); leading || len(jobs) != 0 {
+		t.Fatalf("follower reports leading %v with jobs %+v", leading, jobs)
+	}
+
+	other.Release()
+	waitFor(t, "the first run", func() bool { return runs() > 0 })
+	if leading, jobs := ing.Jobs(); !leading || len(jobs) != 1 || jobs[0].Name != SourceBuoys {
+		t.Errorf("leader reports leading %v with jobs %+v", leading, jobs)
+	}
+
+	st.mu.Lock()
+	ours := st.holder
+	st.mu.Unlock()
+	close(ours.lost)
+	waitFor(t, "the lost lease to be released", func() bool {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		return st.holder != ours
+	})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
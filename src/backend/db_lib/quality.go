@@ -0,0 +1,123 @@
+package dbLib
+
+import (
+	"Go_surf_redesign/src/backend/qc"
+	"Go_surf_redesign/src/backend/store"
+	"strconv"
+	"time"
+)
+
+// buoyLimits are the plausible ranges of NDBC realtime2 values. The
+// sentinels are the fill values NDBC writes when a sensor reports
+// nothing usable.
+var buoyLimits = map[string]qc.Limit{
+	"WindDirectionDegT":     {Min: 0, Max: 360, Sentinels: []float64{999}},
+	"WindSpeedMetersPerSec": {Min: 0, Max: 75, Sentinels: []float64{99}},
+	"WindGustMetersPerSec":  {Min: 0, Max: 100, Sentinels: []float64{99}},
+	"WaveHeightM":           {Min: 0, Max: 25, Sentinels: []float64{99}, MaxChangePerHour: 3},
+	"DominantWavePeriodSec": {Min: 0, Max: 30, Sentinels: []float64{99}},
+	"AvgWavePeriodSec":      {Min: 0, Max: 30, Sentinels: []float64{99}},
+	"MeanWaveDirectionDegT": {Min: 0, Max: 360, Sentinels: []float64{999}},
+	"AirTempDegC":           {Min: -40, Max: 55, Sentinels: []float64{999}, MaxChangePerHour: 8},
+	"WaterTempDegC":         {Min: -3, Max: 40, Sentinels: []float64{999}, MaxChangePerHour: 4},
+}
+
+// weatherLimits are the plausible ranges of NWS station observations.
+var weatherLimits = map[string]qc.Limit{
+	"WindSpeedMph":  {Min: 0, Max: 200},
+	"WindDirection": {Min: 0, Max: 360},
+	"AirTempC":      {Min: -60, Max: 60, Sentinels: []float64{9999}, MaxChangePerHour: 10},
+	"Precipitation": {Min: 0, Max: 300},
+}
+
+// checkBuoyObservation flags the values of obs that fail the buoy limits,
+// comparing against prev for spikes when it is not nil.
+func (c *DataClient) checkBuoyObservation(obs *store.BuoyObservation, prev *store.BuoyObservation, now time.Time) {
+	fields := []qc.Field{
+		{Name: "WindDirectionDegT", Value: obs.WindDirectionDegT},
+		{Name: "WindSpeedMetersPerSec", Value: obs.WindSpeedMetersPerSec},
+		{Name: "WindGustMetersPerSec", Value: obs.WindGustMetersPerSec},
+		{Name: "WaveHeightM", Value: obs.WaveHeightM},
+		{Name: "DominantWavePeriodSec", Value: obs.DominantWavePeriodSec},
+		{Name: "AvgWavePeriodSec", Value: obs.AvgWavePeriodSec},
+		{Name: "MeanWaveDirectionDegT", Value: obs.MeanWaveDirectionDegT},
+		{Name: "AirTempDegC", Value: obs.AirTempDegC},
+		{Name: "WaterTempDegC", Value: obs.WaterTempDegC},
+	}
+	var prevAt time.Time
+	if prev != nil {
+		prevAt = prev.RecordedAt
+		prevValues := []*float64{
+			prev.WindDirectionDegT,
+			prev.WindSpeedMetersPerSec,
+			prev.WindGustMetersPerSec,
+			prev.WaveHeightM,
+			prev.DominantWavePeriodSec,
+			prev.AvgWavePeriodSec,
+			prev.MeanWaveDirectionDegT,
+			prev.AirTempDegC,
+			prev.WaterTempDegC,
+		}
+		for i := range fields {
+			if !prev.QC.Excluded(fields[i].Name) {
+				fields[i].Prev = prevValues[i]
+			}
+		}
+	}
+	checker := qc.Checker{Limits: buoyLimits, MaxAge: c.cfg.QC.MaxAge.Duration}
+	obs.QC = checker.Check(fields, obs.RecordedAt, prevAt, now)
+}
+
+// checkWeatherObservation flags the values of obs that fail the weather
+// limits, comparing against prev for spikes when it is not nil.
+func (c *DataClient) checkWeatherObservation(obs *store.WeatherObservation, prev *store.WeatherObservation, now time.Time) {
+	fields := []qc.Field{
+		{Name: "WindSpeedMph", Value: windSpeed(obs.WindSpeedMph)},
+		{Name: "WindDirection", Value: obs.WindDirection},
+		{Name: "AirTempC", Value: obs.AirTempC},
+		{Name: "Precipitation", Value: obs.Precipitation},
+	}
+	var prevAt time.Time
+	if prev != nil {
+		prevAt = prev.RecordedAt
+		prevValues := []*float64{
+			windSpeed(prev.WindSpeedMph),
+			prev.WindDirection,
+			prev.AirTempC,
+			prev.Precipitation,
+		}
+		for i := range fields {
+			if !prev.QC.Excluded(fields[i].Name) {
+				fields[i].Prev = prevValues[i]
+			}
+		}
+	}
+	checker := qc.Checker{Limits: weatherLimits, MaxAge: c.cfg.QC.MaxAge.Duration}
+	obs.QC = checker.Check(fields, obs.RecordedAt, prevAt, now)
+}
+
+// windSpeed parses a stored wind speed, which is "NA" when unknown.
+func windSpeed(mph string) *float64 {
+	v, err := strconv.ParseFloat(mph, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// carry returns field of an observation for the conditions field target,
+// recording its flag under target in dst. Excluded values are returned as
+// nil; values of a stale observation are kept and flagged stale.
+func carry(dst *qc.Flags, target string, src qc.Flags, field string, stale bool, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	dst.Set(target, src[field])
+	if stale {
+		dst.Set(target, qc.Stale)
+	}
+	if src.Excluded(field) {
+		return nil
+	}
+	return v
+}
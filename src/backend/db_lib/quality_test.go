@@ -0,0 +1,78 @@
+package dbLib
+
+import (
+	"Go_surf_redesign/src/backend/qc"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/config"
+	"context"
+	"testing"
+	"time"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+// A sentinel wave height and a spiking water temperature are flagged and
+// left out of the conditions, while an old weather observation is kept
+// and marked stale.
+func TestConditionsExcludeFlaggedValues(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+	err := st.ReplaceStatic(ctx, store.StaticData{
+		Buoys:  []store.Buoy{{ID: 46222, Name: "San Pedro", Latitude: 33.6, Longitude: -118.3}},
+		Cities: []store.City{{ID: 3, Name: "Huntington Beach", Latitude: 33.7, Longitude: -118.0}},
+		Spots:  []store.Spot{{ID: 5, Name: "Pier", Latitude: 33.65, Longitude: -118.0, CityID: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc := NewDataClient(st, config.Default())
+	now := time.Now()
+	prev := store.BuoyObservation{BuoyID: 46222, RecordedAt: now.Add(-40 * time.Minute), WaterTempDegC: ptr(18)}
+	obs := store.BuoyObservation{
+		BuoyID:                46222,
+		RecordedAt:            now.Add(-10 * time.Minute),
+		WaveHeightM:           ptr(99),
+		DominantWavePeriodSec: ptr(14),
+		WaterTempDegC:         ptr(27),
+	}
+	dc.checkBuoyObservation(&obs, &prev, now)
+	weather := store.WeatherObservation{CityID: 3, RecordedAt: now.Add(-5 * time.Hour), WindSpeedMph: "6", AirTempC: ptr(21)}
+	dc.checkWeatherObservation(&weather, nil, now)
+
+	if err := st.SaveBuoyObservations(ctx, []store.BuoyObservation{obs}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveWeatherObservations(ctx, []store.WeatherObservation{weather}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dc.UpdateCurrentSurfConditions(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	conditions, err := st.SpotConditions(ctx, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conditions.DomSwellHeightM != nil || conditions.WaterTempDegC != nil {
+		t.Errorf("flagged values were kept: %+v", conditions)
+	}
+	if conditions.DominantWavePeriodSec == nil || conditions.AirTempDegC == nil || conditions.WindSpeedMph == nil {
+		t.Errorf("good or stale values were dropped: %+v", conditions)
+	}
+	want := qc.Flags{
+		"DomSwellHeightM": qc.Sentinel,
+		"WaterTempDegC":   qc.Spike,
+		"AirTempDegC":     qc.Stale,
+		"WindSpeedMph":    qc.Stale,
+	}
+	if len(conditions.QC) != len(want) {
+		t.Fatalf("flags: got %v, want %v", conditions.QC, want)
+	}
+	for field, flag := range want {
+		if conditions.QC[field] != flag {
+			t.Errorf("%s: got %q, want %q", field, conditions.QC[field], flag)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package dbLib
+
+import (
+	meteo "Go_surf_redesign/src/backend/api"
+	"Go_surf_redesign/src/backend/archive"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ReprocessReport counts what Reprocess read and wrote.
+type ReprocessReport struct {
+	Payloads       int
+	Skipped        int // payloads that are not observations or tide files, or failed to parse
+	BuoyRows       int
+	HistoryRows    int
+	WeatherRows    int
+	TidePrediction int
+	Conditions     int
+}
+
+// Reprocess parses the payloads archived between from and to again and
+// stores the result as ingestion would have:
+//
+//   - every buoy row goes to the history, filling gaps but leaving rows
+//     already stored untouched;
+//   - the newest buoy and weather observations replace the current ones,
+//     unless the stored observation is newer;
+//   - the newest copy of each tide file replaces the predictions of its
+//     station, leaving the other stations' untouched;
+//   - conditions are rebuilt.
+//
+// Payloads that fail to parse are reported and skipped.
+func (c *DataClient) Reprocess(ctx context.Context, arc archive.Archive, from, to time.Time) (ReprocessReport, error) {
+	var report ReprocessReport
+	buoys := make(map[int][]*meteo.BouyObservation)
+	weather := make(map[string]*meteo.WeatherObservation)
+	tideFiles := make(map[string]meteo.TideChart)
+
+	err := arc.Scan(ctx, from, to, func(p archive.Payload) error {
+		report.Payloads++
+		// Scan is oldest first, so later payloads replace earlier ones.
+		switch p.Source {
+		case archive.SourceNDBC:
+			buoyId, ok := meteo.BuoyIDFromURL(p.Key)
+			if !ok {
+				report.Skipped++
+				return nil
+			}
+			rows, err := meteo.ParseBuoyFile(p.Body, buoyId)
+			if err != nil {
+				c.log(ctx).Warn("could not parse archived payload", "key", p.Key, "fetched_at", p.FetchedAt, "error", err)
+				report.Skipped++
+				return nil
+			}
+			id, _ := strconv.Atoi(buoyId)
+			buoys[id] = rows
+		case archive.SourceNWS:
+			station, ok := meteo.StationFromObservationURL(p.Key)
+			if !ok {
+				report.Skipped++
+				return nil
+			}
+			obs, err := meteo.ParseWeatherObservation(p.Body)
+			if err != nil {
+				c.log(ctx).Warn("could not parse archived payload", "key", p.Key, "fetched_at", p.FetchedAt, "error", err)
+				report.Skipped++
+				return nil
+			}
+			weather[station] = obs
+		case archive.SourceTides:
+			chart, err := meteo.ParseTideChart(p.Body)
+			if err != nil {
+				c.log(ctx).Warn("could not parse archived tide file", "key", p.Key, "fetched_at", p.FetchedAt, "error", err)
+				report.Skipped++
+				return nil
+			}
+			tideFiles[p.Key] = chart
+		default:
+			report.Skipped++
+		}
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("could not read archive: %w", err)
+	}
+
+	if err := c.reprocessBuoys(ctx, buoys, &report); err != nil {
+		return report, err
+	}
+	if err := c.reprocessWeather(ctx, weather, &report); err != nil {
+		return report, err
+	}
+
+	if len(tideFiles) > 0 {
+		names := slices.Sorted(maps.Keys(tideFiles))
+		charts := make([]meteo.TideChart, len(names))
+		for i, name := range names {
+			charts[i] = tideFiles[name]
+		}
+		predictions := tidePredictions(charts)
+		if err := c.store.ReplaceStationTides(ctx, predictions); err != nil {
+			return report, fmt.Errorf("could not replace tide data: %w", err)
+		}
+		c.publish(SourceTides)
+		report.TidePrediction = len(predictions)
+	}
+
+	conditions, err := c.UpdateCurrentSurfConditions(ctx)
+	report.Conditions = conditions.RowsWritten
+	if err != nil {
+		return report, err
+	}
+	c.publish(SourceConditions, conditions.Changed...)
+	return report, nil
+}
+
+// reprocessBuoys adds the archived rows to the history and makes each
+// buoy's newest row current unless a newer one is stored.
+func (c *DataClient) reprocessBuoys(ctx context.Context, buoys map[int][]*meteo.BouyObservation, report *ReprocessReport) error {
+	if len(buoys) == 0 {
+		return nil
+	}
+	latest, err := c.store.LatestBuoyObservations(ctx)
+	if err != nil {
+		return fmt.Errorf("could not read stored buoy data: %w", err)
+	}
+	stored := make(map[int]store.BuoyObservation, len(latest))
+	for _, obs := range latest {
+		stored[obs.BuoyID] = obs
+	}
+
+	now := time.Now()
+	var current []store.BuoyObservation
+	for id, rows := range buoys {
+		var history []store.BuoyObservation
+		var prev *store.BuoyObservation
+		for i := len(rows) - 1; i >= 0; i-- {
+			row := buoyObservation(id, rows[i])
+			c.checkBuoyObservation(&row, prev, rows[i].RecordedAt)
+			history = append(history, row)
+			prev = &history[len(history)-1]
+		}
+		n, err := c.store.AddBuoyHistory(ctx, history)
+		report.HistoryRows += n
+		if err != nil {
+			return fmt.Errorf("could not add history of buoy %d: %w", id, err)
+		}
+
+		if len(rows) == 0 {
+			continue
+		}
+		newest := buoyObservation(id, rows[0])
+		if s, ok := stored[id]; ok && s.RecordedAt.After(newest.RecordedAt) {
+			continue
+		}
+		var before *store.BuoyObservation
+		if len(history) > 1 {
+			before = &history[len(history)-2]
+		}
+		c.checkBuoyObservation(&newest, before, now)
+		current = append(current, newest)
+	}
+	if len(current) == 0 {
+		return nil
+	}
+	// SaveBuoyObservations upserts, so buoys missing from current keep
+	// their stored row.
+	if err := c.store.SaveBuoyObservations(ctx, current); err != nil {
+		return fmt.Errorf("could not save buoy observations: %w", err)
+	}
+	report.BuoyRows = len(current)
+	return nil
+}
+
+// reprocessWeather makes each station's newest archived observation
+// current for the cities using that station, unless a newer one is stored.
+func (c *DataClient) reprocessWeather(ctx context.Context, byStation map[string]*meteo.WeatherObservation, report *ReprocessReport) error {
+	if len(byStation) == 0 {
+		return nil
+	}
+	weatherStations, err := c.GetWeatherStations(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get weather stations: %w", err)
+	}
+	latest, err := c.store.LatestWeatherObservations(ctx)
+	if err != nil {
+		return fmt.Errorf("could not read stored weather data: %w", err)
+	}
+	stored := make(map[int]store.WeatherObservation, len(latest))
+	for _, obs := range latest {
+		stored[obs.CityID] = obs
+	}
+
+	now := time.Now()
+	var rows []store.WeatherObservation
+	for _, ws := range weatherStations {
+		obs, ok := byStation[ws.station]
+		if !ok {
+			continue
+		}
+		row := weatherObservation(ws.cityId, ws.station, obs)
+		var prev *store.WeatherObservation
+		if s, ok := stored[ws.cityId]; ok {
+			if s.RecordedAt.After(row.RecordedAt) {
+				continue
+			}
+			prev = &s
+		}
+		c.checkWeatherObservation(&row, prev, now)
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := c.store.SaveWeatherObservations(ctx, rows); err != nil {
+		return fmt.Errorf("could not save weather observations: %w", err)
+	}
+	report.WeatherRows = len(rows)
+	return nil
+}
@@ -0,0 +1,77 @@
+package dbLib
+
+import (
+	"Go_surf_redesign/src/backend/archive"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/backend/store/memory"
+	"context"
+	"testing"
+	"time"
+)
+
+// Reprocessing the payloads archived by one ingestion cycle rebuilds the
+// current tables of a store that never saw the upstream sources.
+func TestReprocessArchive(t *testing.T) {
+	ctx := context.Background()
+	arc := archive.NewDir(t.TempDir())
+	start := time.Now().Add(-time.Minute)
+
+	dc, providers, _, _ := newTestClient(t, memory.New(), arc)
+	if err := dc.LoadStaticData(ctx, providers); err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range []string{SourceBuoys, SourceWeather} {
+		if err := dc.RunSource(ctx, providers, source); err != nil {
+			t.Fatalf("%s: %v", source, err)
+		}
+	}
+
+	// The fresh store only gets the static data before reprocessing.
+	st := memory.New()
+	fresh, freshProviders, _, _ := newTestClient(t, st, nil)
+	if err := fresh.LoadStaticData(ctx, freshProviders); err != nil {
+		t.Fatal(err)
+	}
+	// A station without archived tide files keeps its predictions.
+	other := store.TidePrediction{StationName: "Elsewhere", Date: "2001-02-03", Time: "04:05", TidalState: "H"}
+	if err := st.ReplaceStationTides(ctx, []store.TidePrediction{other}); err != nil {
+		t.Fatal(err)
+	}
+	report, err := fresh.Reprocess(ctx, arc, start, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Payloads == report.Skipped {
+		t.Errorf("report: %+v", report)
+	}
+
+	buoys, err := st.Buoys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buoyObs, err := st.LatestBuoyObservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buoyObs) != len(buoys) || report.HistoryRows == 0 {
+		t.Errorf("got %d buoy observations for %d buoys and %d history rows", len(buoyObs), len(buoys), report.HistoryRows)
+	}
+	weather, err := st.LatestWeatherObservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weather) == 0 {
+		t.Fatal("no weather observations")
+	}
+	for _, w := range weather {
+		if w.RecordedAt.IsZero() || w.Station != "KTST" {
+			t.Errorf("city %d: recorded at %v by %q", w.CityID, w.RecordedAt, w.Station)
+		}
+	}
+	if report.Conditions == 0 || report.TidePrediction == 0 {
+		t.Errorf("report: %+v", report)
+	}
+	if tides, err := st.TidesOn(ctx, other.Date); err != nil || len(tides) != 1 || tides[0] != other {
+		t.Errorf("other station's tides: %v, %+v", err, tides)
+	}
+}
@@ -0,0 +1,269 @@
+package dbLib
+
+import (
+	meteo "Go_surf_redesign/src/backend/api"
+	"Go_surf_redesign/src/backend/data"
+	"Go_surf_redesign/src/backend/events"
+	"Go_surf_redesign/src/backend/provider"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Static data is reloaded in two phases. Every file is read and parsed
+// (and city weather stations resolved over the network) first; only then
+// is the whole reload handed to the store, which upserts the rows by id
+// and deletes the rows that are no longer listed in one atomic step.
+// Readers see the old tables until it completes.
+
+// LoadStaticData reloads cities, buoys, surf spots and tides together, so
+// the tables always agree with each other.
+func (c *DataClient) LoadStaticData(ctx context.Context, providers *provider.Set) error {
+	buoys, err := c.readBuoysCSV()
+	if err != nil {
+		return fmt.Errorf("buoys: %w", err)
+	}
+	cities, err := c.readCitiesCSV()
+	if err != nil {
+		return fmt.Errorf("cities: %w", err)
+	}
+	c.resolveCityStations(ctx, providers.Weather, cities)
+
+	spots, err := c.readSurfSpotsCSV()
+	if err != nil {
+		return fmt.Errorf("surf spots: %w", err)
+	}
+	charts, err := providers.Tides.GetTideCharts(ctx)
+	if err != nil {
+		return fmt.Errorf("tides: %w", err)
+	}
+
+	return c.replaceStatic(ctx, store.StaticData{
+		Buoys:  buoys,
+		Cities: cities,
+		Spots:  spots,
+		Tides:  tidePredictions(charts),
+	})
+}
+
+// replaceStatic hands data to the store and announces what it replaced.
+func (c *DataClient) replaceStatic(ctx context.Context, data store.StaticData) error {
+	if err := c.store.ReplaceStatic(ctx, data); err != nil {
+		return err
+	}
+	if data.Buoys != nil || data.Cities != nil || data.Spots != nil {
+		c.publish(events.SourceStatic)
+	}
+	if data.Tides != nil {
+		c.publish(SourceTides)
+	}
+	return nil
+}
+
+func (c *DataClient) UpdateStaticBuoyTable(ctx context.Context) error {
+	buoys, err := c.readBuoysCSV()
+	if err != nil {
+		return err
+	}
+	return c.replaceStatic(ctx, store.StaticData{Buoys: buoys})
+}
+
+func (c *DataClient) UpdateStaticCitiesTable(ctx context.Context, weather provider.WeatherObservationProvider) error {
+	cities, err := c.readCitiesCSV()
+	if err != nil {
+		return err
+	}
+	c.resolveCityStations(ctx, weather, cities)
+	return c.replaceStatic(ctx, store.StaticData{Cities: cities})
+}
+
+func (c *DataClient) UpdateStaticSurfSpotTable(ctx context.Context) error {
+	spots, err := c.readSurfSpotsCSV()
+	if err != nil {
+		return err
+	}
+	return c.replaceStatic(ctx, store.StaticData{Spots: spots})
+}
+
+// UpdateStaticTideData replaces the stored tides with the predictions from
+// the tide provider.
+func (c *DataClient) UpdateStaticTideData(ctx context.Context, tides provider.TideProvider) (RunReport, error) {
+	var report RunReport
+
+	charts, err := tides.GetTideCharts(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	predictions := tidePredictions(charts)
+	if err := c.store.ReplaceStatic(ctx, store.StaticData{Tides: predictions}); err != nil {
+		return report, err
+	}
+	report.RowsWritten = len(predictions)
+	return report, nil
+}
+
+// tidePredictions flattens tide charts for the store. The result is never
+// nil, so an empty set of charts clears the stored tides.
+func tidePredictions(charts []meteo.TideChart) []store.TidePrediction {
+	predictions := []store.TidePrediction{}
+	for _, chart := range charts {
+		for _, entry := range chart.TideData {
+			predictions = append(predictions, store.TidePrediction{
+				StationName: chart.StationName,
+				CountyName:  chart.CountyName,
+				StateCode:   chart.State,
+				Date:        entry.ISODate(),
+				Time:        entry.Clock(),
+				WaterLevel:  entry.Heightft,
+				TidalState:  entry.Highlow,
+				TideRegion:  chart.TideRegion,
+			})
+		}
+	}
+	return predictions
+}
+
+// readCSV returns every record of a static csv file except the header.
+func (c *DataClient) readCSV(name string) ([][]string, error) {
+	file, err := os.Open(data.FilePathBuilder(c.cfg.Data.Dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", name, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	var records [][]string
+	linenumber := 0
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("could not read %s: %w", name, err)
+		}
+		linenumber++
+		if linenumber == 1 {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// fieldParser converts csv fields, keeping the first error with its
+// line and column so a bad file is reported precisely.
+type fieldParser struct {
+	file string
+	line int
+	err  error
+}
+
+func (p *fieldParser) int(record []string, i int, name string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(record[i]))
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s line %d: invalid %s: %w", p.file, p.line, name, err)
+	}
+	return v
+}
+
+func (p *fieldParser) float(record []string, i int, name string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s line %d: invalid %s: %w", p.file, p.line, name, err)
+	}
+	return v
+}
+
+func (c *DataClient) readBuoysCSV() ([]store.Buoy, error) {
+	records, err := c.readCSV(dbBuoysList)
+	if err != nil {
+		return nil, err
+	}
+
+	buoys := make([]store.Buoy, 0, len(records))
+	for i, record := range records {
+		p := fieldParser{file: dbBuoysList, line: i + 2}
+		if len(record) < 4 {
+			return nil, fmt.Errorf("%s line %d: expected 4 columns, got %d", p.file, p.line, len(record))
+		}
+		b := store.Buoy{
+			ID:        p.int(record, 0, "id"),
+			Name:      record[1],
+			Latitude:  p.float(record, 2, "latitude"),
+			Longitude: p.float(record, 3, "longitude"),
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+		buoys = append(buoys, b)
+	}
+	return buoys, nil
+}
+
+func (c *DataClient) readCitiesCSV() ([]store.City, error) {
+	records, err := c.readCSV(dbCitiesList)
+	if err != nil {
+		return nil, err
+	}
+
+	cities := make([]store.City, 0, len(records))
+	for i, record := range records {
+		p := fieldParser{file: dbCitiesList, line: i + 2}
+		if len(record) < 7 {
+			return nil, fmt.Errorf("%s line %d: expected 7 columns, got %d", p.file, p.line, len(record))
+		}
+		city := store.City{
+			ID:        p.int(record, 0, "id"),
+			Name:      record[1],
+			Latitude:  p.float(record, 2, "latitude"),
+			Longitude: p.float(record, 3, "longitude"),
+			Country:   record[4],
+			State:     record[5],
+			County:    record[6],
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+		cities = append(cities, city)
+	}
+	return cities, nil
+}
+
+func (c *DataClient) readSurfSpotsCSV() ([]store.Spot, error) {
+	records, err := c.readCSV(dbSurfSpotList)
+	if err != nil {
+		return nil, err
+	}
+
+	spots := make([]store.Spot, 0, len(records))
+	for i, record := range records {
+		p := fieldParser{file: dbSurfSpotList, line: i + 2}
+		if len(record) < 8 {
+			return nil, fmt.Errorf("%s line %d: expected 8 columns, got %d", p.file, p.line, len(record))
+		}
+		spot := store.Spot{
+			ID:          p.int(record, 0, "id"),
+			Name:        record[1],
+			Latitude:    p.float(record, 2, "latitude"),
+			Longitude:   p.float(record, 3, "longitude"),
+			CityID:      p.int(record, 4, "city_id"),
+			BreakType:   record[5],
+			Orientation: p.float(record, 6, "orientation"),
+			TideRegion:  p.int(record, 7, "tide_region"),
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+		spots = append(spots, spot)
+	}
+	return spots, nil
+}
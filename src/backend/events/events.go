@@ -0,0 +1,90 @@
+// Package events tells the parts of a process that serve data when
+// ingestion has rewritten it.
+package events
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Sources of events. The ingestion sources share these names.
+const (
+	SourceBuoys      = "buoys"
+	SourceWeather    = "weather"
+	SourceConditions = "conditions"
+	SourceTides      = "tides"
+	SourceForecasts  = "forecasts"
+	// SourceStatic covers the cities, buoys and surf spots.
+	SourceStatic = "static"
+)
+
+// Sources lists every source.
+var Sources = []string{SourceBuoys, SourceWeather, SourceConditions, SourceTides, SourceForecasts, SourceStatic}
+
+// Event reports that the data of one ingestion source changed.
+type Event struct {
+	Source string
+	// IDs lists the entities that changed, such as the surf spots whose
+	// conditions were rebuilt. Empty means any of the source's data may
+	// have changed.
+	IDs []int
+	At  time.Time
+	// Origin is empty for events raised in this process and otherwise
+	// names where the event came from, so bridges do not echo it back.
+	Origin string
+}
+
+// Affects reports whether e may have changed the entity id.
+func (e Event) Affects(id int) bool {
+	return len(e.IDs) == 0 || slices.Contains(e.IDs, id)
+}
+
+// Bus delivers published events to every subscriber. The zero value is
+// ready to use, and a nil *Bus drops everything published to it.
+type Bus struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]func(Event)
+}
+
+// NewBus returns an empty bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers fn for every later event and returns a function
+// that removes it. fn runs on the publisher's goroutine, so it must
+// return quickly.
+func (b *Bus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[int]func(Event))
+	}
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Publish calls every subscriber with e.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	subs := make([]func(Event), 0, len(b.subs))
+	for _, fn := range b.subs {
+		subs = append(subs, fn)
+	}
+	b.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(e)
+	}
+}
@@ -0,0 +1,40 @@
+// Package logging builds the process logger from the log config and
+// carries request and job scoped loggers through contexts.
+package logging
+
+import (
+	"Go_surf_redesign/src/config"
+	"context"
+	"io"
+	"log/slog"
+)
+
+// New returns a logger writing to w at the configured level, as JSON
+// lines for log collectors or as key=value text for terminals.
+func New(cfg config.LogConfig, w io.Writer) *slog.Logger {
+	var level slog.Level
+	// The config is validated, so the level always parses.
+	_ = level.UnmarshalText([]byte(cfg.Level))
+
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.Format == config.LogJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or fallback when it
+// carries none.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
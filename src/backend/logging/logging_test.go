@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"Go_surf_redesign/src/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestNewJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(config.LogConfig{Level: "warn", Format: config.LogJSON}, &buf)
+	logger.Info("dropped")
+	logger.Warn("kept", "run", 7)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("not one JSON record: %q", buf.String())
+	}
+	if record["msg"] != "kept" || record["run"] != float64(7) {
+		t.Errorf("got %v", record)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := slog.Default()
+	if got := FromContext(context.Background(), fallback); got != fallback {
+		t.Error("empty context did not return the fallback")
+	}
+	logger := fallback.With("request_id", "abc")
+	if got := FromContext(NewContext(context.Background(), logger), fallback); got != logger {
+		t.Error("context logger was not returned")
+	}
+}
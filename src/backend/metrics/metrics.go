@@ -0,0 +1,155 @@
+// Package metrics holds the Prometheus metrics of the API server and the
+// ingestion pipeline, served on /metrics.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gosurf"
+
+// Registry holds every metric below plus the Go runtime and process
+// collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "API requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "API request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of Postgres store operations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	UpstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Upstream fetches by host and result (ok, not_modified or error).",
+	}, []string{"host", "result"})
+
+	UpstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Upstream fetch latency by host.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"host"})
+
+	IngestionRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingestion_runs_total",
+		Help:      "Ingestion runs by source and status.",
+	}, []string{"source", "status"})
+
+	IngestionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ingestion_run_duration_seconds",
+		Help:      "Ingestion run duration by source.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 120, 300, 600},
+	}, []string{"source"})
+
+	IngestionRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingestion_rows_written_total",
+		Help:      "Rows written by ingestion runs, by source.",
+	}, []string{"source"})
+)
+
+// collectTimeout bounds the store reads of collectors that run at scrape
+// time.
+const collectTimeout = 5 * time.Second
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		DBQueryDuration,
+		UpstreamRequests,
+		UpstreamDuration,
+		IngestionRuns,
+		IngestionDuration,
+		IngestionRows,
+	)
+}
+
+// Handler serves the registry and the extra collectors in the Prometheus
+// text format. A collector that fails is logged and left out of the
+// response; the other metrics are still served.
+func Handler(extra ...prometheus.Collector) http.Handler {
+	gatherers := prometheus.Gatherers{Registry}
+	if len(extra) > 0 {
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(extra...)
+		gatherers = append(gatherers, reg)
+	}
+	return promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+		ErrorLog:      slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// BuoyObservationAge returns a collector reporting the age of each buoy's
+// latest stored observation, read with latest at every scrape so every
+// process serving metrics reports it, not only the one ingesting.
+func BuoyObservationAge(latest func(ctx context.Context) (map[int]time.Time, error)) prometheus.Collector {
+	return &ageCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "buoy_observation_age_seconds"),
+			"Age of the latest stored observation of each buoy.",
+			[]string{"buoy"}, nil,
+		),
+		seen: latest,
+	}
+}
+
+// ObserveQuery records the duration of a store operation that started at
+// start. It is meant to be deferred: defer metrics.ObserveQuery("op", time.Now()).
+func ObserveQuery(operation string, start time.Time) {
+	DBQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// ageCollector reports how long ago each labelled thing was last seen,
+// computed when the metrics are scraped.
+type ageCollector struct {
+	desc *prometheus.Desc
+	seen func(ctx context.Context) (map[int]time.Time, error)
+}
+
+func (a *ageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- a.desc
+}
+
+func (a *ageCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	seen, err := a.seen(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(a.desc, err)
+		return
+	}
+	now := time.Now()
+	for id, at := range seen {
+		ch <- prometheus.MustNewConstMetric(a.desc, prometheus.GaugeValue, now.Sub(at).Seconds(), strconv.Itoa(id))
+	}
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"Go_surf_redesign/src/backend/qc"
+	"time"
+)
 
 type CurrentSurfSpotConditions struct {
 	ID                    int
@@ -16,6 +19,64 @@ type CurrentSurfSpotConditions struct {
 	CloudCoverage         *string  // from city weather data
 	DominantWavePeriodSec *float64 // from buoy data
 	NearestBuoy           int
+	QC                    qc.Flags // values flagged by quality control, keyed by field
+	Provenance            Provenance
+	// Rebuild numbers the rebuild that wrote the row. Every rebuild of the
+	// conditions gets a higher number than the last.
+	Rebuild int64 `json:"-"`
+}
+
+// Freshness says whether a source's latest observation is recent enough
+// to trust.
+type Freshness string
+
+const (
+	Fresh   Freshness = "fresh"
+	Stale   Freshness = "stale"
+	Missing Freshness = "missing"
+)
+
+// Source identifies the buoy or weather station part of the conditions
+// came from. DistanceKm is measured from the surf spot.
+type Source struct {
+	ID         string
+	DistanceKm *float64
+	ObservedAt *time.Time
+	AgeMinutes *int
+	Status     Freshness
+}
+
+// Provenance records where and when a spot's conditions were observed.
+// Status is the worst status of the two sources.
+type Provenance struct {
+	Buoy    Source
+	Weather Source
+	Status  Freshness
+}
+
+// UpdateFreshness sets the age and status of each source as of now. A
+// source observed more than maxAge ago is stale; one without an
+// observation is missing.
+func (p *Provenance) UpdateFreshness(now time.Time, maxAge time.Duration) {
+	p.Status = Fresh
+	for _, src := range []*Source{&p.Buoy, &p.Weather} {
+		src.AgeMinutes = nil
+		switch {
+		case src.ObservedAt == nil:
+			src.Status = Missing
+		case now.Sub(*src.ObservedAt) > maxAge:
+			src.Status = Stale
+		default:
+			src.Status = Fresh
+		}
+		if src.ObservedAt != nil {
+			age := int(now.Sub(*src.ObservedAt).Minutes())
+			src.AgeMinutes = &age
+		}
+		if src.Status == Missing || (src.Status == Stale && p.Status == Fresh) {
+			p.Status = src.Status
+		}
+	}
 }
 
 type Buoy struct {
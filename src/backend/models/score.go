@@ -0,0 +1,76 @@
+package models
+
+import (
+	"math"
+	"strconv"
+)
+
+const feetPerMeter = 3.28084
+
+// Score rates the conditions from 0 to 10 for a spot whose beach faces
+// orientation (degrees true). Up to 4 points come from swell height, cut
+// down the further the swell arrives from the direction the beach faces,
+// 3 from the dominant period and 3 from the wind, which costs nothing when
+// light or offshore and everything when strong and onshore. Score is nil
+// when the swell height is unknown.
+func (c CurrentSurfSpotConditions) Score(orientation float64) *float64 {
+	if c.DomSwellHeightM == nil {
+		return nil
+	}
+
+	// Five feet or more is a full score.
+	size := math.Min(*c.DomSwellHeightM*feetPerMeter/5, 1)
+	if c.DomSwellDir != nil {
+		size *= math.Max(math.Cos(angleBetween(*c.DomSwellDir, orientation)), 0)
+	}
+
+	// Periods run from 6s wind swell to 16s groundswell.
+	var period float64
+	if c.DominantWavePeriodSec != nil {
+		period = math.Min(math.Max((*c.DominantWavePeriodSec-6)/10, 0), 1)
+	}
+
+	wind := 0.5 // unknown wind counts as middling
+	if mph := c.WindSpeed(); mph != nil {
+		offshore := 0.0 // a calm or directionless wind counts as cross-shore
+		if dir := parseNumber(c.WindDirection); dir != nil {
+			// Wind directions are where the wind comes from, so offshore
+			// wind blows from behind the beach.
+			offshore = math.Cos(angleBetween(*dir, orientation+180))
+		}
+		// Wind up to 5mph does not matter; from 20mph it counts fully.
+		strength := math.Min(math.Max((*mph-5)/15, 0), 1)
+		wind = 1 - strength*(1-offshore)/2
+	}
+
+	score := math.Round((4*size+3*period+3*wind)*10) / 10
+	return &score
+}
+
+// WindSpeed returns the wind speed in mph, or nil when it is unknown.
+func (c CurrentSurfSpotConditions) WindSpeed() *float64 {
+	return parseNumber(c.WindSpeedMph)
+}
+
+// angleBetween returns the angle in radians between two compass bearings
+// given in degrees, from 0 to π.
+func angleBetween(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360)
+	if d > 180 {
+		d = 360 - d
+	}
+	return d * math.Pi / 180
+}
+
+// parseNumber returns the number in s, or nil when s is nil or not a
+// number, such as the "NA" stored for missing wind speeds.
+func parseNumber(s *string) *float64 {
+	if s == nil {
+		return nil
+	}
+	v, err := strconv.ParseFloat(*s, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,52 @@
+package models
+
+import "testing"
+
+func TestScore(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+	str := func(s string) *string { return &s }
+	// A head-high south swell on a south-facing beach.
+	base := CurrentSurfSpotConditions{
+		DomSwellHeightM:       ptr(1.6),
+		DomSwellDir:           ptr(180),
+		DominantWavePeriodSec: ptr(16),
+	}
+	with := func(mph, dir string) CurrentSurfSpotConditions {
+		c := base
+		c.WindSpeedMph, c.WindDirection = str(mph), str(dir)
+		return c
+	}
+
+	for _, tc := range []struct {
+		name       string
+		conditions CurrentSurfSpotConditions
+		want       float64
+	}{
+		{"calm", with("3", "180"), 10},
+		{"strong offshore", with("25", "0"), 10},
+		{"strong onshore", with("25", "180"), 7},
+		{"strong cross-shore", with("25", "90"), 8.5},
+		{"unknown wind", with("NA", "180"), 8.5},
+	} {
+		got := tc.conditions.Score(180)
+		if got == nil || *got != tc.want {
+			t.Errorf("%s: score %v, want %v", tc.name, deref(got), tc.want)
+		}
+	}
+
+	blocked := with("3", "180")
+	blocked.DomSwellDir = ptr(0) // swell from behind the beach
+	if got := blocked.Score(180); got == nil || *got != 6 {
+		t.Errorf("blocked swell: score %v, want 6", deref(got))
+	}
+	if got := (CurrentSurfSpotConditions{}).Score(180); got != nil {
+		t.Errorf("no swell height: score %v, want nil", *got)
+	}
+}
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,38 @@
+package provider
+
+import (
+	meteo "Go_surf_redesign/src/backend/api"
+	"Go_surf_redesign/src/backend/archive"
+	"Go_surf_redesign/src/backend/data"
+	"context"
+)
+
+// NOAA is the name of the providers backed by NDBC buoys, api.weather.gov
+// and the NOAA tide prediction files shipped in the data directory.
+const NOAA = "noaa"
+
+func init() {
+	RegisterWaves(NOAA, func(env Env) (WaveObservationProvider, error) {
+		return env.Upstream.RTBouy, nil
+	})
+	RegisterWeather(NOAA, func(env Env) (WeatherObservationProvider, error) {
+		return env.Upstream.RTWeather, nil
+	})
+	RegisterForecast(NOAA, func(env Env) (ForecastProvider, error) {
+		return env.Upstream.Forecast, nil
+	})
+	RegisterTides(NOAA, func(env Env) (TideProvider, error) {
+		return noaaTideFiles{dir: data.FilePathBuilder(env.Config.Data.Dir, "tides"), archive: env.Archive}, nil
+	})
+}
+
+// noaaTideFiles reads the annual NOAA tide prediction xml files in dir,
+// archiving each file it reads when archive is set.
+type noaaTideFiles struct {
+	dir     string
+	archive archive.Archive
+}
+
+func (t noaaTideFiles) GetTideCharts(ctx context.Context) ([]meteo.TideChart, error) {
+	return meteo.ReadTideCharts(ctx, t.dir, t.archive)
+}
@@ -0,0 +1,151 @@
+// Package provider defines the interfaces ingestion uses to fetch
+// observations, forecasts and tides, and a registry of named
+// implementations chosen through the [providers] config section.
+package provider
+
+import (
+	meteo "Go_surf_redesign/src/backend/api"
+	"Go_surf_redesign/src/backend/archive"
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/config"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// WaveObservationProvider supplies buoy (wave and sea surface) observations.
+type WaveObservationProvider interface {
+	// GetObservations returns the newest observation of each buoy, with
+	// per-buoy errors for the ones that could not be fetched.
+	GetObservations(ctx context.Context, buoyIds []string) (map[string]*meteo.BouyObservation, map[string]error)
+	// GetHistory returns every observation the source still holds for a
+	// buoy, newest first.
+	GetHistory(ctx context.Context, buoyId string) ([]*meteo.BouyObservation, error)
+}
+
+// WeatherObservationProvider supplies current weather at observation
+// stations.
+type WeatherObservationProvider interface {
+	GetObservations(ctx context.Context, stationIds []string) (map[string]*meteo.WeatherObservation, map[string]error)
+	// NearestStation returns the station that best represents a coordinate.
+	NearestStation(ctx context.Context, lat, lon float64) (string, error)
+}
+
+// ForecastProvider supplies hourly weather forecasts for a coordinate.
+type ForecastProvider interface {
+	GetHourlyForecast(ctx context.Context, lat, lon float64) (*models.HourlyWeatherForecast, error)
+}
+
+// TideProvider supplies tide predictions grouped by station.
+type TideProvider interface {
+	GetTideCharts(ctx context.Context) ([]meteo.TideChart, error)
+}
+
+// Set holds one provider of each kind.
+type Set struct {
+	Waves    WaveObservationProvider
+	Weather  WeatherObservationProvider
+	Forecast ForecastProvider
+	Tides    TideProvider
+}
+
+// Env is passed to provider factories. Upstream is shared by every
+// provider in a Set so they share its rate limits and cache validators.
+// Archive, when not nil, should receive every raw payload a provider
+// reads; Upstream already archives what it fetches.
+type Env struct {
+	Config   *config.Config
+	Upstream *meteo.Client
+	Archive  archive.Archive
+}
+
+// Factory builds a provider.
+type Factory[T any] func(env Env) (T, error)
+
+var registry = struct {
+	sync.Mutex
+	waves    map[string]Factory[WaveObservationProvider]
+	weather  map[string]Factory[WeatherObservationProvider]
+	forecast map[string]Factory[ForecastProvider]
+	tides    map[string]Factory[TideProvider]
+}{
+	waves:    make(map[string]Factory[WaveObservationProvider]),
+	weather:  make(map[string]Factory[WeatherObservationProvider]),
+	forecast: make(map[string]Factory[ForecastProvider]),
+	tides:    make(map[string]Factory[TideProvider]),
+}
+
+// RegisterWaves makes a wave observation provider available under name.
+func RegisterWaves(name string, f Factory[WaveObservationProvider]) {
+	register(registry.waves, name, f)
+}
+
+// RegisterWeather makes a weather observation provider available under name.
+func RegisterWeather(name string, f Factory[WeatherObservationProvider]) {
+	register(registry.weather, name, f)
+}
+
+// RegisterForecast makes a forecast provider available under name.
+func RegisterForecast(name string, f Factory[ForecastProvider]) {
+	register(registry.forecast, name, f)
+}
+
+// RegisterTides makes a tide provider available under name.
+func RegisterTides(name string, f Factory[TideProvider]) {
+	register(registry.tides, name, f)
+}
+
+func register[T any](m map[string]Factory[T], name string, f Factory[T]) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := m[name]; ok {
+		panic(fmt.Sprintf("provider: %q registered twice", name))
+	}
+	m[name] = f
+}
+
+// New builds the providers named in cfg.Providers. Raw payloads are
+// archived in arc unless it is nil.
+func New(cfg *config.Config, arc archive.Archive) (*Set, error) {
+	var opts []meteo.Option
+	if arc != nil {
+		opts = append(opts, meteo.WithArchive(arc))
+	}
+	env := Env{Config: cfg, Upstream: meteo.NewClient(cfg.Upstream, opts...), Archive: arc}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	var set Set
+	var err error
+	if set.Waves, err = build(registry.waves, "waves", cfg.Providers.Waves, env); err != nil {
+		return nil, err
+	}
+	if set.Weather, err = build(registry.weather, "weather", cfg.Providers.Weather, env); err != nil {
+		return nil, err
+	}
+	if set.Forecast, err = build(registry.forecast, "forecast", cfg.Providers.Forecast, env); err != nil {
+		return nil, err
+	}
+	if set.Tides, err = build(registry.tides, "tides", cfg.Providers.Tides, env); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func build[T any](m map[string]Factory[T], kind, name string, env Env) (T, error) {
+	f, ok := m[name]
+	if !ok {
+		var zero T
+		names := slices.Sorted(maps.Keys(m))
+		return zero, fmt.Errorf("unknown %s provider %q (available: %s)", kind, name, strings.Join(names, ", "))
+	}
+	p, err := f(env)
+	if err != nil {
+		return p, fmt.Errorf("could not create %s provider %q: %w", kind, name, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"Go_surf_redesign/src/config"
+	"strings"
+	"testing"
+)
+
+func TestNewSelectsConfiguredProviders(t *testing.T) {
+	cfg := config.Default()
+	set, err := New(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Waves == nil || set.Weather == nil || set.Forecast == nil || set.Tides == nil {
+		t.Fatalf("incomplete provider set: %+v", set)
+	}
+
+	cfg.Providers.Tides = "bom"
+	_, err = New(cfg, nil)
+	if err == nil || !strings.Contains(err.Error(), `unknown tides provider "bom"`) {
+		t.Fatalf("want unknown provider error, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Package qc checks observations before they are stored. Each check looks
+// at one value and returns a Flag describing what is wrong with it; values
+// that pass are not flagged.
+package qc
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Flag describes why a value failed quality control.
+type Flag string
+
+const (
+	// Sentinel marks a placeholder the source sends instead of a missing
+	// value, such as NDBC's 99.0 or 999.
+	Sentinel Flag = "sentinel"
+	// OutOfRange marks a value outside what the instrument can report.
+	OutOfRange Flag = "out_of_range"
+	// Spike marks a value that changed faster than physically plausible
+	// since the previous observation.
+	Spike Flag = "spike"
+	// Stale marks a value taken longer ago than the configured maximum age.
+	Stale Flag = "stale"
+)
+
+// Excludes reports whether a value with flag f should be dropped rather
+// than shown. Stale values are still the latest reading, so they are kept
+// and only marked.
+func (f Flag) Excludes() bool {
+	return f != "" && f != Stale
+}
+
+// Flags maps a field name to the flag it failed with. Fields that passed
+// are absent.
+type Flags map[string]Flag
+
+// Set records flag for field, keeping an earlier flag that excludes the
+// value.
+func (f *Flags) Set(field string, flag Flag) {
+	if flag == "" {
+		return
+	}
+	if *f == nil {
+		*f = make(Flags)
+	}
+	if (*f)[field].Excludes() {
+		return
+	}
+	(*f)[field] = flag
+}
+
+// Excluded reports whether field failed a check that drops its value.
+func (f Flags) Excluded(field string) bool {
+	return f[field].Excludes()
+}
+
+// Value stores the flags as a JSON object.
+func (f Flags) Value() (driver.Value, error) {
+	if len(f) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads flags stored by Value.
+func (f *Flags) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*f = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into qc.Flags", src)
+	}
+	var flags Flags
+	if err := json.Unmarshal(b, &flags); err != nil {
+		return err
+	}
+	if len(flags) == 0 {
+		flags = nil
+	}
+	*f = flags
+	return nil
+}
+
+// Limit holds the checks for one variable.
+type Limit struct {
+	Min, Max float64
+	// Sentinels are placeholder values the source uses for missing data.
+	Sentinels []float64
+	// MaxChangePerHour is the largest plausible change between two
+	// observations an hour apart. Zero disables spike detection.
+	MaxChangePerHour float64
+}
+
+// spikeWindow is how far back a previous observation may be and still be
+// used for spike detection.
+const spikeWindow = 6 * time.Hour
+
+// Check returns the flag v fails with, or "" when it passes.
+func (l Limit) Check(v float64) Flag {
+	for _, s := range l.Sentinels {
+		if v == s {
+			return Sentinel
+		}
+	}
+	if math.IsNaN(v) || v < l.Min || v > l.Max {
+		return OutOfRange
+	}
+	return ""
+}
+
+// CheckChange flags v as a spike when it moved further from prev than the
+// limit allows over elapsed. Changes over less than an hour are held to
+// the hourly limit, so ten-minute readings may move a full hour's worth.
+func (l Limit) CheckChange(prev, v float64, elapsed time.Duration) Flag {
+	if l.MaxChangePerHour == 0 || elapsed <= 0 || elapsed > spikeWindow {
+		return ""
+	}
+	hours := math.Max(elapsed.Hours(), 1)
+	if math.Abs(v-prev) > l.MaxChangePerHour*hours {
+		return Spike
+	}
+	return ""
+}
+
+// Field is one value of an observation under check.
+type Field struct {
+	Name  string
+	Value *float64
+	// Prev is the same field of the previous observation, or nil when
+	// there is none or it was excluded.
+	Prev *float64
+}
+
+// Checker applies per-field limits to observations.
+type Checker struct {
+	Limits map[string]Limit
+	// MaxAge flags observations older than this as stale. Zero disables
+	// the staleness check.
+	MaxAge time.Duration
+}
+
+// Check runs the range and spike checks on fields of an observation taken
+// at recordedAt whose predecessor was taken at prevAt, and the staleness
+// check against now. Every field of a stale observation is flagged stale
+// unless it already failed another check. Fields without a limit are only
+// checked for staleness.
+func (c Checker) Check(fields []Field, recordedAt, prevAt, now time.Time) Flags {
+	var flags Flags
+	for _, field := range fields {
+		limit, ok := c.Limits[field.Name]
+		if field.Value == nil || !ok {
+			continue
+		}
+		flag := limit.Check(*field.Value)
+		if flag == "" && field.Prev != nil {
+			flag = limit.CheckChange(*field.Prev, *field.Value, recordedAt.Sub(prevAt))
+		}
+		flags.Set(field.Name, flag)
+	}
+	if c.MaxAge > 0 && now.Sub(recordedAt) > c.MaxAge {
+		for _, field := range fields {
+			if field.Value != nil {
+				flags.Set(field.Name, Stale)
+			}
+		}
+	}
+	return flags
+}
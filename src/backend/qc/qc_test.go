@@ -0,0 +1,101 @@
+package qc
+
+import (
+	"testing"
+	"time"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestChecker(t *testing.T) {
+	checker := Checker{
+		Limits: map[string]Limit{
+			"height": {Min: 0, Max: 25, Sentinels: []float64{99}, MaxChangePerHour: 3},
+			"temp":   {Min: -3, Max: 40, Sentinels: []float64{999}},
+		},
+		MaxAge: 3 * time.Hour,
+	}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		fields     []Field
+		recordedAt time.Time
+		want       Flags
+	}{
+		{
+			name:       "good values pass",
+			fields:     []Field{{Name: "height", Value: ptr(1.2), Prev: ptr(1.0)}, {Name: "temp", Value: ptr(18)}},
+			recordedAt: now,
+			want:       nil,
+		},
+		{
+			name:       "sentinel",
+			fields:     []Field{{Name: "height", Value: ptr(99)}, {Name: "temp", Value: ptr(999)}},
+			recordedAt: now,
+			want:       Flags{"height": Sentinel, "temp": Sentinel},
+		},
+		{
+			name:       "out of range",
+			fields:     []Field{{Name: "height", Value: ptr(-0.5)}},
+			recordedAt: now,
+			want:       Flags{"height": OutOfRange},
+		},
+		{
+			name:       "spike",
+			fields:     []Field{{Name: "height", Value: ptr(9), Prev: ptr(1)}},
+			recordedAt: now,
+			want:       Flags{"height": Spike},
+		},
+		{
+			name:       "stale values keep other flags",
+			fields:     []Field{{Name: "height", Value: ptr(99)}, {Name: "temp", Value: ptr(18)}, {Name: "missing"}},
+			recordedAt: now.Add(-4 * time.Hour),
+			want:       Flags{"height": Sentinel, "temp": Stale},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checker.Check(tt.fields, tt.recordedAt, tt.recordedAt.Add(-10*time.Minute), now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for field, flag := range tt.want {
+				if got[field] != flag {
+					t.Errorf("%s: got %q, want %q", field, got[field], flag)
+				}
+			}
+		})
+	}
+}
+
+// A slow change over several hours is not a spike, and a previous
+// observation outside the window is not compared.
+func TestCheckChange(t *testing.T) {
+	limit := Limit{Max: 25, MaxChangePerHour: 3}
+	if flag := limit.CheckChange(1, 6, 2*time.Hour); flag != "" {
+		t.Errorf("5 m over 2h: got %q", flag)
+	}
+	if flag := limit.CheckChange(1, 12, 12*time.Hour); flag != "" {
+		t.Errorf("outside the window: got %q", flag)
+	}
+}
+
+func TestFlagsRoundTrip(t *testing.T) {
+	v, err := Flags{"height": Spike}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Flags
+	if err := got.Scan([]byte(v.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if got["height"] != Spike {
+		t.Errorf("got %v", got)
+	}
+
+	v, _ = Flags(nil).Value()
+	if err := got.Scan(v); err != nil || got != nil {
+		t.Errorf("empty flags scanned as %v, %v", got, err)
+	}
+}
@@ -0,0 +1,269 @@
+// Package scheduler runs named jobs on intervals, honouring dependencies
+// between jobs, and reports the state of every job.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job is a unit of work run on an interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Jitter adds a random delay of up to Jitter to every interval so
+	// upstream services are not hit at the same second each cycle.
+	Jitter time.Duration
+	// Timeout cancels a run that takes longer than this. Zero means no limit.
+	Timeout time.Duration
+	// DependsOn lists jobs that must have succeeded recently, and must not
+	// be running, before this job starts. A dependency counts as recent
+	// when its last success is within twice its interval plus jitter.
+	DependsOn []string
+	Run       func(ctx context.Context) error
+}
+
+// Status is a snapshot of one job.
+type Status struct {
+	Name         string        `json:"name"`
+	Running      bool          `json:"running"`
+	LastRun      time.Time     `json:"lastRun"`
+	LastSuccess  time.Time     `json:"lastSuccess"`
+	LastDuration time.Duration `json:"lastDuration"`
+	NextRun      time.Time     `json:"nextRun"`
+	LastError    string        `json:"lastError,omitempty"`
+	WaitingOn    []string      `json:"waitingOn,omitempty"`
+	Runs         int           `json:"runs"`
+	Failures     int           `json:"failures"`
+}
+
+type entry struct {
+	job    Job
+	status Status
+}
+
+// Scheduler runs registered jobs until its context is cancelled.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []*entry
+	byName  map[string]*entry
+	running sync.WaitGroup
+	wake    chan struct{}
+	started bool
+
+	onDone []func(Status)
+}
+
+// New returns an empty scheduler.
+func New() *Scheduler {
+	return &Scheduler{
+		byName: make(map[string]*entry),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Register adds a job. Dependencies must be registered before the jobs
+// that depend on them, which also rules out cycles.
+func (s *Scheduler) Register(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return errors.New("scheduler: cannot register jobs after Run")
+	}
+	if job.Name == "" {
+		return errors.New("scheduler: job name is required")
+	}
+	if _, ok := s.byName[job.Name]; ok {
+		return fmt.Errorf("scheduler: job %q is already registered", job.Name)
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("scheduler: job %q needs a positive interval", job.Name)
+	}
+	if job.Jitter < 0 || job.Timeout < 0 {
+		return fmt.Errorf("scheduler: job %q has a negative jitter or timeout", job.Name)
+	}
+	if job.Run == nil {
+		return fmt.Errorf("scheduler: job %q has no Run function", job.Name)
+	}
+	for _, dep := range job.DependsOn {
+		if _, ok := s.byName[dep]; !ok {
+			return fmt.Errorf("scheduler: job %q depends on unregistered job %q", job.Name, dep)
+		}
+	}
+
+	e := &entry{job: job, status: Status{Name: job.Name}}
+	s.jobs = append(s.jobs, e)
+	s.byName[job.Name] = e
+	return nil
+}
+
+// OnDone registers fn to be called after every run of every job.
+// It must be called before Run.
+func (s *Scheduler) OnDone(fn func(Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onDone = append(s.onDone, fn)
+}
+
+// Run starts every job immediately and then on its interval. It blocks
+// until ctx is cancelled, then cancels running jobs and waits for them.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return errors.New("scheduler: already running")
+	}
+	s.started = true
+	now := time.Now()
+	for _, e := range s.jobs {
+		e.status.NextRun = now
+	}
+	s.mu.Unlock()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		wait := s.startDue(ctx, time.Now())
+
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			s.running.Wait()
+			return nil
+		case <-timer.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// maxWait caps how long Run sleeps between checks when nothing is due.
+const maxWait = time.Minute
+
+// startDue starts every job that is due and whose dependencies are ready.
+// It returns how long to wait before the next job is due.
+func (s *Scheduler) startDue(ctx context.Context, now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := maxWait
+	for _, e := range s.jobs {
+		if e.status.Running {
+			continue
+		}
+
+		e.status.WaitingOn = s.unreadyDeps(e, now)
+		if len(e.status.WaitingOn) > 0 {
+			// Woken again when a dependency finishes.
+			continue
+		}
+
+		if until := e.status.NextRun.Sub(now); until > 0 {
+			wait = min(wait, until)
+			continue
+		}
+		s.start(ctx, e, now)
+	}
+	return wait
+}
+
+// unreadyDeps returns the dependencies of e that have not succeeded
+// recently or are still running.
+func (s *Scheduler) unreadyDeps(e *entry, now time.Time) []string {
+	var waiting []string
+	for _, name := range e.job.DependsOn {
+		dep := s.byName[name]
+		fresh := 2*dep.job.Interval + dep.job.Jitter
+		if dep.status.Running || dep.status.LastSuccess.IsZero() || now.Sub(dep.status.LastSuccess) > fresh {
+			waiting = append(waiting, name)
+		}
+	}
+	return waiting
+}
+
+// start runs e in its own goroutine. s.mu must be held.
+func (s *Scheduler) start(ctx context.Context, e *entry, now time.Time) {
+	e.status.Running = true
+	e.status.LastRun = now
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Done()
+
+		jobCtx := ctx
+		if e.job.Timeout > 0 {
+			var cancel context.CancelFunc
+			jobCtx, cancel = context.WithTimeout(ctx, e.job.Timeout)
+			defer cancel()
+		}
+
+		err := runSafely(jobCtx, e.job.Run)
+		finished := time.Now()
+
+		s.mu.Lock()
+		e.status.Running = false
+		e.status.Runs++
+		e.status.LastDuration = finished.Sub(e.status.LastRun)
+		e.status.NextRun = e.status.LastRun.Add(e.job.Interval + jitter(e.job.Jitter))
+		if err != nil {
+			e.status.Failures++
+			e.status.LastError = err.Error()
+		} else {
+			e.status.LastSuccess = finished
+			e.status.LastError = ""
+		}
+		status := e.status
+		hooks := s.onDone
+		s.mu.Unlock()
+
+		for _, fn := range hooks {
+			fn(status)
+		}
+
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}()
+}
+
+// runSafely calls run, turning a panic into an error so one bad job
+// cannot take down the process.
+func runSafely(ctx context.Context, run func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run(ctx)
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
+
+// Status returns a snapshot of every job, sorted by name.
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		status := e.status
+		status.WaitingOn = append([]string(nil), e.status.WaitingOn...)
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Dependent jobs must not start until their dependencies have succeeded,
+// and Run must return once the context is cancelled.
+func TestSchedulerDependenciesAndShutdown(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	s := New()
+	slowDone := make(chan struct{})
+	jobs := []Job{
+		{Name: "buoys", Interval: time.Hour, Run: func(ctx context.Context) error {
+			time.Sleep(20 * time.Millisecond)
+			defer close(slowDone)
+			return record("buoys")(ctx)
+		}},
+		{Name: "weather", Interval: time.Hour, Run: record("weather")},
+		{Name: "conditions", Interval: time.Hour, DependsOn: []string{"buoys", "weather"}, Run: record("conditions")},
+	}
+	for _, job := range jobs {
+		if err := s.Register(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	deadline := time.After(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(order)
+		mu.Unlock()
+		if n == 3 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("jobs did not all run, got %v", order)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	<-slowDone
+
+	if order[2] != "conditions" {
+		t.Errorf("conditions ran before its dependencies: %v", order)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+// A failing job records its error and schedules the next run; a job that
+// outlives its timeout sees its context cancelled.
+func TestSchedulerErrorsAndTimeouts(t *testing.T) {
+	s := New()
+	s.Register(Job{Name: "broken", Interval: time.Hour, Run: func(ctx context.Context) error {
+		return errors.New("upstream down")
+	}})
+	s.Register(Job{Name: "slow", Interval: time.Hour, Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	finished := make(chan Status, 2)
+	s.OnDone(func(st Status) { finished <- st })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	for range 2 {
+		select {
+		case <-finished:
+		case <-time.After(2 * time.Second):
+			t.Fatal("jobs did not finish")
+		}
+	}
+
+	for _, st := range s.Status() {
+		if st.LastError == "" || st.Failures != 1 {
+			t.Errorf("%s: want one recorded failure, got %+v", st.Name, st)
+		}
+		if !st.NextRun.After(st.LastRun) {
+			t.Errorf("%s: next run %v is not after last run %v", st.Name, st.NextRun, st.LastRun)
+		}
+	}
+}
+
+func TestRegisterRejectsUnknownDependency(t *testing.T) {
+	s := New()
+	err := s.Register(Job{Name: "conditions", Interval: time.Minute, DependsOn: []string{"buoys"}, Run: func(context.Context) error { return nil }})
+	if err == nil {
+		t.Fatal("expected an error for an unregistered dependency")
+	}
+}
@@ -1,43 +1,29 @@
 package spacial
 
 import (
-	"database/sql"
-	"log"
 	"math"
 )
 
-// NearestBuoy finds the nearest buoy to a surf spot.
-// Function runs once on database build and surfspot/buoy updates.
-func NearestBuoy(lat, lon float64, db *sql.DB) int {
-	// fetch db data
-	rows, err := db.Query("SELECT id, latitude, longitude FROM buoys")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer rows.Close()
+// Location is a buoy's position.
+type Location struct {
+	ID        int
+	Latitude  float64
+	Longitude float64
+}
 
-	var id int
-	var bLat float64
-	var bLon float64
+// NearestBuoy finds the nearest buoy to a surf spot. It returns 0 when
+// buoys is empty.
+// Function runs once on database build and surfspot/buoy updates.
+func NearestBuoy(lat, lon float64, buoys []Location) int {
 	var nearestBuoyId int
-	var current float64
 	nearest := math.MaxFloat64
 
-	// for each row in buoy table
-	for rows.Next() {
-
-		err = rows.Scan(&id, &bLat, &bLon)
-		if err != nil {
-			log.Fatal(err)
-		}
-
+	for _, b := range buoys {
 		// find distance between buoy and spot
-		d := Haversine(lat, lon, bLat, bLon)
-		current = d
-
-		if current < nearest {
-			nearest = current
-			nearestBuoyId = id
+		d := Haversine(lat, lon, b.Latitude, b.Longitude)
+		if d < nearest {
+			nearest = d
+			nearestBuoyId = b.ID
 		}
 	}
 
@@ -52,7 +38,7 @@ func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
 	const degToRad = math.Pi / 180.0
 
 	phi1 := lat1 * degToRad
-	phi2 := lat2 + degToRad
+	phi2 := lat2 * degToRad
 	dphi := (lat2 - lat1) * degToRad
 	dlambda := (lon2 - lon1) * degToRad
 
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/spacial"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"strconv"
+	"time"
+)
+
+// Demo returns a store seeded with two Orange County cities, a few of
+// their surf spots, the nearest buoys and current conditions observed ten
+// minutes ago, so the API and frontend can be tried without a database or
+// network access.
+func Demo() *Store {
+	s := New()
+	ctx := context.Background()
+	observed := time.Now().UTC().Add(-10 * time.Minute).Truncate(time.Minute)
+
+	station := "KSNA"
+	err := s.ReplaceStatic(ctx, store.StaticData{
+		Buoys: []store.Buoy{
+			{ID: 46222, Name: "San Pedro, CA (092)", Latitude: 33.614, Longitude: -118.314},
+			{ID: 46253, Name: "San Pedro South, CA (213)", Latitude: 33.576, Longitude: -118.181},
+		},
+		Cities: []store.City{
+			{ID: 3, Name: "Huntington Beach", Latitude: 33.6595, Longitude: -117.9988, Country: "USA", State: "California", County: "Orange County", WeatherStation: &station},
+			{ID: 4, Name: "Newport Beach", Latitude: 33.6189, Longitude: -117.9298, Country: "USA", State: "California", County: "Orange County", WeatherStation: &station},
+		},
+		Spots: []store.Spot{
+			{ID: 5, Name: "Huntington Beach Pier", Latitude: 33.6550, Longitude: -118.0030, CityID: 3, BreakType: "beach", Orientation: 200, TideRegion: 1},
+			{ID: 7, Name: "Newport Pier", Latitude: 33.6073, Longitude: -117.9297, CityID: 4, BreakType: "beach", Orientation: 190, TideRegion: 2},
+			{ID: 10, Name: "The Wedge", Latitude: 33.5930, Longitude: -117.8810, CityID: 4, BreakType: "wedge", Orientation: 180, TideRegion: 2},
+		},
+		Tides: demoTides(),
+	})
+	if err != nil {
+		panic(err) // the seed data is fixed, so this is a programming error
+	}
+
+	s.SaveBuoyObservations(ctx, []store.BuoyObservation{
+		{BuoyID: 46222, RecordedAt: observed, WaveHeightM: ptr(1.1), DominantWavePeriodSec: ptr(14.0), MeanWaveDirectionDegT: ptr(210.0), WaterTempDegC: ptr(17.2), InsertedAt: observed},
+		{BuoyID: 46253, RecordedAt: observed, WaveHeightM: ptr(0.9), DominantWavePeriodSec: ptr(13.0), MeanWaveDirectionDegT: ptr(205.0), WaterTempDegC: ptr(17.8), InsertedAt: observed},
+	})
+	s.SaveWeatherObservations(ctx, []store.WeatherObservation{
+		{CityID: 3, RecordedAt: observed, WindSpeedMph: "6", WindDirection: ptr(250.0), AirTempC: ptr(21.0), CloudCoverage: "FEW", ObservedAt: observed.Format(time.RFC3339), Station: station, StationLatitude: ptr(33.680), StationLongitude: ptr(-117.866)},
+		{CityID: 4, RecordedAt: observed, WindSpeedMph: "5", WindDirection: ptr(240.0), AirTempC: ptr(21.5), CloudCoverage: "CLR", ObservedAt: observed.Format(time.RFC3339), Station: station, StationLatitude: ptr(33.680), StationLongitude: ptr(-117.866)},
+	})
+
+	spots, _ := s.Spots(ctx)
+	buoys := make(map[int]store.BuoyObservation)
+	for _, o := range s.buoyObs {
+		buoys[o.BuoyID] = o
+	}
+	var conditions []models.CurrentSurfSpotConditions
+	for _, spot := range spots {
+		buoy := buoys[spot.NearestBuoy]
+		buoyLocation := s.buoys[spot.NearestBuoy]
+		weather := s.weather[spot.CityID]
+		windDirection := strconv.FormatFloat(*weather.WindDirection, 'f', -1, 64)
+		cloudCoverage := weather.CloudCoverage
+		conditions = append(conditions, models.CurrentSurfSpotConditions{
+			SpotId:                spot.ID,
+			RecordedAt:            observed,
+			DomSwellHeightM:       buoy.WaveHeightM,
+			DomSwellDir:           buoy.MeanWaveDirectionDegT,
+			WindSpeedMph:          &weather.WindSpeedMph,
+			WindDirection:         &windDirection,
+			AirTempDegC:           weather.AirTempC,
+			WaterTempDegC:         buoy.WaterTempDegC,
+			CloudCoverage:         &cloudCoverage,
+			DominantWavePeriodSec: buoy.DominantWavePeriodSec,
+			NearestBuoy:           spot.NearestBuoy,
+			Provenance: models.Provenance{
+				Buoy: models.Source{
+					ID:         strconv.Itoa(spot.NearestBuoy),
+					DistanceKm: ptr(spacial.Haversine(spot.Latitude, spot.Longitude, buoyLocation.Latitude, buoyLocation.Longitude)),
+					ObservedAt: &observed,
+				},
+				Weather: models.Source{
+					ID:         station,
+					DistanceKm: ptr(spacial.Haversine(spot.Latitude, spot.Longitude, *weather.StationLatitude, *weather.StationLongitude)),
+					ObservedAt: &observed,
+				},
+			},
+		})
+	}
+	s.ReplaceConditions(ctx, conditions)
+
+	for _, run := range []struct {
+		source string
+		rows   int
+	}{
+		{"buoys", len(buoys)},
+		{"weather", len(s.weather)},
+		{"conditions", len(conditions)},
+	} {
+		id, _ := s.StartRun(ctx, run.source, observed)
+		finished := observed.Add(5 * time.Second)
+		s.FinishRun(ctx, store.IngestionRun{ID: id, Status: store.RunOK, FinishedAt: &finished, RowsWritten: run.rows})
+	}
+	return s
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// demoTides returns today's tides at the Newport Beach station.
+func demoTides() []store.TidePrediction {
+	today := time.Now().Format("2006-01-02")
+	var tides []store.TidePrediction
+	for _, t := range []struct {
+		time  string
+		level float64
+		state string
+	}{{"00:12", 2.27, "L"}, {"06:31", 5.48, "H"}, {"13:45", -0.35, "L"}, {"20:02", 3.91, "H"}} {
+		tides = append(tides, store.TidePrediction{
+			StationName: "NEWPORT BEACH, NEWPORT BAY ENTRANCE",
+			CountyName:  "Orange County",
+			StateCode:   "CA",
+			Date:        today,
+			Time:        t.time,
+			WaterLevel:  t.level,
+			TidalState:  t.state,
+			TideRegion:  "2",
+		})
+	}
+	return tides
+}
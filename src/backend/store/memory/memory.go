@@ -0,0 +1,496 @@
+// Package memory implements the store repositories in process. Nothing is
+// persisted; it backs tests and running the server without a database.
+package memory
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/spacial"
+	"Go_surf_redesign/src/backend/store"
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is a store.Store that keeps every table in maps guarded by one
+// lock, so each write is atomic like a Postgres transaction.
+type Store struct {
+	mu sync.RWMutex
+
+	buoys      map[int]store.Buoy
+	cities     map[int]store.City
+	spots      map[int]store.Spot
+	tides      []store.TidePrediction
+	buoyObs    map[int]store.BuoyObservation
+	history    map[historyKey]store.BuoyObservation
+	weather    map[int]store.WeatherObservation
+	conditions map[int]models.CurrentSurfSpotConditions
+	forecasts  map[int][]store.ForecastPeriod
+	runs       []store.IngestionRun
+
+	nextConditionsID int
+	rebuilds         int64
+}
+
+var _ store.Store = (*Store)(nil)
+
+type historyKey struct {
+	buoyID     int
+	recordedAt time.Time
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{
+		buoys:      make(map[int]store.Buoy),
+		cities:     make(map[int]store.City),
+		spots:      make(map[int]store.Spot),
+		buoyObs:    make(map[int]store.BuoyObservation),
+		history:    make(map[historyKey]store.BuoyObservation),
+		weather:    make(map[int]store.WeatherObservation),
+		conditions: make(map[int]models.CurrentSurfSpotConditions),
+		forecasts:  make(map[int][]store.ForecastPeriod),
+	}
+}
+
+func (s *Store) Ping(ctx context.Context) error { return nil }
+
+func (s *Store) Close() error { return nil }
+
+// ReplaceStatic applies data with the same rules as the Postgres store: a
+// city that still has surf spots cannot be removed.
+func (s *Store) ReplaceStatic(ctx context.Context, data store.StaticData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	spots := s.spots
+	if data.Spots != nil {
+		spots = make(map[int]store.Spot, len(data.Spots))
+		for _, sp := range data.Spots {
+			spots[sp.ID] = sp
+		}
+	}
+	cities := s.cities
+	if data.Cities != nil {
+		cities = make(map[int]store.City, len(data.Cities))
+		for _, c := range data.Cities {
+			if c.WeatherStation == nil {
+				c.WeatherStation = s.cities[c.ID].WeatherStation
+			}
+			cities[c.ID] = c
+		}
+	}
+	for _, sp := range spots {
+		if _, ok := cities[sp.CityID]; !ok {
+			return fmt.Errorf("could not delete unlisted cities: surf spot %d belongs to city %d", sp.ID, sp.CityID)
+		}
+	}
+
+	if data.Buoys != nil {
+		s.buoys = make(map[int]store.Buoy, len(data.Buoys))
+		for _, b := range data.Buoys {
+			s.buoys[b.ID] = b
+		}
+	}
+	for id := range s.cities {
+		if _, ok := cities[id]; !ok {
+			delete(s.forecasts, id)
+		}
+	}
+	s.cities = cities
+	s.spots = spots
+	if data.Buoys != nil || data.Spots != nil {
+		locations := make([]spacial.Location, 0, len(s.buoys))
+		for _, b := range s.buoys {
+			locations = append(locations, spacial.Location{ID: b.ID, Latitude: b.Latitude, Longitude: b.Longitude})
+		}
+		for id, sp := range s.spots {
+			sp.NearestBuoy = spacial.NearestBuoy(sp.Latitude, sp.Longitude, locations)
+			s.spots[id] = sp
+		}
+	}
+	if data.Tides != nil {
+		s.tides = slices.Clone(data.Tides)
+	}
+	return nil
+}
+
+func (s *Store) Buoys(ctx context.Context) ([]store.Buoy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedBy(s.buoys, func(b store.Buoy) int { return b.ID }), nil
+}
+
+func (s *Store) Cities(ctx context.Context) ([]store.City, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedBy(s.cities, func(c store.City) string { return c.Name }), nil
+}
+
+func (s *Store) Spots(ctx context.Context) ([]store.Spot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedBy(s.spots, func(sp store.Spot) int { return sp.ID }), nil
+}
+
+func (s *Store) SpotsByCity(ctx context.Context, cityID int) ([]store.Spot, error) {
+	spots, _ := s.Spots(ctx)
+	return slices.DeleteFunc(spots, func(sp store.Spot) bool { return sp.CityID != cityID }), nil
+}
+
+func (s *Store) ListCities(ctx context.Context, f store.ListFilter, page store.Keyset) ([]store.City, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tides := make(map[int]bool)
+	for _, sp := range s.spots {
+		if sp.TideRegion != 0 {
+			tides[sp.CityID] = true
+		}
+	}
+	var cities []store.City
+	for _, c := range s.cities {
+		if matchRegion(f.RegionFilter, c) && (f.HasTideStation == nil || tides[c.ID] == *f.HasTideStation) {
+			cities = append(cities, c)
+		}
+	}
+	return keysetPage(cities, page, func(c store.City) (string, int) { return c.Name, c.ID })
+}
+
+func (s *Store) ListSpots(ctx context.Context, f store.ListFilter, page store.Keyset) ([]store.Spot, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var spots []store.Spot
+	for _, sp := range s.spots {
+		if (f.CityID != 0 && sp.CityID != f.CityID) ||
+			!matchRegion(store.RegionFilter{State: f.State, County: f.County}, s.cities[sp.CityID]) ||
+			(f.BreakType != "" && !strings.EqualFold(f.BreakType, sp.BreakType)) ||
+			(f.HasTideStation != nil && (sp.TideRegion != 0) != *f.HasTideStation) {
+			continue
+		}
+		spots = append(spots, sp)
+	}
+	return keysetPage(spots, page, func(sp store.Spot) (string, int) { return sp.Name, sp.ID })
+}
+
+// matchRegion reports whether city is in the state and county of f.
+func matchRegion(f store.RegionFilter, city store.City) bool {
+	return (f.State == "" || strings.EqualFold(f.State, city.State)) &&
+		(f.County == "" || strings.EqualFold(f.County, city.County))
+}
+
+// keysetPage sorts items by name and id and returns those in page, with
+// the number of items.
+func keysetPage[T any](items []T, page store.Keyset, key func(T) (string, int)) ([]T, int, error) {
+	compare := func(a, b T) int {
+		an, ai := key(a)
+		bn, bi := key(b)
+		return cmp.Or(cmp.Compare(an, bn), cmp.Compare(ai, bi))
+	}
+	slices.SortFunc(items, compare)
+	start, _ := slices.BinarySearchFunc(items, page, func(item T, page store.Keyset) int {
+		name, id := key(item)
+		if cmp.Or(cmp.Compare(name, page.AfterName), cmp.Compare(id, page.AfterID)) <= 0 {
+			return -1
+		}
+		return 1
+	})
+	end := len(items)
+	if page.Limit > 0 {
+		end = min(start+page.Limit, end)
+	}
+	return items[start:end], len(items), nil
+}
+
+func (s *Store) ReplaceStationTides(ctx context.Context, tides []store.TidePrediction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stations := make(map[string]bool)
+	for _, t := range tides {
+		stations[t.StationName] = true
+	}
+	s.tides = append(slices.DeleteFunc(s.tides, func(t store.TidePrediction) bool {
+		return stations[t.StationName]
+	}), tides...)
+	return nil
+}
+
+func (s *Store) TidesOn(ctx context.Context, date string) ([]store.TidePrediction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var tides []store.TidePrediction
+	for _, t := range s.tides {
+		if t.Date == date {
+			tides = append(tides, t)
+		}
+	}
+	slices.SortFunc(tides, func(a, b store.TidePrediction) int {
+		return cmp.Or(
+			cmp.Compare(a.TideRegion, b.TideRegion),
+			cmp.Compare(a.StationName, b.StationName),
+			cmp.Compare(a.Time, b.Time),
+		)
+	})
+	return tides, nil
+}
+
+func (s *Store) SaveBuoyObservations(ctx context.Context, obs []store.BuoyObservation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range obs {
+		s.buoyObs[o.BuoyID] = o
+	}
+	for id := range s.buoyObs {
+		if _, ok := s.buoys[id]; !ok {
+			delete(s.buoyObs, id)
+		}
+	}
+	return nil
+}
+
+func (s *Store) LatestBuoyObservations(ctx context.Context) ([]store.BuoyObservation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedBy(s.buoyObs, func(o store.BuoyObservation) int { return o.BuoyID }), nil
+}
+
+func (s *Store) AddBuoyHistory(ctx context.Context, obs []store.BuoyObservation) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	written := 0
+	for _, o := range obs {
+		key := historyKey{o.BuoyID, o.RecordedAt.UTC()}
+		if _, ok := s.history[key]; ok {
+			continue
+		}
+		s.history[key] = o
+		written++
+	}
+	return written, nil
+}
+
+func (s *Store) SaveWeatherObservations(ctx context.Context, obs []store.WeatherObservation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range obs {
+		s.weather[o.CityID] = o
+	}
+	for id := range s.weather {
+		if _, ok := s.cities[id]; !ok {
+			delete(s.weather, id)
+		}
+	}
+	return nil
+}
+
+func (s *Store) LatestWeatherObservations(ctx context.Context) ([]store.WeatherObservation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedBy(s.weather, func(o store.WeatherObservation) int { return o.CityID }), nil
+}
+
+func (s *Store) ReplaceConditions(ctx context.Context, conditions []models.CurrentSurfSpotConditions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rebuilds++
+	replaced := make(map[int]models.CurrentSurfSpotConditions, len(conditions))
+	for _, c := range conditions {
+		c.Rebuild = s.rebuilds
+		if old, ok := s.conditions[c.SpotId]; ok {
+			c.ID = old.ID
+		} else {
+			s.nextConditionsID++
+			c.ID = s.nextConditionsID
+		}
+		replaced[c.SpotId] = c
+	}
+	s.conditions = replaced
+	return nil
+}
+
+func (s *Store) SpotConditions(ctx context.Context, spotID int) (models.CurrentSurfSpotConditions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.conditions[spotID]
+	if !ok {
+		return c, store.ErrNotFound
+	}
+	return c, nil
+}
+
+func (s *Store) AllConditions(ctx context.Context) ([]models.CurrentSurfSpotConditions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := make([]models.CurrentSurfSpotConditions, 0, len(s.conditions))
+	for _, id := range slices.Sorted(maps.Keys(s.conditions)) {
+		all = append(all, s.conditions[id])
+	}
+	return all, nil
+}
+
+func (s *Store) RegionConditions(ctx context.Context, f store.RegionFilter) ([]store.SpotWithConditions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var spots []store.SpotWithConditions
+	for _, spot := range s.spots {
+		city := s.cities[spot.CityID]
+		if (f.CityID != 0 && spot.CityID != f.CityID) ||
+			(f.State != "" && !strings.EqualFold(f.State, city.State)) ||
+			(f.County != "" && !strings.EqualFold(f.County, city.County)) {
+			continue
+		}
+		item := store.SpotWithConditions{Spot: spot}
+		if conditions, ok := s.conditions[spot.ID]; ok {
+			item.Conditions = &conditions
+		}
+		spots = append(spots, item)
+	}
+	slices.SortFunc(spots, func(a, b store.SpotWithConditions) int {
+		return cmp.Or(cmp.Compare(a.Spot.Name, b.Spot.Name), cmp.Compare(a.Spot.ID, b.Spot.ID))
+	})
+	return spots, nil
+}
+
+func (s *Store) ReplaceCityForecast(ctx context.Context, cityID int, periods []store.ForecastPeriod) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forecasts[cityID] = slices.Clone(periods)
+	return nil
+}
+
+func (s *Store) StartRun(ctx context.Context, source string, startedAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := int64(len(s.runs) + 1)
+	s.runs = append(s.runs, store.IngestionRun{
+		ID:        id,
+		Source:    source,
+		Status:    store.RunRunning,
+		StartedAt: startedAt,
+		Failures:  map[string]string{},
+	})
+	return id, nil
+}
+
+func (s *Store) FinishRun(ctx context.Context, run store.IngestionRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run.ID < 1 || int(run.ID) > len(s.runs) {
+		return fmt.Errorf("ingestion run %d: %w", run.ID, store.ErrNotFound)
+	}
+	stored := &s.runs[run.ID-1]
+	stored.Status = run.Status
+	stored.FinishedAt = run.FinishedAt
+	stored.RowsWritten = run.RowsWritten
+	stored.Failures = maps.Clone(run.Failures)
+	if stored.Failures == nil {
+		stored.Failures = map[string]string{}
+	}
+	stored.Error = run.Error
+	return nil
+}
+
+// newestFirst returns the runs ordered by start time, newest first.
+func (s *Store) newestFirst() []store.IngestionRun {
+	runs := slices.Clone(s.runs)
+	slices.SortStableFunc(runs, func(a, b store.IngestionRun) int {
+		return b.StartedAt.Compare(a.StartedAt)
+	})
+	return runs
+}
+
+func (s *Store) RecentRuns(ctx context.Context, limit int) ([]store.IngestionRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	runs := s.newestFirst()
+	return runs[:min(limit, len(runs))], nil
+}
+
+func (s *Store) LatestRuns(ctx context.Context) ([]store.IngestionRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	latest := make(map[string]store.IngestionRun)
+	for _, run := range s.newestFirst() {
+		if _, ok := latest[run.Source]; !ok {
+			latest[run.Source] = run
+		}
+	}
+	return sortedBy(latest, func(r store.IngestionRun) string { return r.Source }), nil
+}
+
+func (s *Store) RunStats(ctx context.Context, since time.Time) ([]store.SourceStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := make(map[string]store.SourceStats)
+	for _, run := range s.runs {
+		st := stats[run.Source]
+		st.Source = run.Source
+		if (run.Status == store.RunOK || run.Status == store.RunPartial) && run.FinishedAt != nil {
+			if st.LastSuccessAt == nil || run.FinishedAt.After(*st.LastSuccessAt) {
+				st.LastSuccessAt = run.FinishedAt
+			}
+		}
+		if run.StartedAt.After(since) {
+			st.Runs++
+			if run.Status == store.RunFailed {
+				st.Failures++
+			}
+		}
+		stats[run.Source] = st
+	}
+	return sortedBy(stats, func(st store.SourceStats) string { return st.Source }), nil
+}
+
+// Status reports the tables under the names the Postgres store uses.
+func (s *Store) Status(ctx context.Context) ([]store.TableStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	newest := func(times []time.Time) *time.Time {
+		if len(times) == 0 {
+			return nil
+		}
+		t := slices.MaxFunc(times, time.Time.Compare)
+		return &t
+	}
+	var buoyTimes, weatherTimes, conditionTimes, historyTimes, runTimes []time.Time
+	for _, o := range s.buoyObs {
+		buoyTimes = append(buoyTimes, o.RecordedAt)
+	}
+	for _, o := range s.weather {
+		weatherTimes = append(weatherTimes, o.RecordedAt)
+	}
+	for _, c := range s.conditions {
+		conditionTimes = append(conditionTimes, c.RecordedAt)
+	}
+	for key := range s.history {
+		historyTimes = append(historyTimes, key.recordedAt)
+	}
+	for _, run := range s.runs {
+		runTimes = append(runTimes, run.StartedAt)
+	}
+
+	return []store.TableStatus{
+		{Table: "cities", Rows: len(s.cities)},
+		{Table: "buoys", Rows: len(s.buoys)},
+		{Table: "surfspot", Rows: len(s.spots)},
+		{Table: "tide_data", Rows: len(s.tides)},
+		{Table: "real_time_buoy_data_points", Rows: len(buoyTimes), Newest: newest(buoyTimes)},
+		{Table: "current_weather", Rows: len(weatherTimes), Newest: newest(weatherTimes)},
+		{Table: "current_surf_spot_conditions", Rows: len(conditionTimes), Newest: newest(conditionTimes)},
+		{Table: "buoy_data_history", Rows: len(historyTimes), Newest: newest(historyTimes)},
+		{Table: "ingestion_runs", Rows: len(runTimes), Newest: newest(runTimes)},
+	}, nil
+}
+
+// sortedBy returns the values of m ordered by key.
+func sortedBy[K comparable, V any, O cmp.Ordered](m map[K]V, key func(V) O) []V {
+	values := slices.Collect(maps.Values(m))
+	slices.SortFunc(values, func(a, b V) int {
+		return cmp.Compare(key(a), key(b))
+	})
+	return values
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/archive"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Archive keeps raw upstream payloads in the raw_payloads table.
+type Archive struct {
+	db *sql.DB
+}
+
+// Archive returns an archive backed by the store's database.
+func (s *Store) Archive() *Archive {
+	return &Archive{db: s.db}
+}
+
+func (a *Archive) Put(ctx context.Context, p archive.Payload) error {
+	body, err := archive.Compress(p.Body)
+	if err != nil {
+		return fmt.Errorf("could not compress payload: %w", err)
+	}
+	_, err = a.db.ExecContext(ctx, `
+		INSERT INTO raw_payloads (source, key, fetched_at, body)
+		VALUES ($1, $2, $3, $4)
+	`, p.Source, p.Key, p.FetchedAt, body)
+	return err
+}
+
+func (a *Archive) Scan(ctx context.Context, from, to time.Time, fn func(archive.Payload) error) error {
+	rows, err := a.db.QueryContext(ctx, `
+		SELECT source, key, fetched_at, body
+		FROM raw_payloads
+		WHERE fetched_at >= $1 AND fetched_at < $2
+		ORDER BY fetched_at, id
+	`, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p archive.Payload
+		var body []byte
+		if err := rows.Scan(&p.Source, &p.Key, &p.FetchedAt, &body); err != nil {
+			return err
+		}
+		if p.Body, err = archive.Decompress(body); err != nil {
+			return fmt.Errorf("could not decompress payload %s: %w", p.Key, err)
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
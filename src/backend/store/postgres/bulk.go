@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Bulk writes avoid a round trip per row. Pure inserts stream the rows with
+// COPY; upserts send as many rows per INSERT statement as the bind
+// parameter limit allows.
+
+// maxParams is the most bind parameters Postgres accepts in one statement.
+const maxParams = 65535
+
+// copyRows streams rows into table with COPY. It must run inside a
+// transaction.
+func copyRows(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]any) error {
+	sqlStmnt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return fmt.Errorf("could not start copy into %s: %w", table, err)
+	}
+	defer sqlStmnt.Close()
+
+	for _, row := range rows {
+		if _, err := sqlStmnt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("could not copy into %s: %w", table, err)
+		}
+	}
+	// An Exec without arguments flushes the buffered rows.
+	if _, err := sqlStmnt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("could not copy into %s: %w", table, err)
+	}
+	return nil
+}
+
+// insertRows inserts rows into table with multi-row INSERT statements,
+// appending suffix (typically an ON CONFLICT clause) to each. It returns
+// the number of rows affected.
+func insertRows(ctx context.Context, q querier, table string, columns []string, suffix string, rows [][]any) (int64, error) {
+	batch := maxParams / len(columns)
+	var affected int64
+	for start := 0; start < len(rows); start += batch {
+		chunk := rows[start:min(start+batch, len(rows))]
+
+		args := make([]any, 0, len(chunk)*len(columns))
+		for _, row := range chunk {
+			args = append(args, row...)
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s %s",
+			table, strings.Join(columns, ", "), placeholders(len(chunk), len(columns)), suffix)
+
+		res, err := q.ExecContext(ctx, query, args...)
+		if err != nil {
+			return affected, err
+		}
+		n, _ := res.RowsAffected()
+		affected += n
+	}
+	return affected, nil
+}
+
+// placeholders returns the VALUES list for rows rows of width columns:
+// ($1, $2), ($3, $4), ...
+func placeholders(rows, width int) string {
+	var b strings.Builder
+	n := 1
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for c := 0; c < width; c++ {
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			n++
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// excludedSet returns the SET list of an upsert that overwrites columns
+// with the values of the conflicting row.
+func excludedSet(columns []string) string {
+	set := make([]string, len(columns))
+	for i, c := range columns {
+		set[i] = c + " = EXCLUDED." + c
+	}
+	return strings.Join(set, ", ")
+}
@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/config"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		rows, width int
+		want        string
+	}{
+		{1, 1, "($1)"},
+		{1, 3, "($1, $2, $3)"},
+		{3, 2, "($1, $2), ($3, $4), ($5, $6)"},
+	}
+	for _, tt := range tests {
+		if got := placeholders(tt.rows, tt.width); got != tt.want {
+			t.Errorf("placeholders(%d, %d) = %q, want %q", tt.rows, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestExcludedSet(t *testing.T) {
+	got := excludedSet([]string{"name", "latitude"})
+	want := "name = EXCLUDED.name, latitude = EXCLUDED.latitude"
+	if got != want {
+		t.Errorf("excludedSet = %q, want %q", got, want)
+	}
+}
+
+// The benchmarks compare the bulk writers with the row-at-a-time inserts
+// they replaced. They need a Postgres database they may wipe, named by
+// GOSURF_TEST_DATABASE_URL:
+//
+//	go test -run '^$' -bench . ./src/backend/store/postgres
+
+func benchmarkStore(b *testing.B) *Store {
+	b.Helper()
+	url := os.Getenv("GOSURF_TEST_DATABASE_URL")
+	if url == "" {
+		b.Skip("GOSURF_TEST_DATABASE_URL is not set")
+	}
+	cfg := config.Default()
+	cfg.Database.URL = url
+	st, err := Open(cfg.Database)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { st.Close() })
+	if _, err := st.Migrate(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	return st
+}
+
+// yearOfTides returns four predictions a day for a year at one station.
+func yearOfTides() []store.TidePrediction {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tides []store.TidePrediction
+	for day := 0; day < 365; day++ {
+		date := start.AddDate(0, 0, day)
+		for i, state := range []string{"L", "H", "L", "H"} {
+			tides = append(tides, store.TidePrediction{
+				StationName: "Los Angeles",
+				CountyName:  "Los Angeles",
+				StateCode:   "CA",
+				Date:        date.Format("2006-01-02"),
+				Time:        fmt.Sprintf("%02d:%02d", i*6+1, day%60),
+				WaterLevel:  float64(i%2)*1.5 + 0.1,
+				TidalState:  state,
+				TideRegion:  "Southern California",
+			})
+		}
+	}
+	return tides
+}
+
+// backfillDays returns the ten-minute observations realtime2 files hold
+// for days days at each of buoys.
+func backfillDays(buoys []int, days int) []store.BuoyObservation {
+	end := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	height := 1.2
+	var obs []store.BuoyObservation
+	for _, id := range buoys {
+		for t := end.AddDate(0, 0, -days); t.Before(end); t = t.Add(10 * time.Minute) {
+			obs = append(obs, store.BuoyObservation{
+				BuoyID:      id,
+				RecordedAt:  t,
+				WaveHeightM: &height,
+				InsertedAt:  end,
+			})
+		}
+	}
+	return obs
+}
+
+// insertEachRow writes rows one prepared INSERT at a time, the way the
+// store wrote them before the bulk path.
+func insertEachRow(ctx context.Context, tx *sql.Tx, query string, rows [][]any) error {
+	sqlStmnt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer sqlStmnt.Close()
+	for _, row := range rows {
+		if _, err := sqlStmnt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func BenchmarkTides(b *testing.B) {
+	st := benchmarkStore(b)
+	ctx := context.Background()
+	tides := yearOfTides()
+
+	b.Run("row-by-row", func(b *testing.B) {
+		rows := make([][]any, len(tides))
+		for i, t := range tides {
+			rows[i] = []any{t.StationName, t.CountyName, t.StateCode, t.Date, t.Time, t.WaterLevel, t.TidalState, t.TideRegion}
+		}
+		for i := 0; i < b.N; i++ {
+			err := st.withTx(ctx, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, `DELETE FROM tide_data`); err != nil {
+					return err
+				}
+				return insertEachRow(ctx, tx, `
+					INSERT INTO tide_data (station_name, county_name, state_code, measurement_date,
+						measurement_time, water_level, tidal_state, tide_region)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				`, rows)
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("copy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := st.ReplaceStatic(ctx, store.StaticData{Tides: tides}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkBuoyBackfill(b *testing.B) {
+	st := benchmarkStore(b)
+	ctx := context.Background()
+	obs := backfillDays([]int{46221, 46222, 46253, 46256}, 45)
+
+	truncate := func() {
+		b.StopTimer()
+		if _, err := st.db.ExecContext(ctx, `TRUNCATE buoy_data_history`); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+	}
+
+	b.Run("row-by-row", func(b *testing.B) {
+		rows := make([][]any, len(obs))
+		for i, o := range obs {
+			rows[i] = buoyObservationRow(o)
+		}
+		for i := 0; i < b.N; i++ {
+			truncate()
+			err := st.withTx(ctx, func(tx *sql.Tx) error {
+				return insertEachRow(ctx, tx, `
+					INSERT INTO buoy_data_history (`+strings.Join(buoyObservationColumns, ", ")+`)
+					VALUES `+placeholders(1, len(buoyObservationColumns))+`
+					ON CONFLICT (buoy_id, recorded_at) DO NOTHING
+				`, rows)
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("copy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			truncate()
+			n, err := st.AddBuoyHistory(ctx, obs)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if n != len(obs) {
+				b.Fatalf("wrote %d rows, want %d", n, len(obs))
+			}
+		}
+	})
+}
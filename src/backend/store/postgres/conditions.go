@@ -0,0 +1,230 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/metrics"
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var conditionsColumns = []string{
+	"spot_id",
+	"recorded_at",
+	"dom_swell_height_m",
+	"dom_swell_dir",
+	"wind_speed_mph",
+	"wind_direction",
+	"air_temp_deg_c",
+	"water_temp_deg_c",
+	"precipitation",
+	"cloud_coverage",
+	"domwp_sec",
+	"nearest_buoy",
+	"qc_flags",
+	"buoy_distance_km",
+	"buoy_recorded_at",
+	"weather_station",
+	"weather_distance_km",
+	"weather_recorded_at",
+	"rebuild",
+}
+
+// ReplaceConditions upserts conditions into current_surf_spot_conditions
+// and deletes the rows of every other spot in one transaction, so readers
+// never see the table empty or half rebuilt. The rows are numbered from
+// conditions_rebuild_seq while the table is locked, so rebuilds commit in
+// the order of their numbers.
+func (s *Store) ReplaceConditions(ctx context.Context, conditions []models.CurrentSurfSpotConditions) error {
+	defer metrics.ObserveQuery("replace_conditions", time.Now())
+	rows := make([][]any, len(conditions))
+	spotIds := make([]int64, len(conditions))
+	for i, data := range conditions {
+		rows[i] = []any{
+			data.SpotId,
+			data.RecordedAt,
+			data.DomSwellHeightM,
+			data.DomSwellDir,
+			data.WindSpeedMph,
+			data.WindDirection,
+			data.AirTempDegC,
+			data.WaterTempDegC,
+			data.Precipitation,
+			data.CloudCoverage,
+			data.DominantWavePeriodSec,
+			data.NearestBuoy,
+			data.QC,
+			data.Provenance.Buoy.DistanceKm,
+			data.Provenance.Buoy.ObservedAt,
+			sql.NullString{String: data.Provenance.Weather.ID, Valid: data.Provenance.Weather.ID != ""},
+			data.Provenance.Weather.DistanceKm,
+			data.Provenance.Weather.ObservedAt,
+		}
+		spotIds[i] = int64(data.SpotId)
+	}
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE current_surf_spot_conditions IN EXCLUSIVE MODE`); err != nil {
+			return fmt.Errorf("could not lock conditions: %w", err)
+		}
+		var rebuild int64
+		if err := tx.QueryRowContext(ctx, `SELECT nextval('conditions_rebuild_seq')`).Scan(&rebuild); err != nil {
+			return fmt.Errorf("could not number the rebuild: %w", err)
+		}
+		for i := range rows {
+			rows[i] = append(rows[i], rebuild)
+		}
+
+		_, err := insertRows(ctx, tx, "current_surf_spot_conditions", conditionsColumns,
+			"ON CONFLICT (spot_id) DO UPDATE SET "+excludedSet(conditionsColumns[1:]), rows)
+		if err != nil {
+			return fmt.Errorf("could not upsert conditions: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM current_surf_spot_conditions
+			WHERE NOT (spot_id = ANY($1))
+		`, pq.Array(spotIds))
+		return err
+	})
+}
+
+// conditionsSelect selects the current_surf_spot_conditions row aliased
+// cc in the order conditionsFields expects. The COALESCEs let it read the
+// nulls of an outer join for a spot without conditions.
+const conditionsSelect = `
+	COALESCE(cc.id, 0),
+	COALESCE(cc.spot_id, 0),
+	COALESCE(cc.recorded_at, 'epoch'),
+	cc.dom_swell_height_m,
+	cc.dom_swell_dir,
+	cc.wind_speed_mph,
+	cc.wind_direction,
+	cc.air_temp_deg_c,
+	cc.water_temp_deg_c,
+	cc.precipitation,
+	cc.cloud_coverage,
+	cc.domwp_sec,
+	COALESCE(cc.nearest_buoy, 0),
+	cc.qc_flags,
+	cc.buoy_distance_km,
+	cc.buoy_recorded_at,
+	COALESCE(cc.weather_station, ''),
+	cc.weather_distance_km,
+	cc.weather_recorded_at,
+	COALESCE(cc.rebuild, 0)
+`
+
+const conditionsQuery = `SELECT ` + conditionsSelect + ` FROM current_surf_spot_conditions cc `
+
+// conditionsFields returns the scan destinations of conditionsSelect.
+func conditionsFields(c *models.CurrentSurfSpotConditions) []any {
+	return []any{
+		&c.ID,
+		&c.SpotId,
+		&c.RecordedAt,
+		&c.DomSwellHeightM,
+		&c.DomSwellDir,
+		&c.WindSpeedMph,
+		&c.WindDirection,
+		&c.AirTempDegC,
+		&c.WaterTempDegC,
+		&c.Precipitation,
+		&c.CloudCoverage,
+		&c.DominantWavePeriodSec,
+		&c.NearestBuoy,
+		&c.QC,
+		&c.Provenance.Buoy.DistanceKm,
+		&c.Provenance.Buoy.ObservedAt,
+		&c.Provenance.Weather.ID,
+		&c.Provenance.Weather.DistanceKm,
+		&c.Provenance.Weather.ObservedAt,
+		&c.Rebuild,
+	}
+}
+
+// setBuoyID fills in the buoy ID of scanned conditions, which is stored as
+// nearest_buoy.
+func setBuoyID(c *models.CurrentSurfSpotConditions) {
+	if c.NearestBuoy != 0 {
+		c.Provenance.Buoy.ID = strconv.Itoa(c.NearestBuoy)
+	}
+}
+
+// scanConditions reads one row selected by conditionsQuery.
+func scanConditions(row interface{ Scan(dest ...any) error }) (models.CurrentSurfSpotConditions, error) {
+	var conditions models.CurrentSurfSpotConditions
+	err := row.Scan(conditionsFields(&conditions)...)
+	setBuoyID(&conditions)
+	return conditions, err
+}
+
+func (s *Store) SpotConditions(ctx context.Context, spotID int) (models.CurrentSurfSpotConditions, error) {
+	defer metrics.ObserveQuery("spot_conditions", time.Now())
+	conditions, err := scanConditions(s.db.QueryRowContext(ctx, conditionsQuery+`WHERE cc.spot_id = $1`, spotID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return conditions, store.ErrNotFound
+	}
+	return conditions, err
+}
+
+func (s *Store) AllConditions(ctx context.Context) ([]models.CurrentSurfSpotConditions, error) {
+	defer metrics.ObserveQuery("all_conditions", time.Now())
+	rows, err := s.db.QueryContext(ctx, conditionsQuery+`ORDER BY cc.spot_id`)
+	if err != nil {
+		return nil, fmt.Errorf("could not query conditions: %w", err)
+	}
+	defer rows.Close()
+
+	var all []models.CurrentSurfSpotConditions
+	for rows.Next() {
+		conditions, err := scanConditions(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan conditions: %w", err)
+		}
+		all = append(all, conditions)
+	}
+	return all, rows.Err()
+}
+
+func (s *Store) RegionConditions(ctx context.Context, f store.RegionFilter) ([]store.SpotWithConditions, error) {
+	defer metrics.ObserveQuery("region_conditions", time.Now())
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+spotColumns+`, cc.spot_id IS NOT NULL, `+conditionsSelect+`
+		FROM surfspot s
+		JOIN cities c ON c.id = s.city_id
+		LEFT JOIN current_surf_spot_conditions cc ON cc.spot_id = s.id
+		WHERE ($1 = 0 OR s.city_id = $1)
+			AND ($2 = '' OR lower(c.state) = lower($2))
+			AND ($3 = '' OR lower(c.county) = lower($3))
+		ORDER BY s.name, s.id
+	`, f.CityID, f.State, f.County)
+	if err != nil {
+		return nil, fmt.Errorf("could not query region conditions: %w", err)
+	}
+	defer rows.Close()
+
+	var spots []store.SpotWithConditions
+	for rows.Next() {
+		var (
+			item       store.SpotWithConditions
+			hasData    bool
+			conditions models.CurrentSurfSpotConditions
+		)
+		dest := append(spotFields(&item.Spot), &hasData)
+		if err := rows.Scan(append(dest, conditionsFields(&conditions)...)...); err != nil {
+			return nil, fmt.Errorf("could not scan region conditions: %w", err)
+		}
+		if hasData {
+			setBuoyID(&conditions)
+			item.Conditions = &conditions
+		}
+		spots = append(spots, item)
+	}
+	return spots, rows.Err()
+}
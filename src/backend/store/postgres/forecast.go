@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/metrics"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"database/sql"
+	"time"
+)
+
+var forecastColumns = []string{
+	"city_id",
+	"start_time",
+	"end_time",
+	"temperature",
+	"temperature_unit",
+	"wind_speed",
+	"wind_direction",
+	"precipitation_chance",
+	"short_forecast",
+}
+
+// ReplaceCityForecast swaps a city's forecast rows in one transaction.
+func (s *Store) ReplaceCityForecast(ctx context.Context, cityID int, periods []store.ForecastPeriod) error {
+	defer metrics.ObserveQuery("replace_city_forecast", time.Now())
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM city_forecast WHERE city_id = $1`, cityID); err != nil {
+			return err
+		}
+
+		rows := make([][]any, len(periods))
+		for i, p := range periods {
+			rows[i] = []any{
+				cityID,
+				p.StartTime,
+				p.EndTime,
+				p.Temperature,
+				p.TemperatureUnit,
+				p.WindSpeed,
+				p.WindDirection,
+				p.PrecipitationChance,
+				p.ShortForecast,
+			}
+		}
+		_, err := insertRows(ctx, tx, "city_forecast", forecastColumns, "", rows)
+		return err
+	})
+}
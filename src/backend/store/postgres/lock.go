@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var _ store.Locker = (*Store)(nil)
+
+// leaseCheck is how often the connection holding a lock is checked. A
+// holder that lost its connection may keep working for up to this long
+// after another process has taken the lock.
+const leaseCheck = 5 * time.Second
+
+// lockKey maps a lock name to an advisory lock key.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("gosurf:" + name))
+	return int64(h.Sum64())
+}
+
+// TryLock takes a session advisory lock on a connection of its own, so
+// the lock is released by Postgres if this process dies.
+func (s *Store) TryLock(ctx context.Context, name string) (store.Lease, bool, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not take lock %s: %w", name, err)
+	}
+	key := lockKey(name)
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("could not take lock %s: %w", name, err)
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	l := &lease{
+		name:   name,
+		conn:   conn,
+		logger: s.logger,
+		key:    key,
+		lost:   make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go l.watch()
+	return l, true, nil
+}
+
+// lease is an advisory lock held on conn.
+type lease struct {
+	name   string
+	conn   *sql.Conn
+	key    int64
+	logger *slog.Logger
+
+	lost chan struct{}
+	stop chan struct{}
+	done chan struct{} // closed when watch returns
+	once sync.Once
+}
+
+func (l *lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// watch closes lost once the connection stops answering.
+func (l *lease) watch() {
+	defer close(l.done)
+	ticker := time.NewTicker(leaseCheck)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), leaseCheck)
+			_, err := l.conn.ExecContext(ctx, `SELECT 1`)
+			cancel()
+			if err != nil {
+				l.logger.Warn("lost lock", "lock", l.name, "error", err)
+				close(l.lost)
+				return
+			}
+		}
+	}
+}
+
+func (l *lease) Release() error {
+	var err error
+	l.once.Do(func() {
+		close(l.stop)
+		<-l.done
+
+		ctx, cancel := context.WithTimeout(context.Background(), leaseCheck)
+		defer cancel()
+		if _, err = l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+			err = fmt.Errorf("could not release lock %s: %w", l.name, err)
+			// Discard the connection instead of returning it to the pool,
+			// so the session, and the lock, end with it.
+			l.conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		l.conn.Close()
+	})
+	return err
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/config"
+	"context"
+	"os"
+	"testing"
+)
+
+// Only one store handle holds a lock until it is released. Needs
+// GOSURF_TEST_DATABASE_URL.
+func TestTryLock(t *testing.T) {
+	url := os.Getenv("GOSURF_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("GOSURF_TEST_DATABASE_URL is not set")
+	}
+	cfg := config.Default()
+	cfg.Database.URL = url
+	ctx := context.Background()
+	var stores [2]*Store
+	for i := range stores {
+		st, err := Open(cfg.Database)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { st.Close() })
+		stores[i] = st
+	}
+
+	lease, ok, err := stores[0].TryLock(ctx, "test")
+	if err != nil || !ok {
+		t.Fatalf("first TryLock: %v, %v", ok, err)
+	}
+	if _, ok, err := stores[1].TryLock(ctx, "test"); err != nil || ok {
+		t.Fatalf("second TryLock while held: %v, %v", ok, err)
+	}
+	if err := lease.Release(); err != nil {
+		t.Fatal(err)
+	}
+	lease, ok, err = stores[1].TryLock(ctx, "test")
+	if err != nil || !ok {
+		t.Fatalf("TryLock after release: %v, %v", ok, err)
+	}
+	lease.Release()
+}
@@ -0,0 +1,88 @@
+-- Baseline schema. Uses IF NOT EXISTS so it can be applied to databases
+-- that were created by hand before migrations existed.
+
+CREATE TABLE IF NOT EXISTS buoys (
+	id        integer PRIMARY KEY,
+	name      text NOT NULL,
+	latitude  double precision NOT NULL,
+	longitude double precision NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS cities (
+	id              integer PRIMARY KEY,
+	name            text NOT NULL,
+	latitude        double precision NOT NULL,
+	longitude       double precision NOT NULL,
+	country         text,
+	state           text,
+	county          text,
+	weather_station text
+);
+
+CREATE TABLE IF NOT EXISTS surfspot (
+	id             integer PRIMARY KEY,
+	name           text NOT NULL,
+	latitude       double precision NOT NULL,
+	longitude      double precision NOT NULL,
+	city_id        integer REFERENCES cities (id),
+	break_type     text,
+	orientation    double precision,
+	nearest_buoy   integer REFERENCES buoys (id),
+	tide_region_id integer
+);
+
+CREATE TABLE IF NOT EXISTS tide_data (
+	id               serial PRIMARY KEY,
+	station_name     text,
+	county_name      text,
+	state_code       text,
+	measurement_date date NOT NULL,
+	measurement_time time NOT NULL,
+	water_level      double precision,
+	tidal_state      text,
+	tide_region      text
+);
+
+CREATE TABLE IF NOT EXISTS real_time_buoy_data_points (
+	id               serial PRIMARY KEY,
+	buoy_id          integer NOT NULL,
+	recorded_at      timestamptz NOT NULL,
+	winddir_degt     double precision,
+	windspeed_m_pers double precision,
+	windgust_m_pers  double precision,
+	waveh_m          double precision,
+	domwp_sec        double precision,
+	avgwavep_sec     double precision,
+	meanwavedir_degt double precision,
+	airt_degc        double precision,
+	watert_degc      double precision,
+	inserted_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS current_weather (
+	id             serial PRIMARY KEY,
+	city_id        integer NOT NULL,
+	recorded_at    timestamptz,
+	wind_speed     text,
+	wind_direction double precision,
+	air_temp_c     double precision,
+	precipitation  double precision,
+	cloud_coverage text,
+	observed_at    text
+);
+
+CREATE TABLE IF NOT EXISTS current_surf_spot_conditions (
+	id                 serial PRIMARY KEY,
+	spot_id            integer NOT NULL,
+	recorded_at        timestamptz,
+	dom_swell_height_m double precision,
+	dom_swell_dir      double precision,
+	wind_speed_mph     text,
+	wind_direction     text,
+	air_temp_deg_c     double precision,
+	water_temp_deg_c   double precision,
+	precipitation      double precision,
+	cloud_coverage     text,
+	domwp_sec          double precision,
+	nearest_buoy       integer
+);
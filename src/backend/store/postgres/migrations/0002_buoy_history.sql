@@ -0,0 +1,18 @@
+-- History of every buoy observation, filled by the backfill command from
+-- the 45 days of data in each NDBC realtime2 file.
+
+CREATE TABLE IF NOT EXISTS buoy_data_history (
+	buoy_id          integer NOT NULL,
+	recorded_at      timestamptz NOT NULL,
+	winddir_degt     double precision,
+	windspeed_m_pers double precision,
+	windgust_m_pers  double precision,
+	waveh_m          double precision,
+	domwp_sec        double precision,
+	avgwavep_sec     double precision,
+	meanwavedir_degt double precision,
+	airt_degc        double precision,
+	watert_degc      double precision,
+	inserted_at      timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (buoy_id, recorded_at)
+);
@@ -0,0 +1,15 @@
+-- Hourly NWS forecast periods for each city, replaced on every refresh.
+
+CREATE TABLE IF NOT EXISTS city_forecast (
+	city_id              integer NOT NULL REFERENCES cities (id) ON DELETE CASCADE,
+	start_time           timestamptz NOT NULL,
+	end_time             timestamptz NOT NULL,
+	temperature          integer,
+	temperature_unit     text,
+	wind_speed           text,
+	wind_direction       text,
+	precipitation_chance double precision,
+	short_forecast       text,
+	updated_at           timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (city_id, start_time)
+);
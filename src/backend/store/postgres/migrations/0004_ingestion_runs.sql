@@ -0,0 +1,16 @@
+-- Audit log of every ingestion run. A row is written when the run starts
+-- (status 'running') and completed when it finishes.
+
+CREATE TABLE IF NOT EXISTS ingestion_runs (
+	id           bigserial PRIMARY KEY,
+	source       text NOT NULL,
+	status       text NOT NULL,
+	started_at   timestamptz NOT NULL,
+	finished_at  timestamptz,
+	rows_written integer NOT NULL DEFAULT 0,
+	failures     jsonb NOT NULL DEFAULT '{}',
+	error        text
+);
+
+CREATE INDEX IF NOT EXISTS ingestion_runs_source_started_idx
+	ON ingestion_runs (source, started_at DESC);
@@ -0,0 +1,24 @@
+-- Current-data tables hold one row per buoy, city and spot so refreshes
+-- can upsert by key instead of truncating. Keep only the newest row for
+-- any key that was duplicated before the constraint existed.
+
+DELETE FROM real_time_buoy_data_points a
+USING real_time_buoy_data_points b
+WHERE a.buoy_id = b.buoy_id AND a.id < b.id;
+
+DELETE FROM current_weather a
+USING current_weather b
+WHERE a.city_id = b.city_id AND a.id < b.id;
+
+DELETE FROM current_surf_spot_conditions a
+USING current_surf_spot_conditions b
+WHERE a.spot_id = b.spot_id AND a.id < b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS real_time_buoy_data_points_buoy_id_key
+	ON real_time_buoy_data_points (buoy_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS current_weather_city_id_key
+	ON current_weather (city_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS current_surf_spot_conditions_spot_id_key
+	ON current_surf_spot_conditions (spot_id);
@@ -0,0 +1,15 @@
+-- Quality control flags of each observation, keyed by field name. Values
+-- that failed a check are kept as reported in the observation tables and
+-- left out of current_surf_spot_conditions.
+
+ALTER TABLE real_time_buoy_data_points
+	ADD COLUMN IF NOT EXISTS qc_flags jsonb NOT NULL DEFAULT '{}';
+
+ALTER TABLE buoy_data_history
+	ADD COLUMN IF NOT EXISTS qc_flags jsonb NOT NULL DEFAULT '{}';
+
+ALTER TABLE current_weather
+	ADD COLUMN IF NOT EXISTS qc_flags jsonb NOT NULL DEFAULT '{}';
+
+ALTER TABLE current_surf_spot_conditions
+	ADD COLUMN IF NOT EXISTS qc_flags jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,15 @@
+-- Where each observation came from, so conditions responses can say which
+-- buoy and weather station they used, how far away they are and how old
+-- their data is.
+
+ALTER TABLE current_weather
+	ADD COLUMN IF NOT EXISTS station           text,
+	ADD COLUMN IF NOT EXISTS station_latitude  double precision,
+	ADD COLUMN IF NOT EXISTS station_longitude double precision;
+
+ALTER TABLE current_surf_spot_conditions
+	ADD COLUMN IF NOT EXISTS buoy_distance_km    double precision,
+	ADD COLUMN IF NOT EXISTS buoy_recorded_at    timestamptz,
+	ADD COLUMN IF NOT EXISTS weather_station     text,
+	ADD COLUMN IF NOT EXISTS weather_distance_km double precision,
+	ADD COLUMN IF NOT EXISTS weather_recorded_at timestamptz;
@@ -0,0 +1,12 @@
+-- Raw upstream payloads, gzip compressed, kept so they can be parsed
+-- again by the reprocess command.
+
+CREATE TABLE IF NOT EXISTS raw_payloads (
+	id         bigserial PRIMARY KEY,
+	source     text NOT NULL,
+	key        text NOT NULL,
+	fetched_at timestamptz NOT NULL,
+	body       bytea NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS raw_payloads_fetched_at_idx ON raw_payloads (fetched_at);
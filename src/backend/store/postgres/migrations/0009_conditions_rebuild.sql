@@ -0,0 +1,7 @@
+-- Numbers each rebuild of the current conditions, so the spot stream can
+-- tell clients reconnecting which rows they have not seen.
+
+CREATE SEQUENCE IF NOT EXISTS conditions_rebuild_seq;
+
+ALTER TABLE current_surf_spot_conditions
+	ADD COLUMN IF NOT EXISTS rebuild bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/events"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// notifyChannel is the channel changes are announced on.
+const notifyChannel = "gosurf_changes"
+
+// maxNotifyPayload keeps payloads under Postgres' 8000 byte limit.
+const maxNotifyPayload = 7900
+
+// listenerPing is how often Listen checks an idle connection.
+const listenerPing = 90 * time.Second
+
+// notification is the JSON payload sent on notifyChannel.
+type notification struct {
+	Origin string    `json:"origin"`
+	Source string    `json:"source"`
+	IDs    []int     `json:"ids,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Notify announces e to the other processes using the database. Events
+// that came from elsewhere are not sent on.
+func (s *Store) Notify(ctx context.Context, e events.Event) error {
+	if e.Origin != "" {
+		return nil
+	}
+	n := notification{Origin: s.instance, Source: e.Source, IDs: e.IDs, At: e.At}
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("could not encode notification: %w", err)
+	}
+	// Without IDs the listeners treat all of the source's data as changed.
+	if len(payload) > maxNotifyPayload {
+		n.IDs = nil
+		if payload, err = json.Marshal(n); err != nil {
+			return fmt.Errorf("could not encode notification: %w", err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, string(payload)); err != nil {
+		return fmt.Errorf("could not notify %s change: %w", e.Source, err)
+	}
+	return nil
+}
+
+// Listen publishes the changes other processes announce with Notify on
+// bus until ctx is done. Notifications sent while the listener was
+// reconnecting are lost, so after a reconnect it publishes an event
+// without IDs for every source.
+func (s *Store) Listen(ctx context.Context, bus *events.Bus) error {
+	l := pq.NewListener(s.connString, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			s.logger.Warn("database listener", "event", ev, "error", err)
+		}
+	})
+	defer l.Close()
+	if err := l.Listen(notifyChannel); err != nil {
+		return fmt.Errorf("could not listen for changes: %w", err)
+	}
+
+	ping := time.NewTicker(listenerPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-l.Notify:
+			if n == nil {
+				for _, source := range events.Sources {
+					bus.Publish(events.Event{Source: source, At: time.Now(), Origin: notifyChannel})
+				}
+				continue
+			}
+			var msg notification
+			if err := json.Unmarshal([]byte(n.Extra), &msg); err != nil {
+				s.logger.Warn("could not decode change notification", "payload", n.Extra, "error", err)
+				continue
+			}
+			if msg.Origin == s.instance {
+				continue
+			}
+			bus.Publish(events.Event{Source: msg.Source, IDs: msg.IDs, At: msg.At, Origin: msg.Origin})
+		case <-ping.C:
+			go l.Ping()
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/events"
+	"Go_surf_redesign/src/config"
+	"context"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// A change announced by one store handle is published on the bus of a
+// listener using another, while the listener's own announcements are not.
+// Needs GOSURF_TEST_DATABASE_URL.
+func TestNotifyListen(t *testing.T) {
+	url := os.Getenv("GOSURF_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("GOSURF_TEST_DATABASE_URL is not set")
+	}
+	cfg := config.Default()
+	cfg.Database.URL = url
+	open := func() *Store {
+		st, err := Open(cfg.Database)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { st.Close() })
+		return st
+	}
+	listener, sender := open(), open()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	bus := events.NewBus()
+	got := make(chan events.Event, 16)
+	bus.Subscribe(func(e events.Event) { got <- e })
+	go listener.Listen(ctx, bus)
+
+	// The listener may not be subscribed yet, so keep announcing until
+	// the change arrives.
+	retry := time.NewTicker(100 * time.Millisecond)
+	defer retry.Stop()
+	for {
+		if err := listener.Notify(ctx, events.Event{Source: events.SourceTides, At: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		if err := sender.Notify(ctx, events.Event{Source: events.SourceConditions, IDs: []int{3, 4}, At: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case e := <-got:
+			if e.Source == events.SourceTides {
+				t.Fatal("the listener's own change was published")
+			}
+			if e.Source != events.SourceConditions || !slices.Equal(e.IDs, []int{3, 4}) || e.Origin != sender.instance {
+				t.Fatalf("got %+v", e)
+			}
+			return
+		case <-retry.C:
+		case <-ctx.Done():
+			t.Fatal("no change arrived")
+		}
+	}
+}
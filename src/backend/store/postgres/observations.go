@@ -0,0 +1,241 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/metrics"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var buoyObservationColumns = []string{
+	"buoy_id",
+	"recorded_at",
+	"winddir_degt",
+	"windspeed_m_pers",
+	"windgust_m_pers",
+	"waveh_m",
+	"domwp_sec",
+	"avgwavep_sec",
+	"meanwavedir_degt",
+	"airt_degc",
+	"watert_degc",
+	"inserted_at",
+	"qc_flags",
+}
+
+// buoyObservationRow returns the values of obs in buoyObservationColumns
+// order.
+func buoyObservationRow(obs store.BuoyObservation) []any {
+	return []any{
+		obs.BuoyID,
+		obs.RecordedAt,
+		obs.WindDirectionDegT,
+		obs.WindSpeedMetersPerSec,
+		obs.WindGustMetersPerSec,
+		obs.WaveHeightM,
+		obs.DominantWavePeriodSec,
+		obs.AvgWavePeriodSec,
+		obs.MeanWaveDirectionDegT,
+		obs.AirTempDegC,
+		obs.WaterTempDegC,
+		obs.InsertedAt,
+		obs.QC,
+	}
+}
+
+// SaveBuoyObservations upserts obs into real_time_buoy_data_points and
+// drops the rows of removed buoys in one transaction.
+func (s *Store) SaveBuoyObservations(ctx context.Context, obs []store.BuoyObservation) error {
+	defer metrics.ObserveQuery("save_buoy_observations", time.Now())
+	rows := make([][]any, len(obs))
+	for i, o := range obs {
+		rows[i] = buoyObservationRow(o)
+	}
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := insertRows(ctx, tx, "real_time_buoy_data_points", buoyObservationColumns,
+			"ON CONFLICT (buoy_id) DO UPDATE SET "+excludedSet(buoyObservationColumns[1:]), rows)
+		if err != nil {
+			return fmt.Errorf("could not upsert buoy observations: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM real_time_buoy_data_points
+			WHERE buoy_id NOT IN (SELECT id FROM buoys)
+		`)
+		return err
+	})
+}
+
+func (s *Store) LatestBuoyObservations(ctx context.Context) ([]store.BuoyObservation, error) {
+	defer metrics.ObserveQuery("latest_buoy_observations", time.Now())
+	rows, err := s.db.QueryContext(ctx, `SELECT `+strings.Join(buoyObservationColumns, ", ")+` FROM real_time_buoy_data_points`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var observations []store.BuoyObservation
+	for rows.Next() {
+		var o store.BuoyObservation
+		if err := rows.Scan(
+			&o.BuoyID,
+			&o.RecordedAt,
+			&o.WindDirectionDegT,
+			&o.WindSpeedMetersPerSec,
+			&o.WindGustMetersPerSec,
+			&o.WaveHeightM,
+			&o.DominantWavePeriodSec,
+			&o.AvgWavePeriodSec,
+			&o.MeanWaveDirectionDegT,
+			&o.AirTempDegC,
+			&o.WaterTempDegC,
+			&o.InsertedAt,
+			&o.QC,
+		); err != nil {
+			return nil, err
+		}
+		observations = append(observations, o)
+	}
+	return observations, rows.Err()
+}
+
+// AddBuoyHistory copies obs into a staging table and moves them into
+// buoy_data_history in one transaction, skipping rows that are already
+// stored.
+func (s *Store) AddBuoyHistory(ctx context.Context, obs []store.BuoyObservation) (int, error) {
+	defer metrics.ObserveQuery("add_buoy_history", time.Now())
+	rows := make([][]any, len(obs))
+	for i, o := range obs {
+		rows[i] = buoyObservationRow(o)
+	}
+
+	var written int64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			CREATE TEMP TABLE buoy_history_load
+			(LIKE buoy_data_history INCLUDING DEFAULTS)
+			ON COMMIT DROP
+		`)
+		if err != nil {
+			return fmt.Errorf("could not create staging table: %w", err)
+		}
+		if err := copyRows(ctx, tx, "buoy_history_load", buoyObservationColumns, rows); err != nil {
+			return err
+		}
+
+		columns := strings.Join(buoyObservationColumns, ", ")
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO buoy_data_history (`+columns+`)
+			SELECT `+columns+` FROM buoy_history_load
+			ON CONFLICT (buoy_id, recorded_at) DO NOTHING
+		`)
+		if err != nil {
+			return fmt.Errorf("could not insert buoy history: %w", err)
+		}
+		written, _ = res.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(written), nil
+}
+
+var weatherObservationColumns = []string{
+	"city_id",
+	"recorded_at",
+	"wind_speed",
+	"wind_direction",
+	"air_temp_c",
+	"precipitation",
+	"cloud_coverage",
+	"observed_at",
+	"qc_flags",
+	"station",
+	"station_latitude",
+	"station_longitude",
+}
+
+// SaveWeatherObservations upserts obs into current_weather and drops the
+// rows of removed cities in one transaction.
+func (s *Store) SaveWeatherObservations(ctx context.Context, obs []store.WeatherObservation) error {
+	defer metrics.ObserveQuery("save_weather_observations", time.Now())
+	rows := make([][]any, len(obs))
+	for i, o := range obs {
+		rows[i] = []any{
+			o.CityID,
+			o.RecordedAt,
+			o.WindSpeedMph,
+			o.WindDirection,
+			o.AirTempC,
+			o.Precipitation,
+			o.CloudCoverage,
+			o.ObservedAt,
+			o.QC,
+			o.Station,
+			o.StationLatitude,
+			o.StationLongitude,
+		}
+	}
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := insertRows(ctx, tx, "current_weather", weatherObservationColumns,
+			"ON CONFLICT (city_id) DO UPDATE SET "+excludedSet(weatherObservationColumns[1:]), rows)
+		if err != nil {
+			return fmt.Errorf("could not upsert weather observations: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM current_weather
+			WHERE city_id NOT IN (SELECT id FROM cities)
+		`)
+		return err
+	})
+}
+
+func (s *Store) LatestWeatherObservations(ctx context.Context) ([]store.WeatherObservation, error) {
+	defer metrics.ObserveQuery("latest_weather_observations", time.Now())
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			city_id,
+			COALESCE(recorded_at, 'epoch'),
+			COALESCE(wind_speed, ''),
+			wind_direction,
+			air_temp_c,
+			precipitation,
+			COALESCE(cloud_coverage, ''),
+			COALESCE(observed_at, ''),
+			qc_flags,
+			COALESCE(station, ''),
+			station_latitude,
+			station_longitude
+		FROM current_weather
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var observations []store.WeatherObservation
+	for rows.Next() {
+		var o store.WeatherObservation
+		if err := rows.Scan(
+			&o.CityID,
+			&o.RecordedAt,
+			&o.WindSpeedMph,
+			&o.WindDirection,
+			&o.AirTempC,
+			&o.Precipitation,
+			&o.CloudCoverage,
+			&o.ObservedAt,
+			&o.QC,
+			&o.Station,
+			&o.StationLatitude,
+			&o.StationLongitude,
+		); err != nil {
+			return nil, err
+		}
+		observations = append(observations, o)
+	}
+	return observations, rows.Err()
+}
@@ -0,0 +1,133 @@
+// Package postgres implements the store repositories on Postgres.
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/metrics"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/config"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const psqlDriver = "postgres"
+
+// Store is a store.Store backed by a Postgres database.
+type Store struct {
+	db         *sql.DB
+	connString string
+	// instance identifies this handle in change notifications.
+	instance string
+	logger   *slog.Logger
+}
+
+var (
+	_ store.Store    = (*Store)(nil)
+	_ store.Migrator = (*Store)(nil)
+)
+
+// Open opens a database handle using the connection settings in cfg.
+// The connection is not verified until Ping is called.
+func Open(cfg config.DatabaseConfig) (*Store, error) {
+	connString := cfg.ConnString()
+	db, err := sql.Open(psqlDriver, connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate database client: %w", err)
+	}
+	return &Store{
+		db:         db,
+		connString: connString,
+		instance:   rand.Text(),
+		logger:     slog.Default().With("component", "postgres"),
+	}, nil
+}
+
+// DB returns the underlying database handle.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *Store) Close() error {
+	s.logger.Info("database disconnected")
+	return s.db.Close()
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so helpers can run
+// inside or outside a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// withTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise. Readers keep seeing the previous rows until the commit,
+// so a refresh is never observed half-built.
+func (s *Store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// statusTables lists the tables reported by Status and the column holding
+// each table's observation time, if any.
+var statusTables = []struct {
+	table  string
+	column string
+}{
+	{"cities", ""},
+	{"buoys", ""},
+	{"surfspot", ""},
+	{"tide_data", ""},
+	{"real_time_buoy_data_points", "recorded_at"},
+	{"current_weather", "recorded_at"},
+	{"current_surf_spot_conditions", "recorded_at"},
+	{"buoy_data_history", "recorded_at"},
+	{"ingestion_runs", "started_at"},
+	{"raw_payloads", "fetched_at"},
+}
+
+// Status returns the row count and newest observation time of each table.
+func (s *Store) Status(ctx context.Context) ([]store.TableStatus, error) {
+	defer metrics.ObserveQuery("status", time.Now())
+	var statuses []store.TableStatus
+	for _, t := range statusTables {
+		status := store.TableStatus{Table: t.table}
+
+		query := fmt.Sprintf(`SELECT count(*) FROM %s`, t.table)
+		if t.column != "" {
+			query = fmt.Sprintf(`SELECT count(*), max(%s) FROM %s`, t.column, t.table)
+		}
+
+		var newest sql.NullTime
+		dest := []any{&status.Rows}
+		if t.column != "" {
+			dest = append(dest, &newest)
+		}
+		if err := s.db.QueryRowContext(ctx, query).Scan(dest...); err != nil {
+			return nil, fmt.Errorf("could not read status of %s: %w", t.table, err)
+		}
+		if newest.Valid {
+			status.Newest = &newest.Time
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
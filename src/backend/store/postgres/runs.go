@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/metrics"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// StartRun inserts a running ingestion_runs row and returns its id.
+func (s *Store) StartRun(ctx context.Context, source string, startedAt time.Time) (int64, error) {
+	defer metrics.ObserveQuery("start_run", time.Now())
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO ingestion_runs (source, status, started_at)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, source, store.RunRunning, startedAt).Scan(&id)
+	return id, err
+}
+
+// FinishRun completes the ingestion_runs row of run.ID.
+func (s *Store) FinishRun(ctx context.Context, run store.IngestionRun) error {
+	defer metrics.ObserveQuery("finish_run", time.Now())
+	failures := run.Failures
+	if failures == nil {
+		failures = map[string]string{}
+	}
+	failuresJSON, err := json.Marshal(failures)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE ingestion_runs
+		SET status = $2, finished_at = $3, rows_written = $4, failures = $5, error = $6
+		WHERE id = $1
+	`, run.ID, run.Status, run.FinishedAt, run.RowsWritten, failuresJSON, run.Error)
+	return err
+}
+
+func (s *Store) RecentRuns(ctx context.Context, limit int) ([]store.IngestionRun, error) {
+	defer metrics.ObserveQuery("recent_runs", time.Now())
+	return s.queryRuns(ctx, `
+		SELECT id, source, status, started_at, finished_at, rows_written, failures, error
+		FROM ingestion_runs
+		ORDER BY started_at DESC
+		LIMIT $1
+	`, limit)
+}
+
+func (s *Store) LatestRuns(ctx context.Context) ([]store.IngestionRun, error) {
+	defer metrics.ObserveQuery("latest_runs", time.Now())
+	return s.queryRuns(ctx, `
+		SELECT DISTINCT ON (source) id, source, status, started_at, finished_at, rows_written, failures, error
+		FROM ingestion_runs
+		ORDER BY source, started_at DESC
+	`)
+}
+
+// RunStats summarises ingestion_runs by source.
+func (s *Store) RunStats(ctx context.Context, since time.Time) ([]store.SourceStats, error) {
+	defer metrics.ObserveQuery("run_stats", time.Now())
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			source,
+			max(finished_at) FILTER (WHERE status IN ($2, $3)),
+			count(*) FILTER (WHERE started_at > $1),
+			count(*) FILTER (WHERE started_at > $1 AND status = $4)
+		FROM ingestion_runs
+		GROUP BY source
+		ORDER BY source
+	`, since, store.RunOK, store.RunPartial, store.RunFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []store.SourceStats
+	for rows.Next() {
+		var st store.SourceStats
+		var lastSuccess sql.NullTime
+		if err := rows.Scan(&st.Source, &lastSuccess, &st.Runs, &st.Failures); err != nil {
+			return nil, err
+		}
+		if lastSuccess.Valid {
+			st.LastSuccessAt = &lastSuccess.Time
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// queryRuns scans ingestion_runs rows selected by query.
+func (s *Store) queryRuns(ctx context.Context, query string, args ...any) ([]store.IngestionRun, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []store.IngestionRun
+	for rows.Next() {
+		var run store.IngestionRun
+		var finishedAt sql.NullTime
+		var failures []byte
+		if err := rows.Scan(
+			&run.ID,
+			&run.Source,
+			&run.Status,
+			&run.StartedAt,
+			&finishedAt,
+			&run.RowsWritten,
+			&failures,
+			&run.Error,
+		); err != nil {
+			return nil, err
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		if err := json.Unmarshal(failures, &run.Failures); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
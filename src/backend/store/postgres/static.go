@@ -0,0 +1,496 @@
+package postgres
+
+import (
+	"Go_surf_redesign/src/backend/metrics"
+	"Go_surf_redesign/src/backend/spacial"
+	"Go_surf_redesign/src/backend/store"
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ReplaceStatic upserts the given tables by id and deletes their unlisted
+// rows in a single transaction, so the tables always agree with each other.
+func (s *Store) ReplaceStatic(ctx context.Context, data store.StaticData) error {
+	defer metrics.ObserveQuery("replace_static", time.Now())
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if data.Buoys != nil {
+			if err := writeBuoys(ctx, tx, data.Buoys); err != nil {
+				return fmt.Errorf("buoys: %w", err)
+			}
+			if err := deleteUnlistedBuoys(ctx, tx, data.Buoys); err != nil {
+				return err
+			}
+		}
+		if data.Cities != nil {
+			if err := writeCities(ctx, tx, data.Cities); err != nil {
+				return fmt.Errorf("cities: %w", err)
+			}
+		}
+		if data.Spots != nil {
+			if err := writeSurfSpots(ctx, tx, data.Spots); err != nil {
+				return fmt.Errorf("surf spots: %w", err)
+			}
+		}
+		if data.Cities != nil {
+			if err := deleteUnlistedCities(ctx, tx, data.Cities); err != nil {
+				return err
+			}
+		}
+		if data.Buoys != nil || data.Spots != nil {
+			if err := refreshNearestBuoys(ctx, tx); err != nil {
+				return err
+			}
+		}
+		if data.Tides != nil {
+			if err := writeTides(ctx, tx, data.Tides); err != nil {
+				return fmt.Errorf("tides: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) Buoys(ctx context.Context) ([]store.Buoy, error) {
+	defer metrics.ObserveQuery("buoys", time.Now())
+	return queryBuoys(ctx, s.db)
+}
+
+func queryBuoys(ctx context.Context, q querier) ([]store.Buoy, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, name, latitude, longitude FROM buoys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buoys []store.Buoy
+	for rows.Next() {
+		var b store.Buoy
+		if err := rows.Scan(&b.ID, &b.Name, &b.Latitude, &b.Longitude); err != nil {
+			return nil, err
+		}
+		buoys = append(buoys, b)
+	}
+	return buoys, rows.Err()
+}
+
+func (s *Store) Cities(ctx context.Context) ([]store.City, error) {
+	defer metrics.ObserveQuery("cities", time.Now())
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, latitude, longitude, COALESCE(country, ''), COALESCE(state, ''), COALESCE(county, ''), weather_station
+		FROM cities
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cities []store.City
+	for rows.Next() {
+		var c store.City
+		if err := rows.Scan(&c.ID, &c.Name, &c.Latitude, &c.Longitude, &c.Country, &c.State, &c.County, &c.WeatherStation); err != nil {
+			return nil, err
+		}
+		cities = append(cities, c)
+	}
+	return cities, rows.Err()
+}
+
+// cityFilter matches the cities c of a ListFilter given as $1 state, $2
+// county and $3 has_tide_station.
+const cityFilter = `
+	($1 = '' OR lower(c.state) = lower($1))
+	AND ($2 = '' OR lower(c.county) = lower($2))
+	AND ($3::boolean IS NULL OR EXISTS (
+		SELECT 1 FROM surfspot s WHERE s.city_id = c.id AND COALESCE(s.tide_region_id, 0) <> 0
+	) = $3)
+`
+
+func (s *Store) ListCities(ctx context.Context, f store.ListFilter, page store.Keyset) ([]store.City, int, error) {
+	defer metrics.ObserveQuery("list_cities", time.Now())
+	args := []any{f.State, f.County, f.HasTideStation}
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM cities c WHERE `+cityFilter, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("could not count cities: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.id, c.name, c.latitude, c.longitude, COALESCE(c.country, ''), COALESCE(c.state, ''),
+			COALESCE(c.county, ''), c.weather_station
+		FROM cities c
+		WHERE `+cityFilter+` AND (c.name, c.id) > ($4, $5)
+		ORDER BY c.name, c.id
+		LIMIT NULLIF($6, 0)
+	`, append(args, page.AfterName, page.AfterID, page.Limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not list cities: %w", err)
+	}
+	defer rows.Close()
+
+	var cities []store.City
+	for rows.Next() {
+		var c store.City
+		if err := rows.Scan(&c.ID, &c.Name, &c.Latitude, &c.Longitude, &c.Country, &c.State, &c.County, &c.WeatherStation); err != nil {
+			return nil, 0, err
+		}
+		cities = append(cities, c)
+	}
+	return cities, total, rows.Err()
+}
+
+// spotFilter matches the spots s, joined to their cities c, of a
+// ListFilter given as $1 city id, $2 state, $3 county, $4 break type and
+// $5 has_tide_station.
+const spotFilter = `
+	($1 = 0 OR s.city_id = $1)
+	AND ($2 = '' OR lower(c.state) = lower($2))
+	AND ($3 = '' OR lower(c.county) = lower($3))
+	AND ($4 = '' OR lower(s.break_type) = lower($4))
+	AND ($5::boolean IS NULL OR (COALESCE(s.tide_region_id, 0) <> 0) = $5)
+`
+
+func (s *Store) ListSpots(ctx context.Context, f store.ListFilter, page store.Keyset) ([]store.Spot, int, error) {
+	defer metrics.ObserveQuery("list_spots", time.Now())
+	args := []any{f.CityID, f.State, f.County, f.BreakType, f.HasTideStation}
+	var total int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT count(*) FROM surfspot s LEFT JOIN cities c ON c.id = s.city_id WHERE `+spotFilter,
+		args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not count spots: %w", err)
+	}
+
+	spots, err := s.querySpots(ctx, `
+		SELECT `+spotColumns+`
+		FROM surfspot s
+		LEFT JOIN cities c ON c.id = s.city_id
+		WHERE `+spotFilter+` AND (s.name, s.id) > ($6, $7)
+		ORDER BY s.name, s.id
+		LIMIT NULLIF($8, 0)
+	`, append(args, page.AfterName, page.AfterID, page.Limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not list spots: %w", err)
+	}
+	return spots, total, nil
+}
+
+// spotColumns selects the surfspot row aliased s in the order spotFields
+// expects.
+const spotColumns = `
+	s.id, s.name, s.latitude, s.longitude, COALESCE(s.city_id, 0), COALESCE(s.break_type, ''),
+	COALESCE(s.orientation, 0), COALESCE(s.nearest_buoy, 0), COALESCE(s.tide_region_id, 0)
+`
+
+// spotFields returns the scan destinations of spotColumns.
+func spotFields(sp *store.Spot) []any {
+	return []any{
+		&sp.ID,
+		&sp.Name,
+		&sp.Latitude,
+		&sp.Longitude,
+		&sp.CityID,
+		&sp.BreakType,
+		&sp.Orientation,
+		&sp.NearestBuoy,
+		&sp.TideRegion,
+	}
+}
+
+func (s *Store) Spots(ctx context.Context) ([]store.Spot, error) {
+	defer metrics.ObserveQuery("spots", time.Now())
+	return s.querySpots(ctx, `SELECT `+spotColumns+` FROM surfspot s ORDER BY s.id`)
+}
+
+func (s *Store) SpotsByCity(ctx context.Context, cityID int) ([]store.Spot, error) {
+	defer metrics.ObserveQuery("spots_by_city", time.Now())
+	return s.querySpots(ctx, `SELECT `+spotColumns+` FROM surfspot s WHERE s.city_id = $1 ORDER BY s.id`, cityID)
+}
+
+func (s *Store) TidesOn(ctx context.Context, date string) ([]store.TidePrediction, error) {
+	defer metrics.ObserveQuery("tides_on", time.Now())
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT COALESCE(station_name, ''), COALESCE(county_name, ''), COALESCE(state_code, ''),
+			to_char(measurement_date, 'YYYY-MM-DD'), to_char(measurement_time, 'HH24:MI'),
+			COALESCE(water_level, 0), COALESCE(tidal_state, ''), COALESCE(tide_region, '')
+		FROM tide_data
+		WHERE measurement_date = $1
+		ORDER BY tide_region, station_name, measurement_time
+	`, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tides []store.TidePrediction
+	for rows.Next() {
+		var t store.TidePrediction
+		err := rows.Scan(&t.StationName, &t.CountyName, &t.StateCode, &t.Date, &t.Time, &t.WaterLevel, &t.TidalState, &t.TideRegion)
+		if err != nil {
+			return nil, err
+		}
+		tides = append(tides, t)
+	}
+	return tides, rows.Err()
+}
+
+func (s *Store) querySpots(ctx context.Context, query string, args ...any) ([]store.Spot, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spots []store.Spot
+	for rows.Next() {
+		var sp store.Spot
+		if err := rows.Scan(spotFields(&sp)...); err != nil {
+			return nil, err
+		}
+		spots = append(spots, sp)
+	}
+	return spots, rows.Err()
+}
+
+func writeBuoys(ctx context.Context, q querier, buoys []store.Buoy) error {
+	sqlStmnt, err := q.PrepareContext(ctx, `
+		INSERT INTO buoys (id, name, latitude, longitude)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude
+	`)
+	if err != nil {
+		return fmt.Errorf("could not prepare statement: %w", err)
+	}
+	defer sqlStmnt.Close()
+
+	for _, b := range buoys {
+		if _, err := sqlStmnt.ExecContext(ctx, b.ID, b.Name, b.Latitude, b.Longitude); err != nil {
+			return fmt.Errorf("could not update buoy %d: %w", b.ID, err)
+		}
+	}
+	return nil
+}
+
+func writeCities(ctx context.Context, q querier, cities []store.City) error {
+	sqlStmnt, err := q.PrepareContext(ctx, `
+		INSERT INTO cities (id, name, latitude, longitude, country, state, county, weather_station)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude,
+			country = EXCLUDED.country,
+			state = EXCLUDED.state,
+			county = EXCLUDED.county,
+			weather_station = COALESCE(EXCLUDED.weather_station, cities.weather_station)
+	`)
+	if err != nil {
+		return fmt.Errorf("could not prepare statement: %w", err)
+	}
+	defer sqlStmnt.Close()
+
+	for _, city := range cities {
+		_, err := sqlStmnt.ExecContext(ctx,
+			city.ID,
+			city.Name,
+			city.Latitude,
+			city.Longitude,
+			city.Country,
+			city.State,
+			city.County,
+			city.WeatherStation,
+		)
+		if err != nil {
+			return fmt.Errorf("could not update city %d: %w", city.ID, err)
+		}
+	}
+	return nil
+}
+
+// writeSurfSpots upserts spots and deletes spots that are no longer
+// listed. Nearest buoys are assigned afterwards by refreshNearestBuoys.
+func writeSurfSpots(ctx context.Context, q querier, spots []store.Spot) error {
+	sqlStmnt, err := q.PrepareContext(ctx, `
+		INSERT INTO surfspot (id, name, latitude, longitude, city_id, break_type, orientation, tide_region_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude,
+			city_id = EXCLUDED.city_id,
+			break_type = EXCLUDED.break_type,
+			orientation = EXCLUDED.orientation,
+			tide_region_id = EXCLUDED.tide_region_id
+	`)
+	if err != nil {
+		return fmt.Errorf("could not prepare statement: %w", err)
+	}
+	defer sqlStmnt.Close()
+
+	ids := make([]int64, 0, len(spots))
+	for _, spot := range spots {
+		_, err := sqlStmnt.ExecContext(ctx,
+			spot.ID,
+			spot.Name,
+			spot.Latitude,
+			spot.Longitude,
+			spot.CityID,
+			spot.BreakType,
+			spot.Orientation,
+			spot.TideRegion,
+		)
+		if err != nil {
+			return fmt.Errorf("could not update surf spot %d: %w", spot.ID, err)
+		}
+		ids = append(ids, int64(spot.ID))
+	}
+
+	_, err = q.ExecContext(ctx, `DELETE FROM surfspot WHERE NOT (id = ANY($1))`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("could not delete unlisted surf spots: %w", err)
+	}
+	return nil
+}
+
+// refreshNearestBuoys reassigns every surf spot's nearest buoy from the
+// buoys visible in q.
+func refreshNearestBuoys(ctx context.Context, q querier) error {
+	buoys, err := queryBuoys(ctx, q)
+	if err != nil {
+		return fmt.Errorf("could not read buoys: %w", err)
+	}
+	locations := make([]spacial.Location, len(buoys))
+	for i, b := range buoys {
+		locations[i] = spacial.Location{ID: b.ID, Latitude: b.Latitude, Longitude: b.Longitude}
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT id, latitude, longitude FROM surfspot`)
+	if err != nil {
+		return err
+	}
+	type spotLocation struct {
+		id       int
+		lat, lon float64
+	}
+	var spots []spotLocation
+	for rows.Next() {
+		var s spotLocation
+		if err := rows.Scan(&s.id, &s.lat, &s.lon); err != nil {
+			rows.Close()
+			return err
+		}
+		spots = append(spots, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range spots {
+		nearestBuoy := spacial.NearestBuoy(s.lat, s.lon, locations)
+		if _, err := q.ExecContext(ctx, `UPDATE surfspot SET nearest_buoy = $2 WHERE id = $1`, s.id, nullID(nearestBuoy)); err != nil {
+			return fmt.Errorf("could not update nearest buoy of spot %d: %w", s.id, err)
+		}
+	}
+	return nil
+}
+
+// nullID stores a zero id as NULL.
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// deleteUnlistedBuoys removes buoys missing from buoys. Surf spots that
+// pointed at a removed buoy are left without one until their nearest buoy
+// is recomputed.
+func deleteUnlistedBuoys(ctx context.Context, q querier, buoys []store.Buoy) error {
+	ids := make([]int64, len(buoys))
+	for i, b := range buoys {
+		ids[i] = int64(b.ID)
+	}
+	_, err := q.ExecContext(ctx, `
+		UPDATE surfspot SET nearest_buoy = NULL
+		WHERE NOT (nearest_buoy = ANY($1))
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("could not detach unlisted buoys: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM buoys WHERE NOT (id = ANY($1))`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("could not delete unlisted buoys: %w", err)
+	}
+	return nil
+}
+
+// deleteUnlistedCities removes cities missing from cities. It fails if a
+// surf spot still belongs to one of them.
+func deleteUnlistedCities(ctx context.Context, q querier, cities []store.City) error {
+	ids := make([]int64, len(cities))
+	for i, city := range cities {
+		ids[i] = int64(city.ID)
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM cities WHERE NOT (id = ANY($1))`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("could not delete unlisted cities: %w", err)
+	}
+	return nil
+}
+
+var tideColumns = []string{
+	"station_name",
+	"county_name",
+	"state_code",
+	"measurement_date",
+	"measurement_time",
+	"water_level",
+	"tidal_state",
+	"tide_region",
+}
+
+func (s *Store) ReplaceStationTides(ctx context.Context, tides []store.TidePrediction) error {
+	defer metrics.ObserveQuery("replace_station_tides", time.Now())
+	stations := make(map[string]bool)
+	for _, t := range tides {
+		stations[t.StationName] = true
+	}
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM tide_data WHERE station_name = ANY($1)`,
+			pq.Array(slices.Collect(maps.Keys(stations))))
+		if err != nil {
+			return fmt.Errorf("could not clear station tides: %w", err)
+		}
+		return copyTides(ctx, tx, tides)
+	})
+}
+
+// writeTides replaces tide_data with tides.
+func writeTides(ctx context.Context, tx *sql.Tx, tides []store.TidePrediction) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tide_data`); err != nil {
+		return fmt.Errorf("could not clear tide_data: %w", err)
+	}
+	return copyTides(ctx, tx, tides)
+}
+
+// copyTides adds tides to tide_data, streaming the predictions with COPY.
+func copyTides(ctx context.Context, tx *sql.Tx, tides []store.TidePrediction) error {
+	rows := make([][]any, len(tides))
+	for i, t := range tides {
+		rows[i] = []any{
+			t.StationName,
+			t.CountyName,
+			t.StateCode,
+			t.Date,
+			t.Time,
+			t.WaterLevel,
+			t.TidalState,
+			t.TideRegion,
+		}
+	}
+	return copyRows(ctx, tx, "tide_data", tideColumns, rows)
+}
@@ -0,0 +1,303 @@
+// Package store defines the repositories ingestion and the API read and
+// write through. store/postgres implements them on Postgres and
+// store/memory in process, so the server and tests can run without a
+// database.
+package store
+
+import (
+	"Go_surf_redesign/src/backend/models"
+	"Go_surf_redesign/src/backend/qc"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+type Buoy struct {
+	ID        int
+	Name      string
+	Latitude  float64
+	Longitude float64
+}
+
+type City struct {
+	ID        int
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Country   string
+	State     string
+	County    string
+	// WeatherStation is the city's observation station. When saving, nil
+	// keeps the station already stored.
+	WeatherStation *string
+}
+
+type Spot struct {
+	ID          int
+	Name        string
+	Latitude    float64
+	Longitude   float64
+	CityID      int
+	BreakType   string
+	Orientation float64
+	NearestBuoy int // assigned by the store from the stored buoys
+	TideRegion  int
+}
+
+// RegionFilter selects spots by their city: one city by ID, or the cities
+// in a state and county. Zero fields match every city; state and county
+// ignore case.
+type RegionFilter struct {
+	CityID int
+	State  string
+	County string
+}
+
+// ListFilter selects the cities or spots of a list. On top of the
+// RegionFilter it matches spots by break type, ignoring case, and by
+// whether they have a tide station; a city has one when any of its spots
+// does. Lists of cities ignore CityID and BreakType.
+type ListFilter struct {
+	RegionFilter
+	BreakType      string
+	HasTideStation *bool
+}
+
+// Keyset is a page of a list ordered by name and then id: the first Limit
+// rows after the row named AfterName with id AfterID. The zero Keyset is
+// the whole list.
+type Keyset struct {
+	AfterName string
+	AfterID   int
+	Limit     int // 0 for every row
+}
+
+// SpotWithConditions is a spot and its current conditions, nil when the
+// spot has none.
+type SpotWithConditions struct {
+	Spot       Spot
+	Conditions *models.CurrentSurfSpotConditions
+}
+
+// BuoyObservation is one row of a buoy's realtime data.
+type BuoyObservation struct {
+	BuoyID                int
+	RecordedAt            time.Time
+	WindDirectionDegT     *float64
+	WindSpeedMetersPerSec *float64
+	WindGustMetersPerSec  *float64
+	WaveHeightM           *float64
+	DominantWavePeriodSec *float64
+	AvgWavePeriodSec      *float64
+	MeanWaveDirectionDegT *float64
+	AirTempDegC           *float64
+	WaterTempDegC         *float64
+	InsertedAt            time.Time
+	// QC holds the quality control flags of the fields above, keyed by
+	// field name.
+	QC qc.Flags
+}
+
+// WeatherObservation is the latest weather of a city.
+type WeatherObservation struct {
+	CityID        int
+	RecordedAt    time.Time
+	WindSpeedMph  string
+	WindDirection *float64
+	AirTempC      *float64
+	Precipitation *float64
+	CloudCoverage string
+	ObservedAt    string
+	QC            qc.Flags
+	// Station is the observation station the weather came from, with its
+	// position when the source reported one.
+	Station          string
+	StationLatitude  *float64
+	StationLongitude *float64
+}
+
+// TidePrediction is one predicted high or low tide. Date is YYYY-MM-DD
+// and Time is HH:MM, in the station's local time.
+type TidePrediction struct {
+	StationName string
+	CountyName  string
+	StateCode   string
+	Date        string
+	Time        string
+	WaterLevel  float64
+	TidalState  string
+	TideRegion  string
+}
+
+// ForecastPeriod is one hour of a city's forecast.
+type ForecastPeriod struct {
+	StartTime           string
+	EndTime             string
+	Temperature         int
+	TemperatureUnit     string
+	WindSpeed           string
+	WindDirection       string
+	PrecipitationChance float64
+	ShortForecast       string
+}
+
+// Run statuses of an IngestionRun.
+const (
+	RunRunning = "running"
+	RunOK      = "ok"
+	RunPartial = "partial" // finished, but some stations failed
+	RunFailed  = "failed"
+)
+
+// IngestionRun is one run of an ingestion source.
+type IngestionRun struct {
+	ID          int64
+	Source      string
+	Status      string
+	StartedAt   time.Time
+	FinishedAt  *time.Time
+	RowsWritten int
+	Failures    map[string]string
+	Error       *string
+}
+
+// SourceStats summarises the runs of one ingestion source.
+type SourceStats struct {
+	Source        string
+	LastSuccessAt *time.Time // newest finish of an ok or partial run
+	Runs          int        // runs started since the requested time
+	Failures      int        // failed runs started since the requested time
+}
+
+// TableStatus summarises one table for the status command.
+type TableStatus struct {
+	Table  string     `json:"table"`
+	Rows   int        `json:"rows"`
+	Newest *time.Time `json:"newest,omitempty"` // newest recorded_at, nil for tables without one
+}
+
+// StaticData is a reload of the static tables. A nil slice leaves its
+// table untouched; a non-nil slice replaces it, deleting rows that are not
+// listed.
+type StaticData struct {
+	Buoys  []Buoy
+	Cities []City
+	Spots  []Spot
+	Tides  []TidePrediction
+}
+
+// Static holds the reference data: buoys, cities, surf spots and tides.
+type Static interface {
+	// ReplaceStatic applies a reload in one atomic step and assigns every
+	// spot its nearest buoy.
+	ReplaceStatic(ctx context.Context, data StaticData) error
+	// ReplaceStationTides replaces the predictions of the stations named
+	// in tides and keeps those of every other station.
+	ReplaceStationTides(ctx context.Context, tides []TidePrediction) error
+	Buoys(ctx context.Context) ([]Buoy, error)
+	// Cities returns every city ordered by name.
+	Cities(ctx context.Context) ([]City, error)
+	Spots(ctx context.Context) ([]Spot, error)
+	SpotsByCity(ctx context.Context, cityID int) ([]Spot, error)
+	// ListCities returns the page of the cities matching f, ordered by
+	// name and id, and how many cities match f in all.
+	ListCities(ctx context.Context, f ListFilter, page Keyset) ([]City, int, error)
+	// ListSpots is ListCities for spots.
+	ListSpots(ctx context.Context, f ListFilter, page Keyset) ([]Spot, int, error)
+	// TidesOn returns the predictions for date, given as YYYY-MM-DD,
+	// ordered by tide region, station and time.
+	TidesOn(ctx context.Context, date string) ([]TidePrediction, error)
+}
+
+// Observations holds the latest buoy and weather observations and the
+// buoy history.
+type Observations interface {
+	// SaveBuoyObservations upserts the latest observation of each given
+	// buoy and drops those of buoys that no longer exist. Other buoys keep
+	// their previous observation.
+	SaveBuoyObservations(ctx context.Context, obs []BuoyObservation) error
+	LatestBuoyObservations(ctx context.Context) ([]BuoyObservation, error)
+	// AddBuoyHistory stores observations that are not already stored and
+	// returns how many were new.
+	AddBuoyHistory(ctx context.Context, obs []BuoyObservation) (int, error)
+	// SaveWeatherObservations upserts the weather of each given city and
+	// drops that of cities that no longer exist.
+	SaveWeatherObservations(ctx context.Context, obs []WeatherObservation) error
+	LatestWeatherObservations(ctx context.Context) ([]WeatherObservation, error)
+}
+
+// Conditions holds the current surf conditions of every spot.
+type Conditions interface {
+	// ReplaceConditions atomically replaces every spot's conditions and
+	// sets their Rebuild above that of any earlier call.
+	ReplaceConditions(ctx context.Context, conditions []models.CurrentSurfSpotConditions) error
+	// SpotConditions returns ErrNotFound if the spot has no conditions.
+	SpotConditions(ctx context.Context, spotID int) (models.CurrentSurfSpotConditions, error)
+	// AllConditions returns the conditions of every spot that has them,
+	// ordered by spot.
+	AllConditions(ctx context.Context) ([]models.CurrentSurfSpotConditions, error)
+	// RegionConditions returns the spots matching f with their current
+	// conditions, ordered by spot name.
+	RegionConditions(ctx context.Context, f RegionFilter) ([]SpotWithConditions, error)
+}
+
+// Forecasts holds each city's hourly forecast.
+type Forecasts interface {
+	// ReplaceCityForecast atomically replaces one city's forecast.
+	ReplaceCityForecast(ctx context.Context, cityID int, periods []ForecastPeriod) error
+}
+
+// IngestionRuns is the audit log of ingestion runs.
+type IngestionRuns interface {
+	StartRun(ctx context.Context, source string, startedAt time.Time) (int64, error)
+	// FinishRun completes a run; Source and StartedAt of run are ignored.
+	FinishRun(ctx context.Context, run IngestionRun) error
+	// RecentRuns returns the newest runs of any source, newest first.
+	RecentRuns(ctx context.Context, limit int) ([]IngestionRun, error)
+	// LatestRuns returns the newest run of each source.
+	LatestRuns(ctx context.Context) ([]IngestionRun, error)
+	RunStats(ctx context.Context, since time.Time) ([]SourceStats, error)
+}
+
+// Store is every repository together.
+type Store interface {
+	Static
+	Observations
+	Conditions
+	Forecasts
+	IngestionRuns
+
+	// Ping verifies the store is reachable.
+	Ping(ctx context.Context) error
+	// Status returns the row count and newest observation of each table.
+	Status(ctx context.Context) ([]TableStatus, error)
+	Close() error
+}
+
+// Locker is implemented by stores that several processes can share.
+type Locker interface {
+	// TryLock takes the lock called name unless another process holds
+	// it, in which case it returns false.
+	TryLock(ctx context.Context, name string) (Lease, bool, error)
+}
+
+// Lease is a held lock.
+type Lease interface {
+	// Lost is closed when the lock can no longer be confirmed, for
+	// example because the database connection holding it failed.
+	Lost() <-chan struct{}
+	Release() error
+}
+
+// Migrator is implemented by stores that keep a versioned schema.
+type Migrator interface {
+	// Migrate applies every pending migration and returns the versions it
+	// applied.
+	Migrate(ctx context.Context) ([]int, error)
+	// SchemaVersion returns the newest applied and the newest known
+	// migration version.
+	SchemaVersion(ctx context.Context) (current int, latest int, err error)
+}
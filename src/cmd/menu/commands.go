@@ -0,0 +1,414 @@
+package main
+
+import (
+	meteo "Go_surf_redesign/src/backend/api"
+	"Go_surf_redesign/src/backend/apiclient"
+	dbLib "Go_surf_redesign/src/backend/db_lib"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/config"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runServe starts the API server and blocks until it is stopped.
+func runServe(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	ingest := fs.Bool("ingest", false, "also run scheduled data ingestion in this process")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	dc, providers, err := connect(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer dc.Close()
+	listenForChanges(ctx, dc)
+
+	var opts []meteo.RouterOption
+	if *ingest {
+		ing, err := dbLib.StartDataIngestion(ctx, dc, providers)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		opts = append(opts, meteo.WithIngestion(ing))
+	}
+
+	if err := meteo.StartRouter(ctx, dc.Store(), cfg, dc.Events(), opts...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// runIngest refreshes the selected sources, either once or on the
+// configured schedule until the process is signalled.
+func runIngest(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("ingest", flag.ContinueOnError)
+	once := fs.Bool("once", false, "refresh each source once and exit")
+	sourceList := fs.String("sources", strings.Join(dbLib.Sources, ","), "comma separated sources to refresh")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	sources, err := parseSources(*sourceList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	dc, providers, err := connect(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer dc.Close()
+
+	if !*once {
+		if err := dbLib.RunIngestion(ctx, dc, providers, sources...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	lease, err := dc.LockIngestion(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if lease != nil {
+		defer lease.Release()
+	}
+	code := exitOK
+	for _, source := range sources {
+		if err := dc.RunSource(ctx, providers, source); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", source, err)
+			code = exitError
+		}
+	}
+	return code
+}
+
+// parseSources splits a comma separated source list and keeps it in the
+// order the sources must run.
+func parseSources(list string) ([]string, error) {
+	requested := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		requested[name] = true
+	}
+
+	var sources []string
+	for _, source := range dbLib.Sources {
+		if requested[source] {
+			sources = append(sources, source)
+			delete(requested, source)
+		}
+	}
+	for name := range requested {
+		return nil, fmt.Errorf("unknown source %q (want one of %s)", name, strings.Join(dbLib.Sources, ", "))
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no sources selected")
+	}
+	return sources, nil
+}
+
+// runLoadStatic reloads the static data sets.
+func runLoadStatic(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("load-static", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	dc, providers, err := connect(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer dc.Close()
+
+	if err := dc.LoadStaticData(ctx, providers); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// runBackfill stores buoy history from the NDBC realtime2 files.
+func runBackfill(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	days := fs.Int("days", 45, "how many days of history to keep (realtime2 files hold about 45)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *days <= 0 {
+		fmt.Fprintln(os.Stderr, "-days must be positive")
+		return exitUsage
+	}
+
+	dc, providers, err := connect(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer dc.Close()
+
+	since := time.Now().UTC().AddDate(0, 0, -*days)
+	written, err := dc.BackfillBuoyHistory(ctx, providers.Waves, since)
+	fmt.Printf("%d buoy history rows written\n", written)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// runReprocess parses the payloads archived in a time range again.
+func runReprocess(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	fromFlag := fs.String("from", "", "start of the range, RFC 3339 or YYYY-MM-DD (required)")
+	toFlag := fs.String("to", "", "end of the range, exclusive (default now)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *fromFlag == "" {
+		fmt.Fprintln(os.Stderr, "-from is required")
+		return exitUsage
+	}
+	from, err := parseTime(*fromFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	to := time.Now()
+	if *toFlag != "" {
+		if to, err = parseTime(*toFlag); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+	if !from.Before(to) {
+		fmt.Fprintln(os.Stderr, "-from must be before -to")
+		return exitUsage
+	}
+
+	st, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	dc := dbLib.NewDataClient(st, cfg)
+	defer dc.Close()
+
+	arc, err := openArchive(cfg, st)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if arc == nil {
+		fmt.Fprintln(os.Stderr, "payload archiving is disabled (archive.backend = \"none\")")
+		return exitError
+	}
+
+	report, err := dc.Reprocess(ctx, arc, from, to)
+	fmt.Printf("%d payloads read, %d skipped\n", report.Payloads, report.Skipped)
+	fmt.Printf("%d buoy, %d buoy history, %d weather, %d tide and %d conditions rows written\n",
+		report.BuoyRows, report.HistoryRows, report.WeatherRows, report.TidePrediction, report.Conditions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// parseTime accepts an RFC 3339 time or a UTC date.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want RFC 3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// runMigrate applies pending migrations.
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	st, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer st.Close()
+
+	migrator, ok := st.(store.Migrator)
+	if !ok {
+		fmt.Printf("the %s store has no schema to migrate\n", cfg.Store.Backend)
+		return exitOK
+	}
+	applied, err := migrator.Migrate(ctx)
+	for _, version := range applied {
+		fmt.Printf("applied migration %04d\n", version)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if len(applied) == 0 {
+		fmt.Println("schema is up to date")
+	}
+	return exitOK
+}
+
+type statusReport struct {
+	Store         string              `json:"store"`
+	Database      string              `json:"database"`
+	SchemaVersion int                 `json:"schemaVersion"`
+	LatestVersion int                 `json:"latestVersion"`
+	Tables        []store.TableStatus `json:"tables"`
+	// Ingestion is the scheduler of the running server, when it ingests.
+	Ingestion *apiclient.IngestionScheduler `json:"ingestion,omitempty"`
+}
+
+// runStatus reports database connectivity, schema version and table
+// freshness, and the ingestion jobs of the server when it can be reached.
+// It exits non-zero when the database is unreachable or the schema is
+// behind.
+func runStatus(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	server := fs.String("server", serverURL(cfg.Server.Addr), "base URL of the API server to ask for its ingestion jobs")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	report := statusReport{Store: cfg.Store.Backend, Database: "ok"}
+	code := exitOK
+
+	st, err := openStore(cfg)
+	migrator, versioned := st.(store.Migrator)
+	if err != nil {
+		report.Database = err.Error()
+		code = exitError
+	} else {
+		defer st.Close()
+
+		if versioned {
+			report.SchemaVersion, report.LatestVersion, err = migrator.SchemaVersion(ctx)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				code = exitError
+			} else if report.SchemaVersion < report.LatestVersion {
+				code = exitError
+			}
+		}
+
+		if report.SchemaVersion > 0 || !versioned {
+			report.Tables, err = st.Status(ctx)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				code = exitError
+			}
+		}
+	}
+
+	// The scheduler lives in the server process, so ask it. A server that is
+	// down or does not ingest is reported but does not fail the command.
+	var jobsErr error
+	if summary, err := apiclient.New(*server, apiclient.WithAdminToken(cfg.Server.AdminToken)).IngestionSummary(ctx, 1); err != nil {
+		jobsErr = err
+	} else {
+		report.Ingestion = summary.Scheduler
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return code
+	}
+
+	fmt.Printf("store:    %s\n", report.Store)
+	fmt.Printf("database: %s\n", report.Database)
+	if versioned {
+		fmt.Printf("schema:   %d of %d\n", report.SchemaVersion, report.LatestVersion)
+	}
+	if len(report.Tables) > 0 {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TABLE\tROWS\tNEWEST")
+		for _, t := range report.Tables {
+			newest := "-"
+			if t.Newest != nil {
+				newest = fmt.Sprintf("%s (%s ago)", t.Newest.UTC().Format(time.RFC3339), time.Since(*t.Newest).Round(time.Minute))
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\n", t.Table, t.Rows, newest)
+		}
+		w.Flush()
+	}
+
+	fmt.Println()
+	switch {
+	case jobsErr != nil:
+		fmt.Printf("ingestion: unknown (%v)\n", jobsErr)
+	case report.Ingestion == nil:
+		fmt.Printf("ingestion: not running in %s\n", *server)
+	case !report.Ingestion.Leading:
+		fmt.Println("ingestion: waiting, another instance holds the lock")
+	default:
+		fmt.Println("ingestion: leading")
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "JOB\tSTATE\tLAST RUN\tNEXT RUN\tLAST ERROR")
+		for _, j := range report.Ingestion.Jobs {
+			state := "idle"
+			if j.Running {
+				state = "running"
+			} else if len(j.WaitingOn) > 0 {
+				state = "waiting on " + strings.Join(j.WaitingOn, ",")
+			}
+			lastErr := "-"
+			if j.LastError != nil {
+				lastErr = *j.LastError
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", j.Name, state, formatTime(j.LastRun), formatTime(j.NextRun), lastErr)
+		}
+		w.Flush()
+	}
+	return code
+}
+
+// serverURL returns the URL a local client reaches a server listening on
+// addr at, such as http://localhost:8080 for ":8080".
+func serverURL(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	return "http://" + addr
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
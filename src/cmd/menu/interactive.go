@@ -0,0 +1,112 @@
+package main
+
+import (
+	meteo "Go_surf_redesign/src/backend/api"
+	dbLib "Go_surf_redesign/src/backend/db_lib"
+	"Go_surf_redesign/src/backend/provider"
+	"Go_surf_redesign/src/config"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runInteractive starts the original fmt.Scan menu.
+func runInteractive(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("interactive", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	dc, providers, err := connect(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	listenForChanges(ctx, dc)
+
+	mainMenu(ctx, cfg, dc, providers)
+	return exitOK
+}
+
+func mainMenu(ctx context.Context, cfg *config.Config, dc *dbLib.DataClient, providers *provider.Set) {
+	input := ""
+	for {
+		fmt.Println("MAIN MENU")
+		fmt.Println()
+		fmt.Println("	(a) Start application - (starts data ingestion and router)")
+		fmt.Println("	(b) Start API server")
+		fmt.Println("	(c) Enter options menu")
+		fmt.Println()
+		fmt.Println("[q] Quit")
+		fmt.Print("> ")
+		fmt.Scan(&input)
+
+		input = strings.TrimSpace(input)
+		switch input {
+		case "a":
+			ing, err := dbLib.StartDataIngestion(ctx, dc, providers)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				continue
+			}
+			if err := meteo.StartRouter(ctx, dc.Store(), cfg, dc.Events(), meteo.WithIngestion(ing)); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		case "b":
+			if err := meteo.StartRouter(ctx, dc.Store(), cfg, dc.Events()); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		case "c":
+			optionsMenu(ctx, dc, providers)
+		case "q":
+			quit(dc)
+		}
+	}
+}
+
+func optionsMenu(ctx context.Context, dc *dbLib.DataClient, providers *provider.Set) {
+	fmt.Println()
+	input := ""
+
+	for input != "q" {
+		fmt.Println("OPTIONS MENU")
+		fmt.Println()
+		fmt.Println("	(a) Load static data sets into database.")
+		fmt.Println("	(b) Update real-time buoy data.")
+		fmt.Println("	(c) Update real-time weather data.")
+		fmt.Println("	(d) Update current surf condition data.")
+		fmt.Println(" 	(e) Update static tide data.")
+		fmt.Println()
+		fmt.Println("[q] Back")
+		fmt.Println()
+		fmt.Print("> ")
+		fmt.Scan(&input)
+
+		var err error
+		input = strings.TrimSpace(input)
+		switch input {
+		case "a":
+			err = dc.LoadStaticData(ctx, providers)
+		case "b":
+			err = dc.RunSource(ctx, providers, dbLib.SourceBuoys)
+		case "c":
+			err = dc.RunSource(ctx, providers, dbLib.SourceWeather)
+		case "d":
+			err = dc.RunSource(ctx, providers, dbLib.SourceConditions)
+		case "e":
+			err = dc.RunSource(ctx, providers, dbLib.SourceTides)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+}
+
+func quit(db *dbLib.DataClient) {
+	db.Close()
+	fmt.Println("Goodbye")
+	os.Exit(0)
+}
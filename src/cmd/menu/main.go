@@ -1,98 +1,190 @@
 package main
 
 import (
-	meteo "Go_surf_redesign/src/backend/api"
+	"Go_surf_redesign/src/backend/archive"
 	dbLib "Go_surf_redesign/src/backend/db_lib"
+	"Go_surf_redesign/src/backend/events"
+	"Go_surf_redesign/src/backend/logging"
+	"Go_surf_redesign/src/backend/provider"
+	"Go_surf_redesign/src/backend/store"
+	"Go_surf_redesign/src/backend/store/memory"
+	"Go_surf_redesign/src/backend/store/postgres"
+	"Go_surf_redesign/src/config"
 	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"strings"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 )
 
+// notifyTimeout bounds announcing a change to other processes.
+const notifyTimeout = 5 * time.Second
+
+// Exit codes returned by every subcommand.
+const (
+	exitOK    = 0
+	exitError = 1 // the command ran and failed
+	exitUsage = 2 // bad flags or arguments
+)
+
+// command is a single CLI subcommand. run receives the arguments that
+// follow the subcommand name and returns the process exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, cfg *config.Config, args []string) int
+}
+
+var commands = []command{
+	{"serve", "Start the API server (and optionally ingestion)", runServe},
+	{"ingest", "Refresh real-time data once or on a schedule", runIngest},
+	{"load-static", "Reload the static cities, buoys, surf spots and tides", runLoadStatic},
+	{"backfill", "Store buoy history from the NDBC realtime2 files", runBackfill},
+	{"reprocess", "Parse archived upstream payloads again and store the result", runReprocess},
+	{"migrate", "Apply pending database migrations", runMigrate},
+	{"status", "Report database, schema and data status", runStatus},
+	{"interactive", "Start the interactive menu", runInteractive},
+}
+
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	global := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configPath := global.String("config", os.Getenv("GOSURF_CONFIG"), "path to a TOML config file")
+	global.Usage = func() { usage(global) }
+	if err := global.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if global.NArg() == 0 {
+		usage(global)
+		return exitUsage
+	}
+	name := global.Arg(0)
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		slog.SetDefault(logging.New(cfg.Log, os.Stderr))
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-	// initialize database client
-	dc := dbLib.NewDBClient()
-	if err := dc.PingDB(); err != nil {
-		log.Fatalf("From Main() - could not connect to database: %v", err)
+		return cmd.run(ctx, cfg, global.Args()[1:])
 	}
-	// instantiate api client
-	api := meteo.NewClient()
 
-	// instantiate context
-	ctx := context.Background()
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage(global)
+	return exitUsage
+}
+
+func usage(global *flag.FlagSet) {
+	out := global.Output()
+	fmt.Fprintf(out, "Usage: %s [-config file] <command> [flags]\n", global.Name())
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Global flags:")
+	global.PrintDefaults()
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Run '%s <command> -h' for command flags.\n", global.Name())
+}
 
-	// meteo.StartRouter(dc.DB)
-	// dbLib.StartDataIngestion(ctx, dc, api)
+// openStore opens the configured store. A Postgres connection is verified
+// before it is returned; the memory store starts with demo data.
+func openStore(cfg *config.Config) (store.Store, error) {
+	if cfg.Store.Backend == config.StoreMemory {
+		return memory.Demo(), nil
+	}
 
-	mainMenu(ctx, dc, api)
+	st, err := postgres.Open(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.Ping(context.Background()); err != nil {
+		st.Close()
+		return nil, fmt.Errorf("could not connect to database: %w", err)
+	}
+	return st, nil
 }
 
-func mainMenu(ctx context.Context, dc *dbLib.DataClient, api *meteo.Client) {
-	input := ""
-	for {
-		fmt.Println("MAIN MENU")
-		fmt.Println()
-		fmt.Println("	(a) Start application - (starts data ingestion and router)")
-		fmt.Println("	(b) Start API server")
-		fmt.Println("	(c) Enter options menu")
-		fmt.Println()
-		fmt.Println("[q] Quit")
-		fmt.Print("> ")
-		fmt.Scan(&input)
-
-		input = strings.TrimSpace(input)
-		switch input {
-		case "a":
-			dbLib.StartDataIngestion(ctx, dc, api)
-			meteo.StartRouter(dc.DB)
-		case "b":
-			meteo.StartRouter(dc.DB)
-		case "c":
-			optionsMenu(ctx, dc, api)
-		case "q":
-			quit(dc)
+// openArchive returns the configured payload archive, or nil when
+// archiving is disabled.
+func openArchive(cfg *config.Config, st store.Store) (archive.Archive, error) {
+	switch cfg.Archive.Backend {
+	case config.ArchiveDir:
+		return archive.NewDir(cfg.Archive.Dir), nil
+	case config.ArchivePostgres:
+		pg, ok := st.(*postgres.Store)
+		if !ok {
+			return nil, fmt.Errorf("the postgres archive needs the postgres store")
 		}
+		return pg.Archive(), nil
 	}
+	return nil, nil
 }
 
-func optionsMenu(ctx context.Context, dc *dbLib.DataClient, api *meteo.Client) {
-	fmt.Println()
-	input := ""
-
-	for input != "q" {
-		fmt.Println("OPTIONS MENU")
-		fmt.Println()
-		fmt.Println("	(a) Load static data sets into database.")
-		fmt.Println("	(b) Update real-time buoy data.")
-		fmt.Println("	(c) Update real-time weather data.")
-		fmt.Println("	(d) Update current surf condition data.")
-		fmt.Println(" 	(e) Update static tide data.")
-		fmt.Println()
-		fmt.Println("[q] Back")
-		fmt.Println()
-		fmt.Print("> ")
-		fmt.Scan(&input)
-
-		input = strings.TrimSpace(input)
-		switch input {
-		case "a":
-			dc.LoadStaticData()
-		case "b":
-			dc.UpdateRTBuoyData(ctx, api)
-		case "c":
-			dc.UpdateRTWeatherData(ctx, api)
-		case "d":
-			dc.UpdateCurrentSurfConditions(api)
-		case "e":
-			dc.UpdateStaticTideData()
-		}
+// connect opens the configured store and builds the configured upstream
+// data providers. The client publishes to a new event bus, so a router
+// started in the same process sees ingestion finish; with Postgres the
+// events are also sent to other processes.
+func connect(cfg *config.Config) (*dbLib.DataClient, *provider.Set, error) {
+	st, err := openStore(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	arc, err := openArchive(cfg, st)
+	if err != nil {
+		st.Close()
+		return nil, nil, err
+	}
+	providers, err := provider.New(cfg, arc)
+	if err != nil {
+		st.Close()
+		return nil, nil, err
 	}
+	bus := events.NewBus()
+	if pg, ok := st.(*postgres.Store); ok {
+		bus.Subscribe(func(e events.Event) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := pg.Notify(ctx, e); err != nil {
+				slog.Error("could not announce change", "source", e.Source, "error", err)
+			}
+		})
+	}
+	dc := dbLib.NewDataClient(st, cfg)
+	dc.SetEvents(bus)
+	return dc, providers, nil
 }
 
-func quit(db *dbLib.DataClient) {
-	db.Close()
-	fmt.Println("Goodbye")
-	os.Exit(0)
+// listenForChanges publishes the changes other processes announce through
+// the database on dc's bus until ctx is done, so an API server learns
+// about ingestion running elsewhere. Only the postgres store has them.
+func listenForChanges(ctx context.Context, dc *dbLib.DataClient) {
+	pg, ok := dc.Store().(*postgres.Store)
+	if !ok {
+		return
+	}
+	go func() {
+		if err := pg.Listen(ctx, dc.Events()); err != nil {
+			slog.Error("stopped listening for changes", "error", err)
+		}
+	}()
 }
@@ -0,0 +1,651 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Config is the typed application configuration. It is built from defaults,
+// then an optional TOML file, then environment variable overrides, and is
+// validated before it is handed to the database client, the API client and
+// the router.
+type Config struct {
+	// Root is the directory relative paths are resolved against.
+	// Defaults to PROJECT_ROOT, or the working directory when unset.
+	Root string `toml:"root"`
+
+	Store     StoreConfig     `toml:"store"`
+	Database  DatabaseConfig  `toml:"database"`
+	Server    ServerConfig    `toml:"server"`
+	Ingestion IngestionConfig `toml:"ingestion"`
+	QC        QCConfig        `toml:"qc"`
+	Status    StatusConfig    `toml:"status"`
+	Archive   ArchiveConfig   `toml:"archive"`
+	Upstream  UpstreamConfig  `toml:"upstream"`
+	Providers ProvidersConfig `toml:"providers"`
+	Data      DataConfig      `toml:"data"`
+	Log       LogConfig       `toml:"log"`
+}
+
+// Store backends.
+const (
+	StorePostgres = "postgres"
+	StoreMemory   = "memory"
+)
+
+// StoreConfig selects where data is kept. "postgres" uses the database
+// settings below; "memory" keeps everything in process, starting from a
+// small demo data set, and loses it on exit.
+type StoreConfig struct {
+	Backend string `toml:"backend"`
+}
+
+// DatabaseConfig holds the Postgres connection settings.
+type DatabaseConfig struct {
+	// URL is a full connection string. When set it takes precedence over
+	// the individual fields below.
+	URL      string `toml:"url"`
+	Host     string `toml:"host"`
+	Port     int    `toml:"port"`
+	User     string `toml:"user"`
+	Password string `toml:"password"`
+	Name     string `toml:"name"`
+	SSLMode  string `toml:"sslmode"`
+}
+
+// ServerConfig holds the API server settings.
+type ServerConfig struct {
+	Addr      string `toml:"addr"`
+	StaticDir string `toml:"static_dir"`
+	// StreamHeartbeat is how often idle event streams send a comment to
+	// keep proxies from closing them.
+	StreamHeartbeat Duration    `toml:"stream_heartbeat"`
+	Cache           CacheConfig `toml:"cache"`
+	// AdminToken is the bearer token the /admin endpoints require. They
+	// are disabled while it is empty.
+	AdminToken string `toml:"admin_token"`
+}
+
+// CacheConfig controls the API response cache. Entries live for their TTL
+// or until ingestion rewrites the data behind them; a zero TTL turns
+// caching off for that kind of response. MaxAge is the Cache-Control
+// max-age sent to browsers and CDNs.
+type CacheConfig struct {
+	StaticTTL     Duration `toml:"static_ttl"`
+	ConditionsTTL Duration `toml:"conditions_ttl"`
+	MaxAge        Duration `toml:"max_age"`
+}
+
+// IngestionConfig holds the schedule for each data source.
+type IngestionConfig struct {
+	// LeaderRetry is how often an instance that shares its database
+	// tries to take over ingestion from the one running it.
+	LeaderRetry Duration `toml:"leader_retry"`
+
+	Buoys      JobConfig `toml:"buoys"`
+	Weather    JobConfig `toml:"weather"`
+	Conditions JobConfig `toml:"conditions"`
+	Tides      JobConfig `toml:"tides"`
+	Forecasts  JobConfig `toml:"forecasts"`
+}
+
+// JobConfig holds the settings for a single ingestion job.
+type JobConfig struct {
+	Interval Duration `toml:"interval"`
+	// Jitter is the largest random delay added to each interval.
+	Jitter Duration `toml:"jitter"`
+	// Timeout cancels a run that takes longer than this.
+	Timeout Duration `toml:"timeout"`
+}
+
+// StaleAfter is how long after its last success a job's data is stale:
+// two missed intervals plus the largest jitter.
+func (j JobConfig) StaleAfter() time.Duration {
+	return 2*j.Interval.Duration + j.Jitter.Duration
+}
+
+// NamedJob pairs an ingestion source with its job settings.
+type NamedJob struct {
+	Name   string
+	Config JobConfig
+}
+
+// Jobs lists every ingestion job in a stable order.
+func (i IngestionConfig) Jobs() []NamedJob {
+	return []NamedJob{
+		{"buoys", i.Buoys},
+		{"weather", i.Weather},
+		{"conditions", i.Conditions},
+		{"tides", i.Tides},
+		{"forecasts", i.Forecasts},
+	}
+}
+
+// QCConfig holds the quality control settings applied to observations
+// before they are stored.
+type QCConfig struct {
+	// MaxAge is how old an observation may be before its values are
+	// flagged stale.
+	MaxAge Duration `toml:"max_age"`
+}
+
+// StatusConfig holds how old the newest observation of each source may
+// be before /v1/status/data reports the data as stale.
+type StatusConfig struct {
+	BuoysMaxAge      Duration `toml:"buoys_max_age"`
+	WeatherMaxAge    Duration `toml:"weather_max_age"`
+	ConditionsMaxAge Duration `toml:"conditions_max_age"`
+}
+
+// Archive backends.
+const (
+	ArchiveNone     = "none"
+	ArchiveDir      = "dir"
+	ArchivePostgres = "postgres"
+)
+
+// ArchiveConfig selects where raw upstream payloads are kept for
+// reprocessing. "dir" writes gzip files under Dir, "postgres" stores them
+// in the database (and needs the postgres store) and "none" keeps nothing.
+type ArchiveConfig struct {
+	Backend string `toml:"backend"`
+	Dir     string `toml:"dir"`
+}
+
+// UpstreamConfig holds the settings for calls to NDBC and api.weather.gov.
+type UpstreamConfig struct {
+	// NDBCURL and NWSURL are the base URLs of the buoy and weather APIs.
+	// Tests point them at local fakes.
+	NDBCURL string   `toml:"ndbc_url"`
+	NWSURL  string   `toml:"nws_url"`
+	Timeout Duration `toml:"timeout"`
+	// UserAgent is sent with every request. api.weather.gov rejects
+	// requests without one and asks that it include contact details.
+	UserAgent string `toml:"user_agent"`
+	// Workers bounds how many requests a batch fetch runs at once.
+	Workers int `toml:"workers"`
+	// Retries is how many times a request that failed with a 5xx, a 429
+	// or a timeout is retried. Backoff is the delay before the first
+	// retry; it doubles on each attempt up to MaxBackoff.
+	Retries    int      `toml:"retries"`
+	Backoff    Duration `toml:"backoff"`
+	MaxBackoff Duration `toml:"max_backoff"`
+	// HostInterval is the minimum average spacing between requests to a
+	// single host, with up to HostBurst requests sent back to back.
+	HostInterval Duration `toml:"host_interval"`
+	HostBurst    int      `toml:"host_burst"`
+	// ValidatorFile stores the ETag and Last-Modified values of upstream
+	// responses so conditional requests survive restarts. Empty keeps them
+	// in memory only.
+	ValidatorFile string `toml:"validator_file"`
+}
+
+// ProvidersConfig names the registered implementation used for each kind
+// of upstream data. See the provider package for the available names.
+type ProvidersConfig struct {
+	Waves    string `toml:"waves"`
+	Weather  string `toml:"weather"`
+	Forecast string `toml:"forecast"`
+	Tides    string `toml:"tides"`
+}
+
+// DataConfig holds the location of the static data sets (csv and tide xml).
+type DataConfig struct {
+	Dir string `toml:"dir"`
+}
+
+// Log formats.
+const (
+	LogText = "text"
+	LogJSON = "json"
+)
+
+// LogConfig controls the process logger. Level is one of debug, info,
+// warn or error; Format is "text" for terminals or "json" for log
+// collectors in production.
+type LogConfig struct {
+	Level  string `toml:"level"`
+	Format string `toml:"format"`
+}
+
+// Duration wraps time.Duration so it can be written as "15m" in config files.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a duration string such as "90s" or "1h".
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalText formats the duration as a string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Default returns the configuration used for local development.
+func Default() *Config {
+	return &Config{
+		Root: os.Getenv("PROJECT_ROOT"),
+		Store: StoreConfig{
+			Backend: StorePostgres,
+		},
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    5432,
+			User:    "postgres",
+			Name:    "surfdb",
+			SSLMode: "disable",
+		},
+		Server: ServerConfig{
+			Addr:            ":8080",
+			StaticDir:       "src/frontend",
+			StreamHeartbeat: Duration{25 * time.Second},
+			Cache: CacheConfig{
+				StaticTTL:     Duration{time.Hour},
+				ConditionsTTL: Duration{15 * time.Minute},
+				MaxAge:        Duration{time.Minute},
+			},
+		},
+		Ingestion: IngestionConfig{
+			LeaderRetry: Duration{15 * time.Second},
+			Buoys: JobConfig{
+				Interval: Duration{15 * time.Minute},
+				Jitter:   Duration{30 * time.Second},
+				Timeout:  Duration{5 * time.Minute},
+			},
+			Weather: JobConfig{
+				Interval: Duration{time.Hour},
+				Jitter:   Duration{time.Minute},
+				Timeout:  Duration{5 * time.Minute},
+			},
+			Conditions: JobConfig{
+				Interval: Duration{15 * time.Minute},
+				Timeout:  Duration{2 * time.Minute},
+			},
+			Tides: JobConfig{
+				Interval: Duration{24 * time.Hour},
+				Timeout:  Duration{5 * time.Minute},
+			},
+			Forecasts: JobConfig{
+				Interval: Duration{3 * time.Hour},
+				Jitter:   Duration{5 * time.Minute},
+				Timeout:  Duration{10 * time.Minute},
+			},
+		},
+		QC: QCConfig{
+			MaxAge: Duration{3 * time.Hour},
+		},
+		Status: StatusConfig{
+			BuoysMaxAge:      Duration{2 * time.Hour},
+			WeatherMaxAge:    Duration{3 * time.Hour},
+			ConditionsMaxAge: Duration{2 * time.Hour},
+		},
+		Archive: ArchiveConfig{
+			Backend: ArchiveDir,
+			Dir:     ".cache/archive",
+		},
+		Upstream: UpstreamConfig{
+			NDBCURL:       "https://www.ndbc.noaa.gov",
+			NWSURL:        "https://api.weather.gov",
+			Timeout:       Duration{10 * time.Second},
+			UserAgent:     "GoSurf/1.0 (https://github.com/Justin-W7/Go_surf)",
+			Workers:       8,
+			Retries:       3,
+			Backoff:       Duration{500 * time.Millisecond},
+			MaxBackoff:    Duration{30 * time.Second},
+			HostInterval:  Duration{200 * time.Millisecond},
+			HostBurst:     5,
+			ValidatorFile: ".cache/upstream_validators.json",
+		},
+		Providers: ProvidersConfig{
+			Waves:    "noaa",
+			Weather:  "noaa",
+			Forecast: "noaa",
+			Tides:    "noaa",
+		},
+		Data: DataConfig{
+			Dir: "src/backend/data",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: LogText,
+		},
+	}
+}
+
+// Load builds a Config from defaults, the TOML file at path (skipped when
+// path is empty) and environment overrides, then validates it.
+// Relative paths in the result are resolved against Root.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open config file: %w", err)
+		}
+		defer f.Close()
+
+		if err := toml.NewDecoder(f).DisallowUnknownFields().Decode(cfg); err != nil {
+			return nil, fmt.Errorf("could not parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	cfg.resolvePaths()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides file values with environment variables.
+func (c *Config) applyEnv() error {
+	var errs []error
+
+	setString := func(key string, dst *string) {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = v
+		}
+	}
+	setInt := func(key string, dst *int) {
+		if v, ok := os.LookupEnv(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				return
+			}
+			*dst = n
+		}
+	}
+	setDuration := func(key string, dst *Duration) {
+		if v, ok := os.LookupEnv(key); ok {
+			if err := dst.UnmarshalText([]byte(v)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+		}
+	}
+
+	setString("PROJECT_ROOT", &c.Root)
+
+	setString("GOSURF_STORE", &c.Store.Backend)
+	setString("DATABASE_URL", &c.Database.URL)
+	setString("GOSURF_DB_HOST", &c.Database.Host)
+	setInt("GOSURF_DB_PORT", &c.Database.Port)
+	setString("GOSURF_DB_USER", &c.Database.User)
+	setString("GOSURF_DB_PASSWORD", &c.Database.Password)
+	setString("GOSURF_DB_NAME", &c.Database.Name)
+	setString("GOSURF_DB_SSLMODE", &c.Database.SSLMode)
+
+	// PORT is the conventional override used by most hosting platforms.
+	if v, ok := os.LookupEnv("PORT"); ok {
+		c.Server.Addr = ":" + v
+	}
+	setString("GOSURF_SERVER_ADDR", &c.Server.Addr)
+	setString("GOSURF_STATIC_DIR", &c.Server.StaticDir)
+	setDuration("GOSURF_STREAM_HEARTBEAT", &c.Server.StreamHeartbeat)
+	setString("GOSURF_ADMIN_TOKEN", &c.Server.AdminToken)
+	setDuration("GOSURF_CACHE_STATIC_TTL", &c.Server.Cache.StaticTTL)
+	setDuration("GOSURF_CACHE_CONDITIONS_TTL", &c.Server.Cache.ConditionsTTL)
+	setDuration("GOSURF_CACHE_MAX_AGE", &c.Server.Cache.MaxAge)
+
+	setDuration("GOSURF_LEADER_RETRY", &c.Ingestion.LeaderRetry)
+	setDuration("GOSURF_BUOYS_INTERVAL", &c.Ingestion.Buoys.Interval)
+	setDuration("GOSURF_WEATHER_INTERVAL", &c.Ingestion.Weather.Interval)
+	setDuration("GOSURF_CONDITIONS_INTERVAL", &c.Ingestion.Conditions.Interval)
+	setDuration("GOSURF_TIDES_INTERVAL", &c.Ingestion.Tides.Interval)
+	setDuration("GOSURF_FORECASTS_INTERVAL", &c.Ingestion.Forecasts.Interval)
+
+	setDuration("GOSURF_QC_MAX_AGE", &c.QC.MaxAge)
+
+	setDuration("GOSURF_STATUS_BUOYS_MAX_AGE", &c.Status.BuoysMaxAge)
+	setDuration("GOSURF_STATUS_WEATHER_MAX_AGE", &c.Status.WeatherMaxAge)
+	setDuration("GOSURF_STATUS_CONDITIONS_MAX_AGE", &c.Status.ConditionsMaxAge)
+
+	setString("GOSURF_ARCHIVE", &c.Archive.Backend)
+	setString("GOSURF_ARCHIVE_DIR", &c.Archive.Dir)
+
+	setString("GOSURF_NDBC_URL", &c.Upstream.NDBCURL)
+	setString("GOSURF_NWS_URL", &c.Upstream.NWSURL)
+	setDuration("GOSURF_UPSTREAM_TIMEOUT", &c.Upstream.Timeout)
+	setString("GOSURF_USER_AGENT", &c.Upstream.UserAgent)
+	setInt("GOSURF_UPSTREAM_WORKERS", &c.Upstream.Workers)
+	setInt("GOSURF_UPSTREAM_RETRIES", &c.Upstream.Retries)
+	setString("GOSURF_UPSTREAM_VALIDATOR_FILE", &c.Upstream.ValidatorFile)
+
+	setString("GOSURF_WAVES_PROVIDER", &c.Providers.Waves)
+	setString("GOSURF_WEATHER_PROVIDER", &c.Providers.Weather)
+	setString("GOSURF_FORECAST_PROVIDER", &c.Providers.Forecast)
+	setString("GOSURF_TIDES_PROVIDER", &c.Providers.Tides)
+
+	setString("GOSURF_DATA_DIR", &c.Data.Dir)
+
+	setString("GOSURF_LOG_LEVEL", &c.Log.Level)
+	setString("GOSURF_LOG_FORMAT", &c.Log.Format)
+
+	return errors.Join(errs...)
+}
+
+// resolvePaths makes every configured path absolute.
+func (c *Config) resolvePaths() {
+	if c.Root == "" {
+		if wd, err := os.Getwd(); err == nil {
+			c.Root = wd
+		}
+	}
+	c.Data.Dir = c.Path(c.Data.Dir)
+	c.Server.StaticDir = c.Path(c.Server.StaticDir)
+	c.Archive.Dir = c.Path(c.Archive.Dir)
+	if c.Upstream.ValidatorFile != "" {
+		c.Upstream.ValidatorFile = c.Path(c.Upstream.ValidatorFile)
+	}
+}
+
+// Path resolves a path relative to the project root.
+// Absolute paths are returned unchanged.
+func (c *Config) Path(relative string) string {
+	if filepath.IsAbs(relative) {
+		return relative
+	}
+	return filepath.Join(c.Root, relative)
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Store.Backend {
+	case StorePostgres, StoreMemory:
+	default:
+		errs = append(errs, fmt.Errorf("store.backend %q is not %q or %q", c.Store.Backend, StorePostgres, StoreMemory))
+	}
+
+	// The database settings only matter when data is kept in Postgres.
+	if c.Store.Backend == StorePostgres {
+		if c.Database.URL != "" {
+			if _, err := url.Parse(c.Database.URL); err != nil {
+				errs = append(errs, fmt.Errorf("database.url: %w", err))
+			}
+		} else {
+			if c.Database.Host == "" {
+				errs = append(errs, errors.New("database.host is required"))
+			}
+			if c.Database.Port <= 0 || c.Database.Port > 65535 {
+				errs = append(errs, fmt.Errorf("database.port %d is out of range", c.Database.Port))
+			}
+			if c.Database.User == "" {
+				errs = append(errs, errors.New("database.user is required"))
+			}
+			if c.Database.Name == "" {
+				errs = append(errs, errors.New("database.name is required"))
+			}
+		}
+	}
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	if c.Server.StreamHeartbeat.Duration <= 0 {
+		errs = append(errs, errors.New("server.stream_heartbeat must be positive"))
+	}
+	for _, d := range []struct {
+		name  string
+		value Duration
+	}{
+		{"static_ttl", c.Server.Cache.StaticTTL},
+		{"conditions_ttl", c.Server.Cache.ConditionsTTL},
+		{"max_age", c.Server.Cache.MaxAge},
+	} {
+		if d.value.Duration < 0 {
+			errs = append(errs, fmt.Errorf("server.cache.%s must not be negative", d.name))
+		}
+	}
+
+	if c.Ingestion.LeaderRetry.Duration <= 0 {
+		errs = append(errs, errors.New("ingestion.leader_retry must be positive"))
+	}
+	for _, job := range c.Ingestion.Jobs() {
+		if job.Config.Interval.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("ingestion.%s.interval must be positive", job.Name))
+		}
+		if job.Config.Jitter.Duration < 0 {
+			errs = append(errs, fmt.Errorf("ingestion.%s.jitter must not be negative", job.Name))
+		}
+		if job.Config.Timeout.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("ingestion.%s.timeout must be positive", job.Name))
+		}
+	}
+
+	if c.QC.MaxAge.Duration <= 0 {
+		errs = append(errs, errors.New("qc.max_age must be positive"))
+	}
+
+	for _, d := range []struct {
+		name  string
+		value Duration
+	}{
+		{"buoys_max_age", c.Status.BuoysMaxAge},
+		{"weather_max_age", c.Status.WeatherMaxAge},
+		{"conditions_max_age", c.Status.ConditionsMaxAge},
+	} {
+		if d.value.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("status.%s must be positive", d.name))
+		}
+	}
+
+	switch c.Archive.Backend {
+	case ArchiveNone:
+	case ArchiveDir:
+		if c.Archive.Dir == "" {
+			errs = append(errs, errors.New("archive.dir is required for the dir archive"))
+		}
+	case ArchivePostgres:
+		if c.Store.Backend != StorePostgres {
+			errs = append(errs, fmt.Errorf("archive.backend %q needs store.backend %q", ArchivePostgres, StorePostgres))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("archive.backend %q is not %q, %q or %q", c.Archive.Backend, ArchiveNone, ArchiveDir, ArchivePostgres))
+	}
+
+	for _, u := range []struct{ name, value string }{
+		{"ndbc_url", c.Upstream.NDBCURL},
+		{"nws_url", c.Upstream.NWSURL},
+	} {
+		if parsed, err := url.Parse(u.value); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			errs = append(errs, fmt.Errorf("upstream.%s %q is not an absolute URL", u.name, u.value))
+		}
+	}
+	if c.Upstream.Timeout.Duration <= 0 {
+		errs = append(errs, errors.New("upstream.timeout must be positive"))
+	}
+	if c.Upstream.UserAgent == "" {
+		errs = append(errs, errors.New("upstream.user_agent is required"))
+	}
+	if c.Upstream.Workers < 1 {
+		errs = append(errs, errors.New("upstream.workers must be at least 1"))
+	}
+	if c.Upstream.Retries < 0 {
+		errs = append(errs, errors.New("upstream.retries must not be negative"))
+	}
+	if c.Upstream.Backoff.Duration <= 0 || c.Upstream.MaxBackoff.Duration < c.Upstream.Backoff.Duration {
+		errs = append(errs, errors.New("upstream.backoff must be positive and no larger than upstream.max_backoff"))
+	}
+	if c.Upstream.HostInterval.Duration < 0 {
+		errs = append(errs, errors.New("upstream.host_interval must not be negative"))
+	}
+	if c.Upstream.HostBurst < 1 {
+		errs = append(errs, errors.New("upstream.host_burst must be at least 1"))
+	}
+
+	for _, p := range []struct{ name, value string }{
+		{"waves", c.Providers.Waves},
+		{"weather", c.Providers.Weather},
+		{"forecast", c.Providers.Forecast},
+		{"tides", c.Providers.Tides},
+	} {
+		if p.value == "" {
+			errs = append(errs, fmt.Errorf("providers.%s is required", p.name))
+		}
+	}
+
+	if info, err := os.Stat(c.Data.Dir); err != nil || !info.IsDir() {
+		errs = append(errs, fmt.Errorf("data.dir %q is not a directory", c.Data.Dir))
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("log.level %q is not debug, info, warn or error", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case LogText, LogJSON:
+	default:
+		errs = append(errs, fmt.Errorf("log.format %q is not %q or %q", c.Log.Format, LogText, LogJSON))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
+// ConnString returns the connection string for database/sql.
+func (d DatabaseConfig) ConnString() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	s := fmt.Sprintf("host=%s port=%d user=%s dbname=%s sslmode=%s",
+		quoteConnValue(d.Host), d.Port, quoteConnValue(d.User), quoteConnValue(d.Name), quoteConnValue(d.SSLMode))
+	if d.Password != "" {
+		s += " password=" + quoteConnValue(d.Password)
+	}
+	return s
+}
+
+// quoteConnValue quotes a value for a key=value connection string.
+func quoteConnValue(v string) string {
+	escaped := ""
+	for _, r := range v {
+		if r == '\'' || r == '\\' {
+			escaped += `\`
+		}
+		escaped += string(r)
+	}
+	return "'" + escaped + "'"
+}
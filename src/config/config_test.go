@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+// Every value of a key=value connection string is quoted, so spaces and
+// quotes survive.
+func TestConnString(t *testing.T) {
+	d := DatabaseConfig{
+		Host:     "db host",
+		Port:     5432,
+		User:     "o'brien",
+		Name:     `surf\data`,
+		SSLMode:  "disable",
+		Password: "p w",
+	}
+	want := `host='db host' port=5432 user='o\'brien' dbname='surf\\data' sslmode='disable' password='p w'`
+	if got := d.ConnString(); got != want {
+		t.Errorf("ConnString() = %s\nwant %s", got, want)
+	}
+
+	d.URL = "postgres://localhost/gosurf"
+	if got := d.ConnString(); got != d.URL {
+		t.Errorf("with a URL: %s", got)
+	}
+}
@@ -1,10 +1,5 @@
 package config
 
-import (
-	"os"
-	"path/filepath"
-)
-
 // API URLs
 const (
 	SpitcastSpotURL     = "https://api.spitcast.com/api/spot"
@@ -19,20 +14,3 @@ const (
 	NDBCBouyDataURL = "https://www.ndbc.noaa.gov/data/realtime2/%s.txt"
 	STATION_ID_FILE = "backend/src/api/station_lists/ndbcstations_CA.txt"
 )
-
-// Database files
-const (
-	DATABASE_BUOYS_FILE        = "backend/src/database/buoys.csv"
-	DATABASE_CITIES_FILE       = "backend/src/database/cities.csv"
-	DATABASE_SURFSPOTS_FILE    = "backend/src/database/surfspots.csv"
-	DATABASE_BUOYS_RT_RAW_DATA = "backend/src/database/raw_data/NDBC_buoy_data"
-	OLD_BUOY_DATA_PATH         = "backend/src/database/raw_data/old_NDBC_buoy_data"
-)
-
-// Environment variable for relative file paths
-var root = os.Getenv("PROJECT_ROOT")
-
-// Path builds relative file paths for const variables in config package.
-func Path(relative string) string {
-	return filepath.Join(root, relative)
-}
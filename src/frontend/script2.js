@@ -1,9 +1,13 @@
 // CONFIG -----------------------------------------------
-const API_BASE = "http://localhost:8080";
+// API_BASE includes the API version. Responses from a deprecated version
+// carry a Deprecation header, logged by apiGet, before it is removed.
+const API_BASE = "http://localhost:8080/v1";
 
 // Application state -------------------------------------
 const state = {
   cities: [],
+  // EventSource pushing updates for the spot on screen.
+  conditionsStream: null,
 };
 
 const cloudDict = {
@@ -56,9 +60,28 @@ DOM.homeButton.addEventListener("click", (e) => {
 });
 
 // API server -------------------------------------------------
+
+// apiGet fetches path from the API and resolves to the decoded body. Error
+// responses reject with the API's error object ({code, message, details,
+// requestId}).
+function apiGet(path) {
+  return fetch(`${API_BASE}${path}`).then((res) => {
+    if (res.headers.has("Deprecation")) {
+      console.warn(
+        `${path} is deprecated (sunset ${res.headers.get("Sunset")}); successor: ${res.headers.get("Link")}`,
+      );
+    }
+    return res.json().then((body) => {
+      if (!res.ok) {
+        throw body.error;
+      }
+      return body;
+    });
+  });
+}
+
 function fetchCities() {
-  fetch(`${API_BASE}/cities`)
-    .then((res) => res.json())
+  apiGet("/cities")
     .then((data) => {
       state.cities = data;
       renderCitiesList(data);
@@ -66,14 +89,14 @@ function fetchCities() {
     .catch((err) => console.error("Error fetching cities", err));
 }
 
-function fetchSurfSpots(cityId) {
-  return fetch(`${API_BASE}/surfspots/${cityId}`).then((res) => res.json());
+// fetchCityConditions resolves to every spot of the city with its score
+// and current conditions, best first.
+function fetchCityConditions(cityId) {
+  return apiGet(`/cities/${cityId}/conditions?sort=score`);
 }
 
 function fetchSurfConditions(spotId) {
-  return fetch(`${API_BASE}/surfforecast/current/${spotId}`).then((res) =>
-    res.json(),
-  );
+  return apiGet(`/surfforecast/current/${spotId}`);
 }
 
 // RENDER -------------------------------------------------
@@ -94,14 +117,17 @@ function renderCitiesList(cities) {
   });
 }
 
+// loadSurfSpots lists the city's spots, best score first, and shows the
+// best one's conditions.
 function loadSurfSpots(cityId) {
-  fetchSurfSpots(cityId).then((data) => {
+  fetchCityConditions(cityId).then((data) => {
     DOM.surfSpotList.innerHTML = "";
 
-    data.forEach((spot) => {
+    data.forEach(({ spot, score }) => {
       const button = document.createElement("button");
       button.className = "spot-button";
-      button.textContent = spot.name;
+      button.textContent =
+        score == null ? spot.name : `${spot.name} (${score.toFixed(1)})`;
 
       button.addEventListener("click", () => {
         loadCurrentSurfConditions(spot.id, spot.name);
@@ -115,83 +141,136 @@ function loadSurfSpots(cityId) {
             */
     });
 
-    // show first surfspot data
-    loadCurrentSurfConditions(data[0].id, data[0].name, data[0].buoyId);
+    // show the best spot, whose conditions came with the list
+    if (data.length > 0) {
+      const { spot, conditions } = data[0];
+      loadCurrentSurfConditions(spot.id, spot.name, conditions);
+    }
   });
 }
 
-function loadCurrentSurfConditions(spotId, spotName) {
-  fetchSurfConditions(spotId).then((data) => {
-    // var swellHeight = display(metersToFeet(data.DomSwellHeightM).toFixed(1));
-    // var waterTemp = display(cToF(data.WaterTempDegC)).toFixed(1);
-    // var airTemp = display(cToF(data.AirTempDegC).toFixed(1));
-    // var windSpeed = display(data.WindSpeedMph);
-    // var windDir = display(degreesToCardinal(data.WindDirection));
-    // var cloudCoverage = cloudCoverageDescription(data.CloudCoverage);
-
-    // var precipitation = display(data.Precipitation);
-    // if (precipitation != "NA") {
-    //   precipitation = precipitation + "%";
-    // }
-
-    var swellHeight =
-      data.DomSwellHeightM == null
-        ? "NA"
-        : metersToFeet(data.DomSwellHeightM).toFixed(1);
-
-    var waterTemp =
-      data.WaterTempDegC == null ? "NA" : cToF(data.WaterTempDegC).toFixed(1);
-
-    var airTemp =
-      data.AirTempDegC == null ? "NA" : cToF(data.AirTempDegC).toFixed(1);
-
-    var windSpeed = data.WindSpeedMph == null ? "NA" : data.WindSpeedMph;
-
-    var windDir = degreesToCardinal(data.WindDirection) ?? "NA";
-
-    var cloudCoverage = cloudCoverageDescription(data.CloudCoverage);
-
-    var precipitation =
-      data.Precipitation == null ? "NA" : data.Precipitation + "%";
-
-    DOM.contentMain.innerHTML = `
-            <div class="current-conditions-parent">
-                <div class="conditions-card">
-                    <div class="conditions-title">
-                        ${spotName} - Current Conditions
-                    </div>
-
-                    <div class="conditions-content">
-                        <div class="conditions-content-left">
-                            <div class="conditions-content-left-title">
-                                Ocean Info - Buoy: ${data.NearestBuoy}
-                            </div>
-                            <div class="content-left-data">
-                                <p>Dominant swell: ${swellHeight} ft @ ${data.DominantWavePeriodSec} sec</p>
-                                <p>Swell Direction: ${data.DomSwellDir}°</p>
-                                <p>Water Temp: ${waterTemp}°</p>
-                            </div>
-                        </div>
-
-                        <div class="conditions-content-right">
-                            <div class="conditions-content-right-title">
-                                Weather Info
-                            </div>
-                            <div class="content-right-data">
-                                <p>Air Temp: ${airTemp}°</p>
-                                <p>Wind: ${windSpeed} mph - (${windDir})</p>
-                                <p>Cloud Coverage: ${cloudCoverage}</p>
-                                <p>Precipitation: ${precipitation}</p>
-                            </div>
-                        </div>
-                    </div>
-                </div>
-            </div>
-        `;
+// loadCurrentSurfConditions shows a spot's conditions, fetching them
+// unless they are given, and keeps them live through the conditions stream
+// until another spot is picked.
+function loadCurrentSurfConditions(spotId, spotName, conditions) {
+  closeConditionsStream();
+  if (conditions != null) {
+    renderCurrentSurfConditions(conditions, spotName);
+  } else {
+    fetchSurfConditions(spotId).then((data) => {
+      renderCurrentSurfConditions(data, spotName);
+    });
+  }
+
+  const stream = new EventSource(`${API_BASE}/stream/spots?ids=${spotId}`);
+  stream.addEventListener("conditions", (e) => {
+    renderCurrentSurfConditions(JSON.parse(e.data), spotName);
   });
+  state.conditionsStream = stream;
+}
+
+function closeConditionsStream() {
+  if (state.conditionsStream != null) {
+    state.conditionsStream.close();
+    state.conditionsStream = null;
+  }
+}
+
+function renderCurrentSurfConditions(data, spotName) {
+  // var swellHeight = display(metersToFeet(data.DomSwellHeightM).toFixed(1));
+  // var waterTemp = display(cToF(data.WaterTempDegC)).toFixed(1);
+  // var airTemp = display(cToF(data.AirTempDegC).toFixed(1));
+  // var windSpeed = display(data.WindSpeedMph);
+  // var windDir = display(degreesToCardinal(data.WindDirection));
+  // var cloudCoverage = cloudCoverageDescription(data.CloudCoverage);
+
+  // var precipitation = display(data.Precipitation);
+  // if (precipitation != "NA") {
+  //   precipitation = precipitation + "%";
+  // }
+
+  var swellHeight =
+    data.DomSwellHeightM == null
+      ? "NA"
+      : metersToFeet(data.DomSwellHeightM).toFixed(1);
+
+  var waterTemp =
+    data.WaterTempDegC == null ? "NA" : cToF(data.WaterTempDegC).toFixed(1);
+
+  var airTemp =
+    data.AirTempDegC == null ? "NA" : cToF(data.AirTempDegC).toFixed(1);
+
+  var windSpeed = data.WindSpeedMph == null ? "NA" : data.WindSpeedMph;
+
+  var windDir = degreesToCardinal(data.WindDirection) ?? "NA";
+
+  var cloudCoverage = cloudCoverageDescription(data.CloudCoverage);
+
+  var precipitation =
+    data.Precipitation == null ? "NA" : data.Precipitation + "%";
+
+  DOM.contentMain.innerHTML = `
+          <div class="current-conditions-parent">
+              <div class="conditions-card">
+                  <div class="conditions-title">
+                      ${spotName} - Current Conditions
+                  </div>
+                  ${freshnessNotice(data.Provenance)}
+
+                  <div class="conditions-content">
+                      <div class="conditions-content-left">
+                          <div class="conditions-content-left-title">
+                              Ocean Info - Buoy: ${data.NearestBuoy}
+                          </div>
+                          <div class="content-left-data">
+                              <p>Dominant swell: ${swellHeight} ft @ ${data.DominantWavePeriodSec} sec</p>
+                              <p>Swell Direction: ${data.DomSwellDir}°</p>
+                              <p>Water Temp: ${waterTemp}°</p>
+                          </div>
+                      </div>
+
+                      <div class="conditions-content-right">
+                          <div class="conditions-content-right-title">
+                              Weather Info
+                          </div>
+                          <div class="content-right-data">
+                              <p>Air Temp: ${airTemp}°</p>
+                              <p>Wind: ${windSpeed} mph - (${windDir})</p>
+                              <p>Cloud Coverage: ${cloudCoverage}</p>
+                              <p>Precipitation: ${precipitation}</p>
+                          </div>
+                      </div>
+                  </div>
+              </div>
+          </div>
+      `;
+}
+
+// freshnessNotice returns a warning when the buoy or weather data behind
+// the conditions is old or missing, and nothing when it is fresh.
+function freshnessNotice(provenance) {
+  if (provenance == null || provenance.Status === "fresh") {
+    return "";
+  }
+  const describe = (label, source) => {
+    if (source.Status === "missing") {
+      return `${label} data is unavailable`;
+    }
+    if (source.Status === "stale") {
+      const hours = Math.floor(source.AgeMinutes / 60);
+      return `${label} data is ${hours} hours old`;
+    }
+    return null;
+  };
+  const notes = [
+    describe("Buoy", provenance.Buoy),
+    describe("Weather", provenance.Weather),
+  ].filter((note) => note != null);
+  return `<div class="conditions-warning">${notes.join(". ")}.</div>`;
 }
 
 function resetHomeUI() {
+  closeConditionsStream();
   DOM.surfSpotList.innerHTML = "";
   DOM.contentMain.innerHTML = `
         <div class="logodiv">